@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -19,62 +20,80 @@ import (
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	slog.SetDefault(newLogger(cfg.LogFormat))
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
 	// Initialize MongoDB
 	mongoRepo, err := repository.NewMongoRepo(cfg.MongoURI, cfg.MongoDB)
 	if err != nil {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
 	}
-	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		_ = mongoRepo.Close(ctx)
-	}()
 
 	// Initialize Meilisearch (optional)
 	var meiliRepo *repository.MeiliRepo
 	if cfg.MeilisearchHost != "" {
-		meiliRepo, err = repository.NewMeiliRepo(cfg.MeilisearchHost, cfg.MeilisearchKey)
+		meiliRepo, err = repository.NewMeiliRepo(cfg.MeilisearchHost, cfg.MeilisearchKey, searchSettings(cfg))
 		if err != nil {
 			log.Printf("Warning: Failed to connect to Meilisearch: %v", err)
 		}
 	}
 
 	// Initialize services
-	validator := service.NewSchemaValidator(mongoRepo)
+	validator := service.NewSchemaValidator(mongoRepo, cfg.ValidationMaxDepth)
 	var syncSvc *service.SyncService
 	if meiliRepo != nil {
-		syncSvc = service.NewSyncService(meiliRepo)
+		syncSvc = service.NewSyncService(meiliRepo, mongoRepo)
+		// Index the attribute fields schemas declare as searchable
+		syncSvc.RefreshSearchableFieldsAsync()
 	}
 	authService := service.NewAuthService(mongoRepo, cfg)
 	sessionStore := service.NewSessionStore(mongoRepo)
+	apiKeyStore := service.NewAPIKeyStore(mongoRepo)
 
 	// Initialize handlers
-	schemaHandler := handler.NewSchemaHandler(mongoRepo)
-	entryHandler := handler.NewEntryHandler(mongoRepo, meiliRepo, validator, syncSvc)
-	authHandler := handler.NewAuthHandler(authService, sessionStore, cfg)
+	schemaHandler := handler.NewSchemaHandler(mongoRepo, syncSvc)
+	entryHandler := handler.NewEntryHandler(mongoRepo, meiliRepo, validator, syncSvc, cfg)
+	authHandler := handler.NewAuthHandler(authService, sessionStore, apiKeyStore, cfg)
 	taxonomyHandler := handler.NewTaxonomyHandler(mongoRepo)
-	termHandler := handler.NewTermHandler(mongoRepo)
-	commentHandler := handler.NewCommentHandler(mongoRepo)
+	termHandler := handler.NewTermHandler(mongoRepo, syncSvc)
+	commentHandler := handler.NewCommentHandler(mongoRepo, cfg)
+	userHandler := handler.NewUserHandler(mongoRepo, sessionStore)
+	adminHandler := handler.NewAdminHandler(mongoRepo, meiliRepo, syncSvc)
+	healthHandler := handler.NewHealthHandler(mongoRepo, meiliRepo)
+	feedHandler := handler.NewFeedHandler(mongoRepo, cfg)
+	mediaHandler := handler.NewMediaHandler(mongoRepo, service.NewLocalStorage(cfg.MediaDir, cfg.MediaBaseURL), cfg)
 
 	// Setup Gin router
-	r := gin.Default()
+	r := gin.New()
+	r.Use(handler.RequestLogger(slog.Default()), gin.Recovery(), handler.MetricsMiddleware())
+	r.Use(handler.BodyLimitMiddleware(cfg.MaxBodySize))
 
 	// CORS configuration
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{cfg.FrontendURL},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", handler.APIKeyHeader, handler.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "X-Total-Count", handler.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	// Health check endpoint
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Health check endpoints: /health/live for liveness, /health and /health/ready check dependencies
+	r.GET("/health", healthHandler.Ready)
+	r.GET("/health/live", healthHandler.Live)
+
+	// Uploaded media served from local storage
+	r.Static("/uploads", cfg.MediaDir)
+
+	// Prometheus metrics
+	r.GET("/metrics", handler.MetricsHandler(mongoRepo, meiliRepo))
+	r.GET("/health/ready", healthHandler.Ready)
 
 	// API routes
 	v1 := r.Group("/api/v1")
@@ -82,16 +101,47 @@ func main() {
 		// Auth routes
 		auth := v1.Group("/auth")
 		{
+			auth.GET("/providers", authHandler.Providers)
 			auth.GET("/signin/:provider", authHandler.SignIn)
-			auth.GET("/callback/:provider", authHandler.Callback)
-			auth.GET("/session", handler.OptionalAuthMiddleware(sessionStore), authHandler.Session)
+			auth.GET("/callback/:provider", handler.OptionalAuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.Callback)
+			auth.GET("/session", handler.OptionalAuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.Session)
 			auth.POST("/signout", authHandler.SignOut)
-			auth.PUT("/profile", handler.AuthMiddleware(sessionStore), authHandler.UpdateProfile)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", authHandler.Login)
+			auth.PUT("/password", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.SetPassword)
+			auth.GET("/link/:provider", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.Link)
+			auth.DELETE("/socials/:provider", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.UnlinkSocial)
+			auth.POST("/api-keys", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.CreateAPIKey)
+			auth.GET("/api-keys", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.ListAPIKeys)
+			auth.DELETE("/api-keys/:id", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.RevokeAPIKey)
+			auth.GET("/sessions", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.ListSessions)
+			auth.DELETE("/sessions/:id", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.RevokeSession)
+			auth.POST("/sessions/revoke-all", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.RevokeOtherSessions)
+			auth.PUT("/profile", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.UpdateProfile)
+			auth.DELETE("/account", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), authHandler.DeleteAccount)
+		}
+
+		// User management routes (admin only)
+		users := v1.Group("/users")
+		users.Use(handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware())
+		{
+			users.POST("/:id/ban", userHandler.Ban)
+			users.POST("/:id/unban", userHandler.Unban)
+		}
+
+		// Admin maintenance routes
+		admin := v1.Group("/admin")
+		admin.Use(handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware())
+		{
+			admin.GET("/stats", adminHandler.Stats)
+			admin.POST("/reindex", adminHandler.Reindex)
+			admin.POST("/search-settings", adminHandler.UpdateSearchSettings)
 		}
 
 		// Schema routes (admin only)
 		schemas := v1.Group("/schemas")
-		schemas.Use(handler.AuthMiddleware(sessionStore), handler.AdminMiddleware())
+		schemas.Use(handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware())
 		{
 			schemas.POST("", schemaHandler.Create)
 			schemas.GET("", schemaHandler.List)
@@ -99,43 +149,81 @@ func main() {
 			schemas.DELETE("/:key", schemaHandler.Delete)
 		}
 
+		// Per-group rate limits, applied after auth so authenticated clients are counted per user
+		readLimit := handler.RateLimitMiddleware(cfg.ReadRateLimit, cfg.ReadRateWindow)
+		writeLimit := handler.RateLimitMiddleware(cfg.WriteRateLimit, cfg.WriteRateWindow)
+
 		// Entry routes
 		entries := v1.Group("/entries")
 		{
-			entries.GET("", handler.OptionalAuthMiddleware(sessionStore), entryHandler.List)
-			entries.GET("/:id", handler.OptionalAuthMiddleware(sessionStore), entryHandler.Get)
-			entries.POST("", handler.AuthMiddleware(sessionStore), entryHandler.Create)
-			entries.PUT("/:id", handler.AuthMiddleware(sessionStore), entryHandler.Update)
-			entries.DELETE("/:id", handler.AuthMiddleware(sessionStore), entryHandler.Delete)
+			entries.GET("", handler.OptionalAuthMiddleware(sessionStore, apiKeyStore, authService), readLimit, entryHandler.List)
+			entries.HEAD("", handler.OptionalAuthMiddleware(sessionStore, apiKeyStore, authService), readLimit, entryHandler.List)
+			entries.GET("/mine", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), readLimit, entryHandler.Mine)
+			entries.GET("/:id", handler.OptionalAuthMiddleware(sessionStore, apiKeyStore, authService), entryHandler.Get)
+			entries.GET("/slug/:schema_key/:slug", handler.OptionalAuthMiddleware(sessionStore, apiKeyStore, authService), entryHandler.GetBySlug)
+			entries.POST("", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, entryHandler.Create)
+			entries.POST("/bulk", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, handler.BodyLimitMiddleware(cfg.BulkMaxBodySize), entryHandler.BulkCreate)
+			entries.PUT("/:id", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, entryHandler.Update)
+			entries.POST("/:id/status", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, entryHandler.UpdateStatus)
+			entries.POST("/:id/transfer", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), writeLimit, entryHandler.Transfer)
+			entries.POST("/transfer", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), writeLimit, entryHandler.TransferByAuthor)
+			entries.DELETE("/:id", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, entryHandler.Delete)
 		}
 
+		// Media routes
+		media := v1.Group("/media")
+		{
+			// Leave some room for the multipart envelope on top of the file itself
+			media.POST("", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, handler.BodyLimitMiddleware(cfg.MediaMaxSize+1<<20), mediaHandler.Upload)
+			media.GET("/:id", mediaHandler.Get)
+			media.DELETE("/:id", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, mediaHandler.Delete)
+		}
+
+		// Feed routes: /feeds/<schema_key>.xml
+		v1.GET("/feeds/:feed", readLimit, feedHandler.Schema)
+
 		// Taxonomy routes
 		taxonomies := v1.Group("/taxonomies")
 		{
 			taxonomies.GET("", taxonomyHandler.List)
 			taxonomies.GET("/:key", taxonomyHandler.Get)
-			taxonomies.POST("", handler.AuthMiddleware(sessionStore), handler.AdminMiddleware(), taxonomyHandler.Create)
-			taxonomies.PUT("/:key", handler.AuthMiddleware(sessionStore), handler.AdminMiddleware(), taxonomyHandler.Update)
-			taxonomies.DELETE("/:key", handler.AuthMiddleware(sessionStore), handler.AdminMiddleware(), taxonomyHandler.Delete)
+			taxonomies.GET("/:key/stats", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), taxonomyHandler.Stats)
+			taxonomies.POST("", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), taxonomyHandler.Create)
+			taxonomies.PUT("/:key", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), taxonomyHandler.Update)
+			taxonomies.DELETE("/:key", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), taxonomyHandler.Delete)
 		}
 
 		// Term routes
 		terms := v1.Group("/terms")
 		{
 			terms.GET("/taxonomy/:key", termHandler.ListByTaxonomy)
+			terms.GET("/taxonomy/:key/tree", termHandler.Tree)
+			terms.GET("/taxonomy/:key/counts", termHandler.Counts)
+			terms.GET("/taxonomy/:key/slug/:slug", termHandler.GetBySlug)
+			terms.POST("/taxonomy/:key/reorder", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), termHandler.Reorder)
 			terms.GET("/:id", termHandler.Get)
-			terms.POST("", handler.AuthMiddleware(sessionStore), handler.AdminMiddleware(), termHandler.Create)
-			terms.PUT("/:id", handler.AuthMiddleware(sessionStore), handler.AdminMiddleware(), termHandler.Update)
-			terms.DELETE("/:id", handler.AuthMiddleware(sessionStore), handler.AdminMiddleware(), termHandler.Delete)
+			terms.POST("", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), termHandler.Create)
+			terms.POST("/merge", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), termHandler.Merge)
+			terms.PUT("/:id", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), termHandler.Update)
+			terms.DELETE("/:id", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.AdminMiddleware(), termHandler.Delete)
 		}
 
 		// Comment routes
 		comments := v1.Group("/comments")
 		{
-			comments.GET("/entry/:entry_id", commentHandler.ListByEntry)
-			comments.POST("", handler.AuthMiddleware(sessionStore), commentHandler.Create)
-			comments.PUT("/:id", handler.AuthMiddleware(sessionStore), commentHandler.Update)
-			comments.DELETE("/:id", handler.AuthMiddleware(sessionStore), commentHandler.Delete)
+			comments.GET("/entry/:entry_id", handler.OptionalAuthMiddleware(sessionStore, apiKeyStore, authService), readLimit, commentHandler.ListByEntry)
+			comments.GET("/:root_id/replies", handler.OptionalAuthMiddleware(sessionStore, apiKeyStore, authService), readLimit, commentHandler.Replies)
+			comments.GET("/pending", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.RoleMiddleware("admin", "editor"), commentHandler.ListPending)
+			comments.GET("/reported", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.RoleMiddleware("admin", "editor"), commentHandler.ListReported)
+			comments.POST("", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, commentHandler.Create)
+			comments.POST("/counts", readLimit, commentHandler.Counts)
+			comments.PUT("/:id", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, commentHandler.Update)
+			comments.DELETE("/:id", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, commentHandler.Delete)
+			comments.POST("/:id/like", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, commentHandler.Like)
+			comments.DELETE("/:id/like", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, commentHandler.Unlike)
+			comments.POST("/:id/report", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), writeLimit, commentHandler.Report)
+			comments.POST("/:id/approve", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.RoleMiddleware("admin", "editor"), commentHandler.Approve)
+			comments.POST("/:id/reject", handler.AuthMiddleware(sessionStore, apiKeyStore, authService), handler.RoleMiddleware("admin", "editor"), commentHandler.Reject)
 		}
 	}
 
@@ -148,6 +236,10 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Periodically purge expired sessions and OAuth states in addition to the TTL indexes
+	janitor := service.NewJanitor(mongoRepo, cfg.CleanupInterval)
+	janitor.Start()
+
 	// Graceful shutdown
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -160,14 +252,52 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	janitor.Stop()
 
 	// Give outstanding requests 30 seconds to complete
+	exitCode := 0
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
+		exitCode = 1
 	}
+	cancel()
+
+	// Close MongoDB only after in-flight requests have drained
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	if err := mongoRepo.Close(ctx); err != nil {
+		log.Printf("Failed to disconnect from MongoDB: %v", err)
+	}
+	cancel()
 
 	log.Println("Server exited")
+	os.Exit(exitCode)
+}
+
+// newLogger 按 LOG_FORMAT 创建日志输出；设为默认后标准库 log 的输出也走同一格式
+func newLogger(format string) *slog.Logger {
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+	return slog.New(slog.NewTextHandler(os.Stdout, nil))
+}
+
+// searchSettings 将环境变量中的搜索调优项转换为 Meilisearch 设置，未配置的项保持默认
+func searchSettings(cfg *config.Config) repository.SearchSettings {
+	var settings repository.SearchSettings
+	if cfg.SearchTypoTolerance != "" {
+		enabled := cfg.SearchTypoTolerance == "true"
+		settings.TypoTolerance = &enabled
+	}
+	if cfg.SearchMinWordOneTypo > 0 {
+		n := int64(cfg.SearchMinWordOneTypo)
+		settings.MinWordSizeOne = &n
+	}
+	if cfg.SearchMinWordTwoTypos > 0 {
+		n := int64(cfg.SearchMinWordTwoTypos)
+		settings.MinWordSizeTwo = &n
+	}
+	settings.RankingRules = cfg.SearchRankingRules
+	settings.StopWords = cfg.SearchStopWords
+	return settings
 }
@@ -1,57 +1,378 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/goccy/go-yaml"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	Port            string
+	LogFormat       string // "text"（默认）或 "json"
 	MongoURI        string
 	MongoDB         string
 	MeilisearchHost string
 	MeilisearchKey  string
-	AdminEmail      string
+
+	// 搜索调优，留空/为 0 时保持 Meilisearch 默认值
+	SearchTypoTolerance   string              // "true" / "false"
+	SearchMinWordOneTypo  int                 // 允许 1 个拼写错误的最短词长
+	SearchMinWordTwoTypos int                 // 允许 2 个拼写错误的最短词长
+	SearchRankingRules    []string            // 逗号分隔，例如 "words,typo,proximity,attribute,sort,exactness"
+	SearchStopWords       []string            // 逗号分隔
+	SearchFallback        bool                // Meilisearch 不可用时退化为 Mongo 文本索引搜索，默认开启
+	AdminEmails           map[string]struct{} // ADMIN_EMAILS（逗号分隔）与旧的 ADMIN_EMAIL 合并，已转为小写
 
 	GitHubClientID     string
 	GitHubClientSecret string
 	GoogleClientID     string
 	GoogleClientSecret string
+	GitLabClientID     string
+	GitLabClientSecret string
+	GitLabURL          string // 自托管 GitLab 实例地址，默认 gitlab.com
+	DiscordClientID    string
+	DiscordSecret      string
 	OAuthRedirectURL   string
+	OAuthStateTTL      time.Duration // 发起登录到回调之间允许的最长时间
 
 	FrontendURL  string
+	SiteTitle    string // 订阅源标题
+	SiteURL      string // 订阅源中 entry 链接的前缀，默认同 FrontendURL
+	APIBaseURL   string // API 对外地址，用于订阅源的 self 链接和 Atom id
 	SecureCookie bool
 	CookieDomain string // Cookie 域名，留空则使用当前请求域名
+
+	RedirectAllowedHosts []string // 登录完成后允许跳转的主机（可带端口），FRONTEND_URL 的主机始终允许
+
+	SessionDuration time.Duration // session 有效期，刷新后重新计算
+	CookieSameSite  http.SameSite // COOKIE_SAMESITE: lax（默认）/ strict / none；none 需配合 SECURE_COOKIE
+	JWTSecret       string        // HS256 签名密钥，留空则不签发 JWT
+
+	ValidationMaxDepth int // entry 属性对象/数组的最大嵌套层数
+
+	MaxBodySize     int64 // 请求体的最大字节数，超出返回 413
+	BulkMaxBodySize int64 // 批量接口（如 /entries/bulk）的请求体上限
+
+	MediaDir          string   // 本地存储目录，通过 /uploads 对外提供
+	MediaBaseURL      string   // 媒体文件 URL 前缀，可指向 CDN
+	MediaMaxSize      int64    // 单个文件的最大字节数
+	MediaAllowedTypes []string // 允许上传的 MIME 类型（按文件内容识别）
+
+	CommentEditWindow time.Duration // 评论发布后允许编辑的时长，0 表示不限制
+	CommentModeration bool          // 开启后新评论需管理员审核才会公开显示
+	CommentRateLimit  int           // 每个用户在窗口内最多可发表的评论数，0 表示不限制
+	CommentRateWindow time.Duration
+
+	LoginRateLimit  int // 每个邮箱在窗口内允许的登录尝试次数
+	LoginRateWindow time.Duration
+
+	// 按路由组限流，已认证时按用户计数，否则按 IP，0 表示不限制
+	ReadRateLimit   int // 列表与搜索
+	ReadRateWindow  time.Duration
+	WriteRateLimit  int // 创建、修改、删除 entry 与评论
+	WriteRateWindow time.Duration
+
+	SignInRateLimit  int // 每个 IP 在窗口内允许发起的 OAuth 登录次数，0 表示不限制
+	SignInRateWindow time.Duration
+	CleanupInterval  time.Duration // 过期 session / OAuth state 的清理间隔，作为 TTL 索引的补充，0 表示关闭
 }
 
 var AppConfig *Config
 
-func Load() *Config {
+// Load 读取 .env、CONFIG_FILE 和环境变量；CONFIG_FILE 无法读取或解析时返回错误，由调用方决定如何退出
+func Load() (*Config, error) {
 	_ = godotenv.Load()
 
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := loadConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CONFIG_FILE: %w", err)
+		}
+		fileValues = values
+	}
+
 	AppConfig = &Config{
-		Port:               getEnv("PORT", "8080"),
-		MongoURI:           getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		MongoDB:            getEnv("MONGO_DB", "matter_core"),
-		MeilisearchHost:    getEnv("MEILISEARCH_HOST", "http://localhost:7700"),
-		MeilisearchKey:     getEnv("MEILISEARCH_KEY", ""),
-		AdminEmail:         getEnv("ADMIN_EMAIL", ""),
-		GitHubClientID:     getEnv("GITHUB_CLIENT_ID", ""),
-		GitHubClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
-		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
-		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
-		OAuthRedirectURL:   getEnv("OAUTH_REDIRECT_URL", "http://localhost:8080/api/v1/auth/callback"),
-		FrontendURL:        getEnv("FRONTEND_URL", "http://localhost:3000"),
-		SecureCookie:       getEnv("SECURE_COOKIE", "false") == "true",
-		CookieDomain:       getEnv("COOKIE_DOMAIN", ""), // 例如 ".example.com" 用于跨子域共享
-	}
-	return AppConfig
+		Port:            getEnv("PORT", "8080"),
+		LogFormat:       getEnv("LOG_FORMAT", "text"),
+		MongoURI:        getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		MongoDB:         getEnv("MONGO_DB", "matter_core"),
+		MeilisearchHost: getEnv("MEILISEARCH_HOST", "http://localhost:7700"),
+		MeilisearchKey:  getEnv("MEILISEARCH_KEY", ""),
+
+		SearchTypoTolerance:   getEnv("SEARCH_TYPO_TOLERANCE", ""),
+		SearchMinWordOneTypo:  getIntEnv("SEARCH_MIN_WORD_ONE_TYPO", 0),
+		SearchMinWordTwoTypos: getIntEnv("SEARCH_MIN_WORD_TWO_TYPOS", 0),
+		SearchRankingRules:    getListEnv("SEARCH_RANKING_RULES"),
+		SearchStopWords:       getListEnv("SEARCH_STOP_WORDS"),
+		SearchFallback:        getEnv("SEARCH_FALLBACK", "true") != "false",
+		AdminEmails:           parseEmailSet(getEnv("ADMIN_EMAILS", ""), getEnv("ADMIN_EMAIL", "")),
+		GitHubClientID:        getEnv("GITHUB_CLIENT_ID", ""),
+		GitHubClientSecret:    getEnv("GITHUB_CLIENT_SECRET", ""),
+		GoogleClientID:        getEnv("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret:    getEnv("GOOGLE_CLIENT_SECRET", ""),
+		GitLabClientID:        getEnv("GITLAB_CLIENT_ID", ""),
+		GitLabClientSecret:    getEnv("GITLAB_CLIENT_SECRET", ""),
+		GitLabURL:             strings.TrimRight(getEnv("GITLAB_URL", "https://gitlab.com"), "/"),
+		DiscordClientID:       getEnv("DISCORD_CLIENT_ID", ""),
+		DiscordSecret:         getEnv("DISCORD_CLIENT_SECRET", ""),
+		OAuthRedirectURL:      getEnv("OAUTH_REDIRECT_URL", "http://localhost:8080/api/v1/auth/callback"),
+		OAuthStateTTL:         getDurationEnv("OAUTH_STATE_TTL", 10*time.Minute),
+		FrontendURL:           getEnv("FRONTEND_URL", "http://localhost:3000"),
+		RedirectAllowedHosts:  getListEnv("REDIRECT_ALLOWED_HOSTS"),
+		SiteTitle:             getEnv("SITE_TITLE", "Matter"),
+		SiteURL:               getEnv("SITE_URL", getEnv("FRONTEND_URL", "http://localhost:3000")),
+		APIBaseURL:            strings.TrimRight(getEnv("API_BASE_URL", "http://localhost:8080"), "/"),
+		SecureCookie:          getEnv("SECURE_COOKIE", "false") == "true",
+		CookieDomain:          getEnv("COOKIE_DOMAIN", ""), // 例如 ".example.com" 用于跨子域共享
+		SessionDuration:       getDurationEnv("SESSION_DURATION", 7*24*time.Hour),
+		CookieSameSite:        parseSameSite(getEnv("COOKIE_SAMESITE", "lax")),
+		JWTSecret:             getEnv("JWT_SECRET", ""),
+		ValidationMaxDepth:    getIntEnv("VALIDATION_MAX_DEPTH", 32),
+		MaxBodySize:           int64(getIntEnv("MAX_BODY_SIZE", 1<<20)),
+		BulkMaxBodySize:       int64(getIntEnv("BULK_MAX_BODY_SIZE", 10<<20)),
+		MediaDir:              getEnv("MEDIA_DIR", "./uploads"),
+		MediaBaseURL:          getEnv("MEDIA_BASE_URL", "http://localhost:8080/uploads"),
+		MediaMaxSize:          int64(getIntEnv("MEDIA_MAX_SIZE", 10<<20)),
+		MediaAllowedTypes:     getListEnv("MEDIA_ALLOWED_TYPES"),
+		CommentEditWindow:     getDurationEnv("COMMENT_EDIT_WINDOW", 15*time.Minute),
+		CommentModeration:     getEnv("COMMENT_MODERATION", "false") == "true",
+		CommentRateLimit:      getIntEnv("COMMENT_RATE_LIMIT", 5),
+		CommentRateWindow:     getDurationEnv("COMMENT_RATE_WINDOW", time.Minute),
+		LoginRateLimit:        getIntEnv("LOGIN_RATE_LIMIT", 5),
+		LoginRateWindow:       getDurationEnv("LOGIN_RATE_WINDOW", 15*time.Minute),
+
+		ReadRateLimit:    getIntEnv("READ_RATE_LIMIT", 120),
+		ReadRateWindow:   getDurationEnv("READ_RATE_WINDOW", time.Minute),
+		WriteRateLimit:   getIntEnv("WRITE_RATE_LIMIT", 30),
+		WriteRateWindow:  getDurationEnv("WRITE_RATE_WINDOW", time.Minute),
+		SignInRateLimit:  getIntEnv("SIGNIN_RATE_LIMIT", 10),
+		SignInRateWindow: getDurationEnv("SIGNIN_RATE_WINDOW", time.Minute),
+		// 兼容旧的 OAUTH_STATE_CLEAN_INTERVAL
+		CleanupInterval: getDurationEnv("CLEANUP_INTERVAL", getDurationEnv("OAUTH_STATE_CLEAN_INTERVAL", 10*time.Minute)),
+	}
+	if len(AppConfig.MediaAllowedTypes) == 0 {
+		AppConfig.MediaAllowedTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+	}
+	return AppConfig, nil
+}
+
+// fileValues CONFIG_FILE 中的配置，键为环境变量名；环境变量优先
+var fileValues map[string]string
+
+// loadConfigFile 读取 YAML 或 JSON 配置文件（JSON 是 YAML 的子集），
+// 顶层键与环境变量同名（不区分大小写），列表值会拼接为逗号分隔的字符串
+func loadConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[strings.ToUpper(key)] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("%s: %s must be a scalar or a list", path, key)
+		default:
+			values[strings.ToUpper(key)] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// lookupEnv 先取环境变量，未设置时取 CONFIG_FILE 中的值；设置为空字符串的环境变量同样覆盖文件中的值
+func lookupEnv(key string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fileValues[key]
 }
 
 func getEnv(key, fallback string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		return value
 	}
 	return fallback
 }
+
+// IsAdminEmail 判断邮箱是否在管理员列表中（忽略大小写）
+func (c *Config) IsAdminEmail(email string) bool {
+	if email == "" {
+		return false
+	}
+	_, ok := c.AdminEmails[strings.ToLower(strings.TrimSpace(email))]
+	return ok
+}
+
+func parseEmailSet(lists ...string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, list := range lists {
+		for _, email := range strings.Split(list, ",") {
+			email = strings.ToLower(strings.TrimSpace(email))
+			if email != "" {
+				set[email] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	log.Printf("Warning: invalid COOKIE_SAMESITE %q, using lax", value)
+	return http.SameSiteLaxMode
+}
+
+// getListEnv 解析逗号分隔的列表，未设置时返回 nil
+func getListEnv(key string) []string {
+	value := lookupEnv(key)
+	if value == "" {
+		return nil
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	value := lookupEnv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid duration for %s: %q, using default %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+func getIntEnv(key string, fallback int) int {
+	value := lookupEnv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid integer for %s: %q, using default %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// Validate 检查相互依赖的配置项，返回所有问题（errors.Join），供启动时一次性报告
+func (c *Config) Validate() error {
+	var problems []error
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if _, err := strconv.Atoi(c.Port); err != nil {
+		add("PORT must be a number, got %q", c.Port)
+	}
+	if !strings.HasPrefix(c.MongoURI, "mongodb://") && !strings.HasPrefix(c.MongoURI, "mongodb+srv://") {
+		add("MONGO_URI must start with mongodb:// or mongodb+srv://")
+	}
+	if c.MeilisearchHost != "" && !isAbsoluteURL(c.MeilisearchHost) {
+		add("MEILISEARCH_HOST must be an absolute URL, got %q", c.MeilisearchHost)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		add("LOG_FORMAT must be text or json, got %q", c.LogFormat)
+	}
+	if c.SearchTypoTolerance != "" && c.SearchTypoTolerance != "true" && c.SearchTypoTolerance != "false" {
+		add("SEARCH_TYPO_TOLERANCE must be true or false, got %q", c.SearchTypoTolerance)
+	}
+
+	// OAuth client IDs and secrets come in pairs
+	for _, p := range []struct{ name, id, secret string }{
+		{"GITHUB", c.GitHubClientID, c.GitHubClientSecret},
+		{"GOOGLE", c.GoogleClientID, c.GoogleClientSecret},
+		{"GITLAB", c.GitLabClientID, c.GitLabClientSecret},
+		{"DISCORD", c.DiscordClientID, c.DiscordSecret},
+	} {
+		if (p.id == "") != (p.secret == "") {
+			add("%s_CLIENT_ID and %s_CLIENT_SECRET must be set together", p.name, p.name)
+		}
+	}
+
+	if !isAbsoluteURL(c.FrontendURL) {
+		add("FRONTEND_URL must be an absolute URL, got %q", c.FrontendURL)
+	}
+	if !isAbsoluteURL(c.APIBaseURL) {
+		add("API_BASE_URL must be an absolute URL, got %q", c.APIBaseURL)
+	}
+	if !isAbsoluteURL(c.OAuthRedirectURL) {
+		add("OAUTH_REDIRECT_URL must be an absolute URL, got %q", c.OAuthRedirectURL)
+	} else if c.SecureCookie && !strings.HasPrefix(c.OAuthRedirectURL, "https://") {
+		add("SECURE_COOKIE=true requires an https OAUTH_REDIRECT_URL")
+	}
+	if c.CookieSameSite == http.SameSiteNoneMode && !c.SecureCookie {
+		add("COOKIE_SAMESITE=none requires SECURE_COOKIE=true")
+	}
+	// The OAuth callback is a cross-site navigation from the provider, so strict cookies are not sent with it
+	if c.CookieSameSite == http.SameSiteStrictMode &&
+		(c.GitHubClientID != "" || c.GoogleClientID != "" || c.GitLabClientID != "" || c.DiscordClientID != "") {
+		add("COOKIE_SAMESITE=strict cannot be used with OAuth providers; use lax")
+	}
+
+	if c.SessionDuration <= 0 {
+		add("SESSION_DURATION must be positive")
+	}
+	if c.OAuthStateTTL <= 0 {
+		add("OAUTH_STATE_TTL must be positive")
+	}
+	if c.MediaMaxSize <= 0 {
+		add("MEDIA_MAX_SIZE must be positive")
+	}
+	if c.MaxBodySize <= 0 {
+		add("MAX_BODY_SIZE must be positive")
+	}
+	if c.BulkMaxBodySize <= 0 {
+		add("BULK_MAX_BODY_SIZE must be positive")
+	}
+	for name, d := range map[string]time.Duration{
+		"CLEANUP_INTERVAL":    c.CleanupInterval,
+		"COMMENT_EDIT_WINDOW": c.CommentEditWindow,
+	} {
+		if d < 0 {
+			add("%s must not be negative", name)
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+	"matter-core/internal/service"
+	"matter-core/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statsCacheTTL 概览数据的缓存时长，避免频繁全表统计
+const statsCacheTTL = 1 * time.Minute
+
+// reindexBatchSize 重建索引时每批从 Mongo 读取并推送到 Meilisearch 的 entry 数量
+const reindexBatchSize = 500
+
+type AdminHandler struct {
+	mongoRepo  repository.Repository
+	meiliRepo  *repository.MeiliRepo
+	syncSvc    *service.SyncService
+	statsCache *utils.TTLCache[*model.AdminStats]
+}
+
+func NewAdminHandler(mongoRepo repository.Repository, meiliRepo *repository.MeiliRepo, syncSvc *service.SyncService) *AdminHandler {
+	return &AdminHandler{
+		mongoRepo:  mongoRepo,
+		meiliRepo:  meiliRepo,
+		syncSvc:    syncSvc,
+		statsCache: utils.NewTTLCache[*model.AdminStats](statsCacheTTL),
+	}
+}
+
+// GET /api/v1/admin/stats - 管理后台概览：entry、用户、评论和各分类的 term 数量
+func (h *AdminHandler) Stats(c *gin.Context) {
+	if stats, ok := h.statsCache.Get("stats"); ok {
+		utils.Success(c, stats)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := h.mongoRepo.GetAdminStats(ctx)
+	if err != nil {
+		utils.InternalError(c, "failed to get stats")
+		return
+	}
+	h.statsCache.Set("stats", stats)
+
+	utils.Success(c, stats)
+}
+
+// POST /api/v1/admin/search-settings - 运行时调整搜索相关度设置，未提供的字段保持不变
+func (h *AdminHandler) UpdateSearchSettings(c *gin.Context) {
+	if h.meiliRepo == nil {
+		utils.ServiceUnavailable(c, "search is not configured")
+		return
+	}
+
+	var req repository.SearchSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if (req.MinWordSizeOne != nil && *req.MinWordSizeOne < 0) || (req.MinWordSizeTwo != nil && *req.MinWordSizeTwo < 0) {
+		utils.BadRequest(c, "min word sizes must not be negative")
+		return
+	}
+
+	if err := h.meiliRepo.ApplySettings(req); err != nil {
+		utils.ErrorWithData(c, http.StatusBadGateway, "failed to update search settings", gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.Success(c, req)
+}
+
+// POST /api/v1/admin/reindex - 将所有已发布的 entry 重新写入搜索索引
+// 文档以 entry ID 为主键覆盖写入，可重复执行
+func (h *AdminHandler) Reindex(c *gin.Context) {
+	if h.syncSvc == nil {
+		utils.ServiceUnavailable(c, "search is not configured")
+		return
+	}
+
+	ctx := c.Request.Context()
+	published := false
+	total, err := h.mongoRepo.CountEntries(ctx, nil, nil, &published, "")
+	if err != nil {
+		utils.InternalError(c, "failed to count entries")
+		return
+	}
+
+	var indexed int64
+	err = h.mongoRepo.ForEachEntryBatch(ctx, &published, reindexBatchSize, func(batch []model.Entry) error {
+		entries := make([]*model.Entry, len(batch))
+		for i := range batch {
+			entries[i] = &batch[i]
+		}
+		if err := h.syncSvc.SyncEntries(entries); err != nil {
+			return err
+		}
+		indexed += int64(len(batch))
+		log.Printf("reindex: %d/%d entries indexed", indexed, total)
+		return nil
+	})
+	if err != nil {
+		log.Printf("reindex failed after %d entries: %v", indexed, err)
+		utils.ErrorWithData(c, http.StatusBadGateway, "failed to reindex entries", gin.H{"indexed": indexed, "total": total})
+		return
+	}
+
+	utils.Success(c, gin.H{"indexed": indexed, "total": total})
+}
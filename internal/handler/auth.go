@@ -1,46 +1,70 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"matter-core/internal/config"
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
 	"matter-core/internal/service"
 	"matter-core/pkg/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-const (
-	SessionCookieName = "session_token"
-	SessionDuration   = 7 * 24 * time.Hour
-)
+const SessionCookieName = "session_token"
 
 type AuthHandler struct {
-	authService  *service.AuthService
-	sessionStore *service.SessionStore
-	cfg          *config.Config
+	authService   *service.AuthService
+	sessionStore  *service.SessionStore
+	apiKeyStore   *service.APIKeyStore
+	cfg           *config.Config
+	loginLimiter  *service.RateLimiter
+	signInLimiter *service.RateLimiter
 }
 
-func NewAuthHandler(authService *service.AuthService, sessionStore *service.SessionStore, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, sessionStore *service.SessionStore, apiKeyStore *service.APIKeyStore, cfg *config.Config) *AuthHandler {
 	return &AuthHandler{
-		authService:  authService,
-		sessionStore: sessionStore,
-		cfg:          cfg,
+		authService:   authService,
+		sessionStore:  sessionStore,
+		apiKeyStore:   apiKeyStore,
+		cfg:           cfg,
+		loginLimiter:  service.NewRateLimiter(cfg.LoginRateLimit, cfg.LoginRateWindow),
+		signInLimiter: service.NewRateLimiter(cfg.SignInRateLimit, cfg.SignInRateWindow),
 	}
 }
 
-// GET /api/v1/auth/signin/:provider - 跳转到 OAuth 提供商
+// GET /api/v1/auth/signin/:provider?redirect= - 跳转到 OAuth 提供商，登录成功后返回 redirect（须在白名单内）
 func (h *AuthHandler) SignIn(c *gin.Context) {
 	provider := c.Param("provider")
 
-	url, err := h.authService.GetAuthURL(c.Request.Context(), provider)
+	// 每次登录都会写入一条 oauth_states，按 IP 限流防止刷库
+	if ok, retryAfter := h.signInLimiter.Allow(c.ClientIP()); !ok {
+		utils.TooManyRequests(c, retryAfter, "too many sign-in attempts")
+		return
+	}
+
+	authURL, err := h.authService.GetAuthURL(c.Request.Context(), provider, h.allowedRedirect(c.Query("redirect")))
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
-	c.Redirect(http.StatusFound, url)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// GET /api/v1/auth/providers - 列出已启用的 OAuth 提供商，前端据此隐藏不可用的登录按钮
+func (h *AuthHandler) Providers(c *gin.Context) {
+	utils.Success(c, gin.H{
+		"providers": h.authService.EnabledProviders(),
+		"password":  true,
+	})
 }
 
 // GET /api/v1/auth/callback/:provider - OAuth 回调
@@ -49,43 +73,157 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 	code := c.Query("code")
 	state := c.Query("state")
 
+	if !slices.Contains(h.authService.EnabledProviders(), provider) {
+		c.Redirect(http.StatusFound, h.cfg.FrontendURL+"?error=provider_not_configured")
+		return
+	}
+
 	if code == "" {
 		c.Redirect(http.StatusFound, h.cfg.FrontendURL+"?error=missing_code")
 		return
 	}
 
-	// Validate CSRF state
-	if !h.authService.ValidateState(c.Request.Context(), state) {
+	// Validate CSRF state and recover the PKCE verifier
+	oauthState, ok := h.authService.ValidateState(c.Request.Context(), state)
+	if !ok {
 		c.Redirect(http.StatusFound, h.cfg.FrontendURL+"?error=invalid_state")
 		return
 	}
+	if oauthState.LinkUserID != "" {
+		h.finishLink(c, provider, code, oauthState)
+		return
+	}
 
-	user, err := h.authService.HandleCallback(c.Request.Context(), provider, code)
+	user, err := h.authService.HandleCallback(c.Request.Context(), provider, code, oauthState.CodeVerifier)
 	if err != nil {
 		c.Redirect(http.StatusFound, h.cfg.FrontendURL+"?error=auth_failed")
 		return
 	}
 
+	if user.IsDeleted() {
+		c.Redirect(http.StatusFound, h.cfg.FrontendURL+"?error=account_deleted")
+		return
+	}
+	if user.IsBanned() {
+		c.Redirect(http.StatusFound, h.cfg.FrontendURL+"?error=account_banned")
+		return
+	}
+
 	// 创建 session
-	token, err := h.sessionStore.Create(c.Request.Context(), user.ID, user.Role, SessionDuration)
+	token, err := h.sessionStore.Create(c.Request.Context(), user.ID, user.Role, h.cfg.SessionDuration, sessionClient(c))
 	if err != nil {
 		c.Redirect(http.StatusFound, h.cfg.FrontendURL+"?error=session_failed")
 		return
 	}
 
 	// 设置 Cookie
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie(
-		SessionCookieName,
-		token,
-		int(SessionDuration.Seconds()),
-		"/",
-		h.cfg.CookieDomain,
-		h.cfg.SecureCookie,
-		true, // HttpOnly
-	)
+	h.setSessionCookie(c, token)
 
-	c.Redirect(http.StatusFound, h.cfg.FrontendURL)
+	// 配置了 JWT_SECRET 时额外签发 JWT，通过 URL fragment 交给前端（不会发送到服务器日志）
+	// 白名单可能在发起登录后被修改，这里再校验一次
+	redirectURL := h.cfg.FrontendURL
+	if target := h.allowedRedirect(oauthState.Redirect); target != "" {
+		redirectURL = target
+	}
+	if jwtToken, err := h.authService.IssueJWT(user.ID, user.Role, h.cfg.SessionDuration); err == nil {
+		redirectURL += "#token=" + jwtToken
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// GET /api/v1/auth/link/:provider?redirect= - 已登录用户发起绑定第三方账号，回调与登录共用
+func (h *AuthHandler) Link(c *gin.Context) {
+	if ok, retryAfter := h.signInLimiter.Allow(c.ClientIP()); !ok {
+		utils.TooManyRequests(c, retryAfter, "too many sign-in attempts")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userOID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		utils.Unauthorized(c, "invalid user")
+		return
+	}
+
+	authURL, err := h.authService.GetLinkURL(c.Request.Context(), c.Param("provider"), userOID, h.allowedRedirect(c.Query("redirect")))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// finishLink 完成绑定流程，结果通过跳转的 linked / error 参数告知前端，不创建新的 session
+func (h *AuthHandler) finishLink(c *gin.Context, provider, code string, oauthState *model.OAuthState) {
+	redirectURL := h.cfg.FrontendURL
+	if target := h.allowedRedirect(oauthState.Redirect); target != "" {
+		redirectURL = target
+	}
+	target, err := url.Parse(redirectURL)
+	if err != nil {
+		c.Redirect(http.StatusFound, h.cfg.FrontendURL+"?error=link_failed")
+		return
+	}
+	query := target.Query()
+
+	// 回调必须来自发起绑定的同一个登录用户，防止诱导他人把自己的第三方账号绑定到攻击者账号上
+	if currentUserID, _ := c.Get("user_id"); currentUserID != oauthState.LinkUserID {
+		query.Set("error", "link_session_mismatch")
+		target.RawQuery = query.Encode()
+		c.Redirect(http.StatusFound, target.String())
+		return
+	}
+
+	_, err = h.authService.LinkSocial(c.Request.Context(), oauthState.LinkUserID, provider, code, oauthState.CodeVerifier)
+	switch {
+	case err == nil:
+		query.Set("linked", provider)
+	case errors.Is(err, service.ErrSocialLinkedToOther):
+		query.Set("error", "social_linked_to_other")
+	case errors.Is(err, service.ErrProviderLinked):
+		query.Set("error", "provider_already_linked")
+	default:
+		query.Set("error", "link_failed")
+	}
+	target.RawQuery = query.Encode()
+
+	c.Redirect(http.StatusFound, target.String())
+}
+
+// allowedRedirect 校验登录后的跳转地址：相对路径基于 FRONTEND_URL 解析，
+// 绝对地址的主机须为 FRONTEND_URL 的主机或在 REDIRECT_ALLOWED_HOSTS 中，且协议与 FRONTEND_URL 相同
+// （跳转地址会带上 JWT，不能降级到 http）；不合法时返回空字符串
+func (h *AuthHandler) allowedRedirect(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	base, err := url.Parse(h.cfg.FrontendURL)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	target := base.ResolveReference(ref)
+	if target.Scheme != base.Scheme || target.User != nil {
+		return ""
+	}
+	// fragment 留给 JWT 使用
+	target.Fragment = ""
+
+	if strings.EqualFold(target.Host, base.Host) {
+		return target.String()
+	}
+	for _, host := range h.cfg.RedirectAllowedHosts {
+		// 未写端口的条目匹配该主机的任意端口
+		if strings.EqualFold(target.Host, host) || !strings.Contains(host, ":") && strings.EqualFold(target.Hostname(), host) {
+			return target.String()
+		}
+	}
+	return ""
 }
 
 // GET /api/v1/auth/session - 获取当前用户信息
@@ -112,9 +250,411 @@ func (h *AuthHandler) SignOut(c *gin.Context) {
 		h.sessionStore.Delete(c.Request.Context(), token)
 	}
 
-	c.SetSameSite(http.SameSiteLaxMode)
+	h.clearSessionCookie(c)
+
+	utils.Success(c, nil)
+}
+
+// POST /api/v1/auth/refresh - 续期 session（滑动过期），旧 token 立即失效
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	oldToken, err := c.Cookie(SessionCookieName)
+	if err != nil {
+		utils.Unauthorized(c, "not authenticated")
+		return
+	}
+
+	token, err := h.sessionStore.Refresh(c.Request.Context(), oldToken, h.cfg.SessionDuration, sessionClient(c))
+	if err != nil {
+		if errors.Is(err, service.ErrSessionInvalid) {
+			h.clearSessionCookie(c)
+			utils.Unauthorized(c, "session expired")
+			return
+		}
+		// 旧 session 仍然有效，保留 cookie 以便重试
+		utils.InternalError(c, "failed to refresh session")
+		return
+	}
+
+	h.setSessionCookie(c, token)
+	utils.Success(c, gin.H{"expires_at": time.Now().Add(h.cfg.SessionDuration)})
+}
+
+// GET /api/v1/auth/sessions - 列出当前用户的登录会话
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userOID, ok := currentUserOID(c)
+	if !ok {
+		utils.Unauthorized(c, "not authenticated")
+		return
+	}
+	currentToken, _ := c.Cookie(SessionCookieName)
+
+	sessions, err := h.sessionStore.ListByUser(c.Request.Context(), userOID, currentToken)
+	if err != nil {
+		utils.InternalError(c, "failed to list sessions")
+		return
+	}
+	if sessions == nil {
+		sessions = []model.Session{}
+	}
+
+	utils.Success(c, sessions)
+}
+
+// DELETE /api/v1/auth/sessions/:id - 注销指定会话
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userOID, ok := currentUserOID(c)
+	if !ok {
+		utils.Unauthorized(c, "not authenticated")
+		return
+	}
+	sessionOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid session id")
+		return
+	}
+
+	deleted, err := h.sessionStore.Revoke(c.Request.Context(), userOID, sessionOID)
+	if err != nil {
+		utils.InternalError(c, "failed to revoke session")
+		return
+	}
+	if !deleted {
+		utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeSessionNotFound, "session not found")
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// POST /api/v1/auth/sessions/revoke-all - 注销除当前会话外的所有会话
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userOID, ok := currentUserOID(c)
+	if !ok {
+		utils.Unauthorized(c, "not authenticated")
+		return
+	}
+	currentToken, _ := c.Cookie(SessionCookieName)
+
+	revoked, err := h.sessionStore.RevokeOthers(c.Request.Context(), userOID, currentToken)
+	if err != nil {
+		utils.InternalError(c, "failed to revoke sessions")
+		return
+	}
+
+	utils.Success(c, gin.H{"revoked": revoked})
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Role      string     `json:"role" binding:"omitempty,oneof=admin editor user"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// POST /api/v1/auth/api-keys - 创建 API key，完整 key 仅在响应中返回一次
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	userOID, ok := currentUserOID(c)
+	if !ok {
+		utils.Unauthorized(c, "not authenticated")
+		return
+	}
+	userRole, _ := c.Get("user_role")
+
+	// Keys can never carry more privilege than their owner
+	role := userRole.(string)
+	if req.Role != "" && req.Role != role {
+		if model.UserRole(req.Role).Rank() > model.UserRole(role).Rank() {
+			utils.Forbidden(c, "cannot create a key with a higher role")
+			return
+		}
+		role = req.Role
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		utils.BadRequest(c, "expires_at must be in the future")
+		return
+	}
+
+	key, plain, err := h.apiKeyStore.Create(c.Request.Context(), userOID, req.Name, role, req.ExpiresAt)
+	if err != nil {
+		utils.InternalError(c, "failed to create api key")
+		return
+	}
+
+	utils.Created(c, gin.H{"api_key": key, "key": plain})
+}
+
+// GET /api/v1/auth/api-keys - 列出当前用户的 API key（不含明文）
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	userOID, ok := currentUserOID(c)
+	if !ok {
+		utils.Unauthorized(c, "not authenticated")
+		return
+	}
+
+	keys, err := h.apiKeyStore.List(c.Request.Context(), userOID)
+	if err != nil {
+		utils.InternalError(c, "failed to list api keys")
+		return
+	}
+	if keys == nil {
+		keys = []model.APIKey{}
+	}
+
+	utils.Success(c, keys)
+}
+
+// DELETE /api/v1/auth/api-keys/:id - 吊销 API key
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	userOID, ok := currentUserOID(c)
+	if !ok {
+		utils.Unauthorized(c, "not authenticated")
+		return
+	}
+	keyOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid api key id")
+		return
+	}
+
+	deleted, err := h.apiKeyStore.Revoke(c.Request.Context(), userOID, keyOID)
+	if err != nil {
+		utils.InternalError(c, "failed to revoke api key")
+		return
+	}
+	if !deleted {
+		utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeAPIKeyNotFound, "api key not found")
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+func sessionClient(c *gin.Context) model.SessionClient {
+	return model.SessionClient{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// currentUserOID 从认证中间件设置的 user_id 解析出 ObjectID
+func currentUserOID(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return primitive.NilObjectID, false
+	}
+	oid, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return oid, true
+}
+
+// setSessionCookie 写入 session cookie；配置了 COOKIE_DOMAIN 时在该域名下设置，便于跨子域共享
+func (h *AuthHandler) setSessionCookie(c *gin.Context, token string) {
+	c.SetSameSite(h.cfg.CookieSameSite)
+	c.SetCookie(
+		SessionCookieName,
+		token,
+		int(h.cfg.SessionDuration.Seconds()),
+		"/",
+		h.cfg.CookieDomain,
+		h.cfg.SecureCookie,
+		true, // HttpOnly
+	)
+}
+
+// clearSessionCookie 清除 session cookie，Domain 和 Path 必须与 setSessionCookie 一致，否则浏览器不会删除
+func (h *AuthHandler) clearSessionCookie(c *gin.Context) {
+	c.SetSameSite(h.cfg.CookieSameSite)
 	c.SetCookie(SessionCookieName, "", -1, "/", h.cfg.CookieDomain, h.cfg.SecureCookie, true)
+}
+
+type RegisterRequest struct {
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,min=8,max=72"` // bcrypt 只使用前 72 字节
+	Nickname string `json:"nickname" binding:"omitempty,max=50"`
+}
 
+// POST /api/v1/auth/register - 邮箱密码注册
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	user, err := h.authService.Register(c.Request.Context(), req.Email, req.Password, req.Nickname)
+	if err != nil {
+		if errors.Is(err, service.ErrEmailTaken) {
+			utils.Conflict(c, err.Error())
+			return
+		}
+		utils.InternalError(c, "failed to register")
+		return
+	}
+
+	if !h.startSession(c, user) {
+		return
+	}
+	utils.Created(c, gin.H{"user": user})
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,max=72"`
+}
+
+// POST /api/v1/auth/login - 邮箱密码登录
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	// 按邮箱限制尝试次数，防止暴力破解
+	if ok, retryAfter := h.loginLimiter.Allow(utils.NormalizeEmail(req.Email)); !ok {
+		utils.TooManyRequests(c, retryAfter, "too many login attempts")
+		return
+	}
+
+	user, err := h.authService.Login(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			utils.Unauthorized(c, err.Error())
+			return
+		}
+		utils.InternalError(c, "failed to login")
+		return
+	}
+
+	if !h.startSession(c, user) {
+		return
+	}
+	utils.Success(c, gin.H{"user": user})
+}
+
+type SetPasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"max=72"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+// PUT /api/v1/auth/password - 设置或修改密码（OAuth 用户也可借此启用邮箱登录）
+func (h *AuthHandler) SetPassword(c *gin.Context) {
+	var req SetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	user, err := h.authService.GetUserByID(c.Request.Context(), userID.(string))
+	if err != nil {
+		utils.InternalError(c, "failed to get user")
+		return
+	}
+	if user.Email == "" {
+		utils.BadRequest(c, "account has no email address")
+		return
+	}
+
+	if err := h.authService.SetPassword(c.Request.Context(), user, req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrPasswordMismatch) {
+			utils.Forbidden(c, err.Error())
+			return
+		}
+		utils.InternalError(c, "failed to set password")
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// DELETE /api/v1/auth/socials/:provider - 解绑第三方账号
+func (h *AuthHandler) UnlinkSocial(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	user, err := h.authService.GetUserByID(c.Request.Context(), userID.(string))
+	if err != nil {
+		utils.InternalError(c, "failed to get user")
+		return
+	}
+
+	socials, err := h.authService.UnlinkSocial(c.Request.Context(), user, c.Param("provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSocialNotLinked):
+			utils.NotFound(c, err.Error())
+		case errors.Is(err, service.ErrLastLoginMethod):
+			utils.Conflict(c, err.Error())
+		default:
+			utils.InternalError(c, "failed to unlink provider")
+		}
+		return
+	}
+
+	utils.Success(c, socials)
+}
+
+// startSession 创建 session 并写入 cookie，失败时已写入错误响应
+func (h *AuthHandler) startSession(c *gin.Context, user *model.User) bool {
+	if user.IsDeleted() {
+		utils.Unauthorized(c, "account has been deleted")
+		return false
+	}
+	if user.IsBanned() {
+		utils.Forbidden(c, "account is banned")
+		return false
+	}
+	token, err := h.sessionStore.Create(c.Request.Context(), user.ID, user.Role, h.cfg.SessionDuration, sessionClient(c))
+	if err != nil {
+		utils.InternalError(c, "failed to create session")
+		return false
+	}
+	h.setSessionCookie(c, token)
+	return true
+}
+
+type DeleteAccountRequest struct {
+	Anonymize *bool `json:"anonymize"` // 默认 true，false 时仅停用账号并保留个人资料
+}
+
+// DELETE /api/v1/auth/account - 注销当前账号，注销所有 session 和 API Key
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	var req DeleteAccountRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BindError(c, err)
+			return
+		}
+	}
+	anonymize := req.Anonymize == nil || *req.Anonymize
+
+	userID, _ := c.Get("user_id")
+	oid, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		utils.BadRequest(c, "invalid user id")
+		return
+	}
+
+	if err := h.authService.DeleteAccount(c.Request.Context(), oid, anonymize); err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeUserNotFound, "user not found")
+			return
+		}
+		utils.InternalError(c, "failed to delete account")
+		return
+	}
+	if err := h.sessionStore.RevokeAll(c.Request.Context(), oid); err != nil {
+		utils.InternalError(c, "failed to revoke sessions")
+		return
+	}
+
+	h.clearSessionCookie(c)
 	utils.Success(c, nil)
 }
 
@@ -127,7 +667,7 @@ type UpdateProfileRequest struct {
 func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
 		return
 	}
 
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"matter-core/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAllowedRedirect(t *testing.T) {
+	h := &AuthHandler{cfg: &config.Config{
+		FrontendURL:          "https://example.com",
+		RedirectAllowedHosts: []string{"app.example.com", "admin.example.com:8443"},
+	}}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"/posts/1?tab=comments#top", "https://example.com/posts/1?tab=comments"},
+		{"https://example.com/me", "https://example.com/me"},
+		{"https://app.example.com/x", "https://app.example.com/x"},
+		{"https://app.example.com:444/x", "https://app.example.com:444/x"},
+		{"https://admin.example.com:8443/x", "https://admin.example.com:8443/x"},
+		{"https://admin.example.com/x", ""},
+		{"http://app.example.com/x", ""}, // 不允许降级到 http
+		{"http://example.com/", ""},
+		{"//evil.com/x", ""},
+		{"https://evil.com", ""},
+		{"https://example.com.evil.com/", ""},
+		{"https://user@example.com/", ""},
+		{"javascript:alert(1)", ""},
+	}
+	for _, tt := range tests {
+		if got := h.allowedRedirect(tt.in); got != tt.want {
+			t.Errorf("allowedRedirect(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSessionCookieDomain(t *testing.T) {
+	h := &AuthHandler{cfg: &config.Config{
+		CookieDomain:    ".example.com",
+		SecureCookie:    true,
+		CookieSameSite:  http.SameSiteLaxMode,
+		SessionDuration: time.Hour,
+	}}
+
+	tests := []struct {
+		name       string
+		write      func(*gin.Context)
+		wantValue  string
+		wantMaxAge int
+	}{
+		{"set", func(c *gin.Context) { h.setSessionCookie(c, "token") }, "token", 3600},
+		{"clear", h.clearSessionCookie, "", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			tt.write(c)
+
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 {
+				t.Fatalf("got %d cookies, want 1", len(cookies))
+			}
+			ck := cookies[0]
+			// net/http 解析时会去掉 Domain 开头的点
+			if ck.Name != SessionCookieName || ck.Domain != "example.com" || ck.Path != "/" {
+				t.Errorf("cookie = %s domain=%q path=%q, want %s domain=%q path=%q",
+					ck.Name, ck.Domain, ck.Path, SessionCookieName, "example.com", "/")
+			}
+			if ck.Value != tt.wantValue || ck.MaxAge != tt.wantMaxAge {
+				t.Errorf("value=%q max-age=%d, want %q %d", ck.Value, ck.MaxAge, tt.wantValue, tt.wantMaxAge)
+			}
+			if !ck.Secure || !ck.HttpOnly || ck.SameSite != http.SameSiteLaxMode {
+				t.Errorf("secure=%v httponly=%v samesite=%v", ck.Secure, ck.HttpOnly, ck.SameSite)
+			}
+		})
+	}
+}
@@ -2,24 +2,32 @@ package handler
 
 import (
 	"context"
+	"net/http"
 	"strconv"
 	"time"
 
+	"matter-core/internal/config"
 	"matter-core/internal/model"
 	"matter-core/internal/repository"
+	"matter-core/internal/service"
 	"matter-core/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type CommentHandler struct {
-	mongoRepo *repository.MongoRepo
+	mongoRepo   repository.Repository
+	cfg         *config.Config
+	rateLimiter *service.RateLimiter
 }
 
-func NewCommentHandler(mongoRepo *repository.MongoRepo) *CommentHandler {
-	return &CommentHandler{mongoRepo: mongoRepo}
+func NewCommentHandler(mongoRepo repository.Repository, cfg *config.Config) *CommentHandler {
+	return &CommentHandler{
+		mongoRepo:   mongoRepo,
+		cfg:         cfg,
+		rateLimiter: service.NewRateLimiter(cfg.CommentRateLimit, cfg.CommentRateWindow),
+	}
 }
 
 type CreateCommentRequest struct {
@@ -32,12 +40,17 @@ type CreateCommentRequest struct {
 func (h *CommentHandler) Create(c *gin.Context) {
 	var req CreateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
 		return
 	}
 
 	userID, _ := c.Get("user_id")
 
+	if ok, retryAfter := h.rateLimiter.Allow(userID.(string)); !ok {
+		utils.TooManyRequests(c, retryAfter, "too many comments, please slow down")
+		return
+	}
+
 	entryOID, err := primitive.ObjectIDFromHex(req.EntryID)
 	if err != nil {
 		utils.BadRequest(c, "invalid entry_id")
@@ -47,22 +60,44 @@ func (h *CommentHandler) Create(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	// Verify entry exists
-	_, err = h.mongoRepo.GetEntryByID(ctx, entryOID)
+	// Verify entry exists (deleted entries are removed, so they 404 here)
+	entry, err := h.mongoRepo.GetEntryByID(ctx, entryOID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "entry not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeEntryNotFound, "entry not found")
 			return
 		}
 		utils.InternalError(c, "failed to verify entry")
 		return
 	}
+	// 草稿只有作者本人和 staff 可以评论，与 entry 的可见性一致
+	userRole, _ := c.Get("user_role")
+	if entry.Base.Draft && entry.AuthorID != userID.(string) && !isStaff(userRole) {
+		utils.Forbidden(c, "cannot comment on an unpublished entry")
+		return
+	}
+	var schema *model.Schema
+	if entry.CommentsEnabled == nil {
+		schema, err = h.mongoRepo.GetLatestSchema(ctx, entry.SchemaKey)
+		if err != nil && err != repository.ErrNotFound {
+			utils.InternalError(c, "failed to get schema")
+			return
+		}
+	}
+	if !entry.AcceptsComments(schema) {
+		utils.Forbidden(c, "comments are disabled for this entry")
+		return
+	}
 
 	comment := &model.Comment{
 		EntryID:    entryOID,
 		AuthorID:   userID.(string),
 		Content:    req.Content,
 		ReplyToUID: req.ReplyToUID,
+		Status:     model.CommentApproved,
+	}
+	if h.cfg.CommentModeration {
+		comment.Status = model.CommentPending
 	}
 
 	// Handle reply (two-level flat structure)
@@ -76,14 +111,19 @@ func (h *CommentHandler) Create(c *gin.Context) {
 		// Get parent comment to determine root_id
 		parentComment, err := h.mongoRepo.GetCommentByID(ctx, parentOID)
 		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				utils.NotFound(c, "parent comment not found")
+			if err == repository.ErrNotFound {
+				utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeCommentNotFound, "parent comment not found")
 				return
 			}
 			utils.InternalError(c, "failed to get parent comment")
 			return
 		}
 
+		if parentComment.EntryID != entryOID {
+			utils.BadRequest(c, "parent comment belongs to a different entry")
+			return
+		}
+
 		comment.ParentID = parentOID
 		// For two-level flat: if parent is already a reply, use its root_id; otherwise parent is the root
 		if parentComment.RootID.IsZero() {
@@ -91,6 +131,22 @@ func (h *CommentHandler) Create(c *gin.Context) {
 		} else {
 			comment.RootID = parentComment.RootID
 		}
+
+		// reply_to_uid 必须是同一线程中某条评论的作者
+		if req.ReplyToUID != "" {
+			inThread, err := h.mongoRepo.HasThreadCommentByAuthor(ctx, comment.RootID, req.ReplyToUID)
+			if err != nil {
+				utils.InternalError(c, "failed to verify reply_to_uid")
+				return
+			}
+			if !inThread {
+				utils.BadRequest(c, "reply_to_uid does not match any comment in this thread")
+				return
+			}
+		}
+	} else if req.ReplyToUID != "" {
+		utils.BadRequest(c, "reply_to_uid requires parent_id")
+		return
 	}
 
 	if err := h.mongoRepo.CreateComment(ctx, comment); err != nil {
@@ -124,13 +180,17 @@ func (h *CommentHandler) ListByEntry(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	comments, err := h.mongoRepo.GetCommentsByEntryPaginated(ctx, entryOID, limit, offset)
+	// 非管理员只能看到已审核通过的评论
+	userRole, _ := c.Get("user_role")
+	approvedOnly := !isStaff(userRole)
+
+	comments, err := h.mongoRepo.GetCommentsByEntryPaginated(ctx, entryOID, approvedOnly, limit, offset)
 	if err != nil {
 		utils.InternalError(c, "failed to list comments")
 		return
 	}
 
-	total, err := h.mongoRepo.CountCommentsByEntry(ctx, entryOID)
+	total, err := h.mongoRepo.CountRootCommentsByEntry(ctx, entryOID, approvedOnly)
 	if err != nil {
 		utils.InternalError(c, "failed to count comments")
 		return
@@ -139,10 +199,112 @@ func (h *CommentHandler) ListByEntry(c *gin.Context) {
 	if comments == nil {
 		comments = []model.CommentWithAuthor{}
 	}
+	h.markLiked(ctx, c, comments)
+	renderCommentsHTML(c, comments)
 
 	utils.SuccessWithPagination(c, comments, total, limit, offset)
 }
 
+// Replies 分页返回某条顶层评论下的回复
+func (h *CommentHandler) Replies(c *gin.Context) {
+	rootOID, err := primitive.ObjectIDFromHex(c.Param("root_id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid root_id")
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+	limit, _ := strconv.ParseInt(limitStr, 10, 64)
+	offset, _ := strconv.ParseInt(offsetStr, 10, 64)
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	root, err := h.mongoRepo.GetCommentByID(ctx, rootOID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeCommentNotFound, "comment not found")
+			return
+		}
+		utils.InternalError(c, "failed to get comment")
+		return
+	}
+	if !root.RootID.IsZero() {
+		utils.BadRequest(c, "comment is not a root comment")
+		return
+	}
+
+	userRole, _ := c.Get("user_role")
+	approvedOnly := !isStaff(userRole)
+
+	replies, err := h.mongoRepo.GetRepliesByRoot(ctx, rootOID, approvedOnly, limit, offset)
+	if err != nil {
+		utils.InternalError(c, "failed to list replies")
+		return
+	}
+
+	total, err := h.mongoRepo.CountRepliesByRoot(ctx, rootOID, approvedOnly)
+	if err != nil {
+		utils.InternalError(c, "failed to count replies")
+		return
+	}
+
+	if replies == nil {
+		replies = []model.CommentWithAuthor{}
+	}
+	h.markLiked(ctx, c, replies)
+	renderCommentsHTML(c, replies)
+
+	utils.SuccessWithPagination(c, replies, total, limit, offset)
+}
+
+type CommentCountsRequest struct {
+	EntryIDs []string `json:"entry_ids" binding:"required,max=100"`
+}
+
+// Counts 批量返回多篇 entry 的评论数，避免列表页 N+1 请求
+func (h *CommentHandler) Counts(c *gin.Context) {
+	var req CommentCountsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	oids := make([]primitive.ObjectID, 0, len(req.EntryIDs))
+	for _, id := range req.EntryIDs {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			utils.BadRequest(c, "invalid entry id: "+id)
+			return
+		}
+		oids = append(oids, oid)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	counts, err := h.mongoRepo.CountCommentsByEntries(ctx, oids)
+	if err != nil {
+		utils.InternalError(c, "failed to count comments")
+		return
+	}
+
+	result := make(map[string]int64, len(oids))
+	for _, oid := range oids {
+		result[oid.Hex()] = counts[oid]
+	}
+
+	utils.Success(c, result)
+}
+
 type UpdateCommentRequest struct {
 	Content string `json:"content" binding:"required,min=1,max=5000"`
 }
@@ -157,7 +319,7 @@ func (h *CommentHandler) Update(c *gin.Context) {
 
 	var req UpdateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
 		return
 	}
 
@@ -166,8 +328,8 @@ func (h *CommentHandler) Update(c *gin.Context) {
 
 	comment, err := h.mongoRepo.GetCommentByID(ctx, oid)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "comment not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeCommentNotFound, "comment not found")
 			return
 		}
 		utils.InternalError(c, "failed to get comment")
@@ -181,7 +343,19 @@ func (h *CommentHandler) Update(c *gin.Context) {
 		return
 	}
 
+	// 超出编辑窗口后不允许再修改
+	if h.cfg.CommentEditWindow > 0 && time.Since(comment.CreatedAt) > h.cfg.CommentEditWindow {
+		utils.Forbidden(c, "edit window has expired")
+		return
+	}
+
+	if comment.Deleted {
+		utils.BadRequest(c, "comment has been deleted")
+		return
+	}
+
 	comment.Content = req.Content
+	comment.Edited = true
 	if err := h.mongoRepo.UpdateComment(ctx, comment); err != nil {
 		utils.InternalError(c, "failed to update comment")
 		return
@@ -204,8 +378,8 @@ func (h *CommentHandler) Delete(c *gin.Context) {
 	// Get comment to check ownership
 	comment, err := h.mongoRepo.GetCommentByID(ctx, oid)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "comment not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeCommentNotFound, "comment not found")
 			return
 		}
 		utils.InternalError(c, "failed to get comment")
@@ -215,18 +389,37 @@ func (h *CommentHandler) Delete(c *gin.Context) {
 	// Check ownership or admin
 	userID, _ := c.Get("user_id")
 	userRole, _ := c.Get("user_role")
-	if comment.AuthorID != userID.(string) && userRole != "admin" {
+	if comment.AuthorID != userID.(string) && !isStaff(userRole) {
 		utils.Forbidden(c, "not authorized to delete this comment")
 		return
 	}
 
-	// Delete comment and its replies (if this is a root comment)
+	// 管理员可通过 ?hard=true 直接删除整个线程
+	hard := c.Query("hard") == "true" && isStaff(userRole)
+
 	if comment.RootID.IsZero() {
-		// This is a root comment, delete all replies first
-		if err := h.mongoRepo.DeleteCommentsByRootID(ctx, oid); err != nil {
-			utils.InternalError(c, "failed to delete replies")
+		replyCount, err := h.mongoRepo.CountRepliesByRoot(ctx, oid, false)
+		if err != nil {
+			utils.InternalError(c, "failed to count replies")
+			return
+		}
+
+		if replyCount > 0 && !hard {
+			// Keep other users' replies: tombstone the root instead of removing it
+			if err := h.mongoRepo.SoftDeleteComment(ctx, oid); err != nil {
+				utils.InternalError(c, "failed to delete comment")
+				return
+			}
+			utils.Success(c, gin.H{"deleted": true, "soft": true})
 			return
 		}
+
+		if replyCount > 0 {
+			if err := h.mongoRepo.DeleteCommentsByRootID(ctx, oid); err != nil {
+				utils.InternalError(c, "failed to delete replies")
+				return
+			}
+		}
 	}
 
 	if err := h.mongoRepo.DeleteComment(ctx, oid); err != nil {
@@ -236,3 +429,240 @@ func (h *CommentHandler) Delete(c *gin.Context) {
 
 	utils.Success(c, nil)
 }
+
+// ListPending 返回待审核评论队列（管理员）
+func (h *CommentHandler) ListPending(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+	limit, _ := strconv.ParseInt(limitStr, 10, 64)
+	offset, _ := strconv.ParseInt(offsetStr, 10, 64)
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	comments, err := h.mongoRepo.GetPendingComments(ctx, limit, offset)
+	if err != nil {
+		utils.InternalError(c, "failed to list pending comments")
+		return
+	}
+
+	total, err := h.mongoRepo.CountPendingComments(ctx)
+	if err != nil {
+		utils.InternalError(c, "failed to count pending comments")
+		return
+	}
+
+	if comments == nil {
+		comments = []model.CommentWithAuthor{}
+	}
+
+	utils.SuccessWithPagination(c, comments, total, limit, offset)
+}
+
+func (h *CommentHandler) Approve(c *gin.Context) {
+	h.setStatus(c, model.CommentApproved)
+}
+
+func (h *CommentHandler) Reject(c *gin.Context) {
+	h.setStatus(c, model.CommentRejected)
+}
+
+func (h *CommentHandler) setStatus(c *gin.Context, status model.CommentStatus) {
+	id := c.Param("id")
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		utils.BadRequest(c, "invalid comment id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	comment, err := h.mongoRepo.GetCommentByID(ctx, oid)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeCommentNotFound, "comment not found")
+			return
+		}
+		utils.InternalError(c, "failed to get comment")
+		return
+	}
+
+	if err := h.mongoRepo.UpdateCommentStatus(ctx, oid, status); err != nil {
+		utils.InternalError(c, "failed to update comment status")
+		return
+	}
+	comment.Status = status
+
+	utils.Success(c, comment)
+}
+
+func (h *CommentHandler) Like(c *gin.Context) {
+	h.vote(c, true)
+}
+
+func (h *CommentHandler) Unlike(c *gin.Context) {
+	h.vote(c, false)
+}
+
+func (h *CommentHandler) vote(c *gin.Context, like bool) {
+	id := c.Param("id")
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		utils.BadRequest(c, "invalid comment id")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, err := h.mongoRepo.GetCommentByID(ctx, oid); err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeCommentNotFound, "comment not found")
+			return
+		}
+		utils.InternalError(c, "failed to get comment")
+		return
+	}
+
+	if like {
+		_, err = h.mongoRepo.LikeComment(ctx, oid, userID.(string))
+	} else {
+		_, err = h.mongoRepo.UnlikeComment(ctx, oid, userID.(string))
+	}
+	if err != nil {
+		utils.InternalError(c, "failed to update like")
+		return
+	}
+
+	comment, err := h.mongoRepo.GetCommentByID(ctx, oid)
+	if err != nil {
+		utils.InternalError(c, "failed to get comment")
+		return
+	}
+
+	utils.Success(c, gin.H{"liked": like, "like_count": comment.LikeCount})
+}
+
+// markLiked 为已登录用户标记其点赞过的评论，失败时静默忽略
+func (h *CommentHandler) markLiked(ctx context.Context, c *gin.Context, comments []model.CommentWithAuthor) {
+	userID, exists := c.Get("user_id")
+	if !exists || len(comments) == 0 {
+		return
+	}
+	ids := make([]primitive.ObjectID, 0, len(comments))
+	for _, cm := range comments {
+		ids = append(ids, cm.ID)
+	}
+	liked, err := h.mongoRepo.GetLikedCommentIDs(ctx, userID.(string), ids)
+	if err != nil {
+		return
+	}
+	for i := range comments {
+		comments[i].Liked = liked[comments[i].ID]
+	}
+}
+
+type ReportCommentRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+func (h *CommentHandler) Report(c *gin.Context) {
+	id := c.Param("id")
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		utils.BadRequest(c, "invalid comment id")
+		return
+	}
+
+	var req ReportCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, err := h.mongoRepo.GetCommentByID(ctx, oid); err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeCommentNotFound, "comment not found")
+			return
+		}
+		utils.InternalError(c, "failed to get comment")
+		return
+	}
+
+	report := &model.CommentReport{
+		CommentID: oid,
+		UserID:    userID.(string),
+		Reason:    req.Reason,
+	}
+	created, err := h.mongoRepo.CreateCommentReport(ctx, report)
+	if err != nil {
+		utils.InternalError(c, "failed to report comment")
+		return
+	}
+	if !created {
+		utils.Conflict(c, "comment already reported")
+		return
+	}
+
+	utils.Created(c, report)
+}
+
+// ListReported 返回举报数达到阈值的评论（管理员）
+func (h *CommentHandler) ListReported(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+	minStr := c.DefaultQuery("min_reports", "1")
+	limit, _ := strconv.ParseInt(limitStr, 10, 64)
+	offset, _ := strconv.ParseInt(offsetStr, 10, 64)
+	minReports, _ := strconv.ParseInt(minStr, 10, 64)
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if minReports <= 0 {
+		minReports = 1
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	reported, err := h.mongoRepo.GetReportedComments(ctx, minReports, limit, offset)
+	if err != nil {
+		utils.InternalError(c, "failed to list reported comments")
+		return
+	}
+
+	if reported == nil {
+		reported = []model.ReportedComment{}
+	}
+
+	utils.Success(c, reported)
+}
+
+// renderCommentsHTML 在 ?format=html 时附带安全渲染后的 HTML 内容
+func renderCommentsHTML(c *gin.Context, comments []model.CommentWithAuthor) {
+	if c.Query("format") != "html" {
+		return
+	}
+	for i := range comments {
+		comments[i].ContentHTML = service.RenderMarkdown(comments[i].Content)
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"matter-core/internal/config"
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func asUser(userID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("user_role", "user")
+	}
+}
+
+func TestCommentCreateReplies(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	entryA := &model.Entry{SchemaKey: "post"}
+	entryB := &model.Entry{SchemaKey: "post"}
+	for _, entry := range []*model.Entry{entryA, entryB} {
+		if err := repo.CreateEntry(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rootA := &model.Comment{EntryID: entryA.ID, AuthorID: "alice", Content: "root", Status: model.CommentApproved}
+	if err := repo.CreateComment(ctx, rootA); err != nil {
+		t.Fatal(err)
+	}
+	replyA := &model.Comment{EntryID: entryA.ID, AuthorID: "bob", Content: "reply", RootID: rootA.ID, ParentID: rootA.ID, Status: model.CommentApproved}
+	rootB := &model.Comment{EntryID: entryB.ID, AuthorID: "carol", Content: "elsewhere", Status: model.CommentApproved}
+	for _, comment := range []*model.Comment{replyA, rootB} {
+		if err := repo.CreateComment(ctx, comment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewCommentHandler(repo, &config.Config{})
+
+	tests := []struct {
+		name     string
+		req      CreateCommentRequest
+		wantCode int
+		wantMsg  string
+	}{
+		{"reply to root", CreateCommentRequest{EntryID: entryA.ID.Hex(), Content: "hi", ParentID: rootA.ID.Hex(), ReplyToUID: "alice"}, http.StatusCreated, "created"},
+		{"reply to reply", CreateCommentRequest{EntryID: entryA.ID.Hex(), Content: "hi", ParentID: replyA.ID.Hex(), ReplyToUID: "bob"}, http.StatusCreated, "created"},
+		{"parent from another entry", CreateCommentRequest{EntryID: entryB.ID.Hex(), Content: "hi", ParentID: rootA.ID.Hex()}, http.StatusBadRequest, "parent comment belongs to a different entry"},
+		{"reply_to from another entry", CreateCommentRequest{EntryID: entryA.ID.Hex(), Content: "hi", ParentID: rootA.ID.Hex(), ReplyToUID: "carol"}, http.StatusBadRequest, "reply_to_uid does not match any comment in this thread"},
+		{"reply_to without parent", CreateCommentRequest{EntryID: entryA.ID.Hex(), Content: "hi", ReplyToUID: "alice"}, http.StatusBadRequest, "reply_to_uid requires parent_id"},
+		{"missing parent", CreateCommentRequest{EntryID: entryA.ID.Hex(), Content: "hi", ParentID: primitive.NewObjectID().Hex()}, http.StatusNotFound, "parent comment not found"},
+		{"malformed parent", CreateCommentRequest{EntryID: entryA.ID.Hex(), Content: "hi", ParentID: "nope"}, http.StatusBadRequest, "invalid parent_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := serveJSON(t, http.MethodPost, tt.req, asUser("dave"), h.Create)
+			if code != tt.wantCode || resp.Message != tt.wantMsg {
+				t.Fatalf("Create() = %d %q, want %d %q", code, resp.Message, tt.wantCode, tt.wantMsg)
+			}
+			if data, ok := resp.Data.(map[string]any); ok && data["root_id"] != rootA.ID.Hex() {
+				t.Errorf("root_id = %v, want %s", data["root_id"], rootA.ID.Hex())
+			}
+		})
+	}
+}
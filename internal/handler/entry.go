@@ -1,10 +1,19 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"matter-core/internal/config"
 	"matter-core/internal/model"
 	"matter-core/internal/repository"
 	"matter-core/internal/service"
@@ -12,27 +21,29 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type EntryHandler struct {
-	mongoRepo *repository.MongoRepo
+	mongoRepo repository.Repository
 	meiliRepo *repository.MeiliRepo
 	validator *service.SchemaValidator
 	syncSvc   *service.SyncService
+	cfg       *config.Config
 }
 
 func NewEntryHandler(
-	mongoRepo *repository.MongoRepo,
+	mongoRepo repository.Repository,
 	meiliRepo *repository.MeiliRepo,
 	validator *service.SchemaValidator,
 	syncSvc *service.SyncService,
+	cfg *config.Config,
 ) *EntryHandler {
 	return &EntryHandler{
 		mongoRepo: mongoRepo,
 		meiliRepo: meiliRepo,
 		validator: validator,
 		syncSvc:   syncSvc,
+		cfg:       cfg,
 	}
 }
 
@@ -41,14 +52,18 @@ type CreateEntryRequest struct {
 	Title      string         `json:"title" binding:"required,max=200"`
 	Slug       string         `json:"slug" binding:"max=200"`
 	Body       string         `json:"body" binding:"max=100000"`
+	BodyFormat string         `json:"body_format" binding:"omitempty,oneof=markdown html plain"`           // 默认 markdown
+	Status     string         `json:"status" binding:"omitempty,oneof=draft in_review published archived"` // 优先于 draft
 	Draft      bool           `json:"draft"`
 	Attributes map[string]any `json:"attributes"`
+	// CommentsEnabled 留空时沿用 schema 的 comments_disabled 设置
+	CommentsEnabled *bool `json:"comments_enabled"`
 }
 
 func (h *EntryHandler) Create(c *gin.Context) {
 	var req CreateEntryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
 		return
 	}
 
@@ -59,8 +74,8 @@ func (h *EntryHandler) Create(c *gin.Context) {
 
 	schema, err := h.mongoRepo.GetLatestSchema(ctx, req.SchemaKey)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "schema not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeSchemaNotFound, "schema not found")
 			return
 		}
 		utils.InternalError(c, "failed to get schema")
@@ -71,8 +86,11 @@ func (h *EntryHandler) Create(c *gin.Context) {
 		req.Attributes = make(map[string]interface{})
 	}
 
-	if err := h.validator.ValidateEntry(*schema, req.Attributes); err != nil {
-		utils.BadRequest(c, err.Error())
+	if err := h.validator.ValidateEntry(ctx, *schema, req.Attributes); err != nil {
+		validationFailed(c, err)
+		return
+	}
+	if !h.checkUnique(ctx, c, schema, req.Attributes, primitive.NilObjectID) {
 		return
 	}
 
@@ -82,15 +100,22 @@ func (h *EntryHandler) Create(c *gin.Context) {
 		SchemaVersion: schema.Version,
 		AuthorID:      userID.(string),
 		Base: model.BaseMeta{
-			Title: req.Title,
-			Slug:  req.Slug,
-			Draft: req.Draft,
+			Title:  req.Title,
+			Slug:   req.Slug,
+			Status: model.EntryStatus(req.Status),
+			Draft:  req.Draft,
 		},
-		Body:       req.Body,
-		Attributes: req.Attributes,
+		Body:            req.Body,
+		BodyFormat:      bodyFormat(req.BodyFormat),
+		Attributes:      req.Attributes,
+		CommentsEnabled: req.CommentsEnabled,
 	}
 
 	if err := h.mongoRepo.CreateEntry(ctx, entry); err != nil {
+		if err == repository.ErrConflict {
+			uniqueConflict(c, nil)
+			return
+		}
 		utils.InternalError(c, "failed to create entry")
 		return
 	}
@@ -103,12 +128,166 @@ func (h *EntryHandler) Create(c *gin.Context) {
 	utils.Created(c, entry)
 }
 
+// bodyFormat 未指定时默认为 markdown，取值已由 binding 的 oneof 校验
+func bodyFormat(format string) model.BodyFormat {
+	if format == "" {
+		return model.BodyMarkdown
+	}
+	return model.BodyFormat(format)
+}
+
+// checkUnique 检查 Unique 字段是否与同 schema 的其他 entry 重复，重复时写入 409 并返回 false
+func (h *EntryHandler) checkUnique(ctx context.Context, c *gin.Context, schema *model.Schema, attributes map[string]any, excludeID primitive.ObjectID) bool {
+	conflicts, err := h.uniqueConflicts(ctx, schema, attributes, excludeID)
+	if err != nil {
+		utils.InternalError(c, "failed to check unique fields")
+		return false
+	}
+	if len(conflicts) > 0 {
+		uniqueConflict(c, conflicts)
+		return false
+	}
+	return true
+}
+
+func (h *EntryHandler) uniqueConflicts(ctx context.Context, schema *model.Schema, attributes map[string]any, excludeID primitive.ObjectID) ([]utils.FieldError, error) {
+	values := make(map[string]any)
+	for _, field := range schema.Fields {
+		if value, ok := attributes[field.Key]; field.Unique && ok && value != nil {
+			values[field.Key] = value
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	keys, err := h.mongoRepo.FindUniqueConflicts(ctx, schema.Key, values, excludeID)
+	if err != nil {
+		return nil, err
+	}
+	conflicts := make([]utils.FieldError, len(keys))
+	for i, key := range keys {
+		conflicts[i] = utils.FieldError{Field: key, Message: "must be unique"}
+	}
+	return conflicts, nil
+}
+
+// uniqueConflict 响应唯一字段冲突；details 为空表示检查后写入时才被唯一索引拒绝（并发写入）
+func uniqueConflict(c *gin.Context, details any) {
+	utils.ErrorWithDetails(c, http.StatusConflict, utils.CodeConflict, "duplicate value for unique field", details)
+}
+
+// validationFailed 将 *service.ValidationError 的逐字段错误放入响应的 details
+func validationFailed(c *gin.Context, err error) {
+	var verr *service.ValidationError
+	if errors.As(err, &verr) {
+		utils.ErrorWithDetails(c, http.StatusBadRequest, utils.CodeValidationFailed, "validation failed", verr.Errors)
+		return
+	}
+	utils.InternalError(c, "failed to validate entry")
+}
+
+type BulkCreateEntriesRequest struct {
+	Entries []CreateEntryRequest `json:"entries" binding:"required,min=1,max=100,dive"`
+}
+
+// POST /api/v1/entries/bulk - 批量创建 entry，全部校验通过后才写入，并一次性同步到搜索引擎
+func (h *EntryHandler) BulkCreate(c *gin.Context) {
+	var req BulkCreateEntriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	schemas := make(map[string]*model.Schema)
+	entries := make([]*model.Entry, 0, len(req.Entries))
+	for i, item := range req.Entries {
+		schema, ok := schemas[item.SchemaKey]
+		if !ok {
+			var err error
+			schema, err = h.mongoRepo.GetLatestSchema(ctx, item.SchemaKey)
+			if err != nil {
+				if err == repository.ErrNotFound {
+					utils.ErrorWithDetails(c, http.StatusNotFound, utils.CodeSchemaNotFound, "schema not found", gin.H{"index": i})
+					return
+				}
+				utils.InternalError(c, "failed to get schema")
+				return
+			}
+			schemas[item.SchemaKey] = schema
+		}
+
+		if item.Attributes == nil {
+			item.Attributes = make(map[string]interface{})
+		}
+		if err := h.validator.ValidateEntry(ctx, *schema, item.Attributes); err != nil {
+			var verr *service.ValidationError
+			if !errors.As(err, &verr) {
+				utils.InternalError(c, "failed to validate entry")
+				return
+			}
+			utils.ErrorWithDetails(c, http.StatusBadRequest, utils.CodeValidationFailed, "validation failed", gin.H{"index": i, "errors": verr.Errors})
+			return
+		}
+		conflicts, err := h.uniqueConflicts(ctx, schema, item.Attributes, primitive.NilObjectID)
+		if err != nil {
+			utils.InternalError(c, "failed to check unique fields")
+			return
+		}
+		if len(conflicts) > 0 {
+			uniqueConflict(c, gin.H{"index": i, "errors": conflicts})
+			return
+		}
+
+		entries = append(entries, &model.Entry{
+			SchemaID:      schema.ID,
+			SchemaKey:     schema.Key,
+			SchemaVersion: schema.Version,
+			AuthorID:      userID.(string),
+			Base: model.BaseMeta{
+				Title:  item.Title,
+				Slug:   item.Slug,
+				Status: model.EntryStatus(item.Status),
+				Draft:  item.Draft,
+			},
+			Body:            item.Body,
+			BodyFormat:      bodyFormat(item.BodyFormat),
+			Attributes:      item.Attributes,
+			CommentsEnabled: item.CommentsEnabled,
+		})
+	}
+
+	if err := h.mongoRepo.CreateEntries(ctx, entries); err != nil {
+		// Also catches duplicates within the batch itself
+		if err == repository.ErrConflict {
+			uniqueConflict(c, nil)
+			return
+		}
+		utils.InternalError(c, "failed to create entries")
+		return
+	}
+
+	// One indexing request for the whole batch
+	if h.syncSvc != nil {
+		h.syncSvc.SyncEntriesAsync(entries)
+	}
+
+	utils.Created(c, entries)
+}
+
 type UpdateEntryRequest struct {
-	Title      *string        `json:"title" binding:"omitempty,max=200"`
-	Slug       *string        `json:"slug" binding:"omitempty,max=200"`
-	Body       *string        `json:"body" binding:"omitempty,max=100000"`
-	Draft      *bool          `json:"draft"`
-	Attributes map[string]any `json:"attributes"`
+	Title           *string        `json:"title" binding:"omitempty,max=200"`
+	Slug            *string        `json:"slug" binding:"omitempty,max=200"`
+	Body            *string        `json:"body" binding:"omitempty,max=100000"`
+	BodyFormat      *string        `json:"body_format" binding:"omitempty,oneof=markdown html plain"`
+	Status          *string        `json:"status" binding:"omitempty,oneof=draft in_review published archived"`
+	Draft           *bool          `json:"draft"`
+	Attributes      map[string]any `json:"attributes"`
+	CommentsEnabled *bool          `json:"comments_enabled"`
 }
 
 func (h *EntryHandler) Update(c *gin.Context) {
@@ -121,7 +300,7 @@ func (h *EntryHandler) Update(c *gin.Context) {
 
 	var req UpdateEntryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
 		return
 	}
 
@@ -130,8 +309,8 @@ func (h *EntryHandler) Update(c *gin.Context) {
 
 	entry, err := h.mongoRepo.GetEntryByID(ctx, oid)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "entry not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeEntryNotFound, "entry not found")
 			return
 		}
 		utils.InternalError(c, "failed to get entry")
@@ -141,7 +320,7 @@ func (h *EntryHandler) Update(c *gin.Context) {
 	// Check ownership or admin
 	userID, _ := c.Get("user_id")
 	userRole, _ := c.Get("user_role")
-	if entry.AuthorID != userID.(string) && userRole != "admin" {
+	if entry.AuthorID != userID.(string) && !isStaff(userRole) {
 		utils.Forbidden(c, "not authorized to update this entry")
 		return
 	}
@@ -156,8 +335,20 @@ func (h *EntryHandler) Update(c *gin.Context) {
 	if req.Body != nil {
 		entry.Body = *req.Body
 	}
-	if req.Draft != nil {
+	if req.BodyFormat != nil {
+		entry.BodyFormat = model.BodyFormat(*req.BodyFormat)
+	} else if entry.BodyFormat == "" {
+		entry.BodyFormat = model.BodyMarkdown
+	}
+	if req.Status != nil {
+		entry.Base.Status = model.EntryStatus(*req.Status)
+	} else if req.Draft != nil {
+		// 旧客户端只传 draft：由 draft 重新推导 status
 		entry.Base.Draft = *req.Draft
+		entry.Base.Status = ""
+	}
+	if req.CommentsEnabled != nil {
+		entry.CommentsEnabled = req.CommentsEnabled
 	}
 	if req.Attributes != nil {
 		schema, err := h.mongoRepo.GetSchemaByID(ctx, entry.SchemaID)
@@ -165,18 +356,77 @@ func (h *EntryHandler) Update(c *gin.Context) {
 			utils.InternalError(c, "failed to get schema")
 			return
 		}
-		if err := h.validator.ValidateEntry(*schema, req.Attributes); err != nil {
-			utils.BadRequest(c, err.Error())
+		if err := h.validator.ValidateEntry(ctx, *schema, req.Attributes); err != nil {
+			validationFailed(c, err)
+			return
+		}
+		if !h.checkUnique(ctx, c, schema, req.Attributes, entry.ID) {
 			return
 		}
 		entry.Attributes = req.Attributes
 	}
 
+	if err := h.mongoRepo.UpdateEntry(ctx, entry); err != nil {
+		if err == repository.ErrConflict {
+			uniqueConflict(c, nil)
+			return
+		}
+		utils.InternalError(c, "failed to update entry")
+		return
+	}
+
+	if h.syncSvc != nil {
+		h.syncSvc.SyncEntryAsync(entry)
+	}
+
+	utils.Success(c, entry)
+}
+
+type UpdateEntryStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=draft in_review published archived"`
+}
+
+// POST /api/v1/entries/:id/status - 切换 entry 状态，权限与修改 entry 相同（作者本人或 staff）
+func (h *EntryHandler) UpdateStatus(c *gin.Context) {
+	oid, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid entry id")
+		return
+	}
+
+	var req UpdateEntryStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	entry, err := h.mongoRepo.GetEntryByID(ctx, oid)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeEntryNotFound, "entry not found")
+			return
+		}
+		utils.InternalError(c, "failed to get entry")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+	if entry.AuthorID != userID.(string) && !isStaff(userRole) {
+		utils.Forbidden(c, "not authorized to update this entry")
+		return
+	}
+
+	entry.Base.Status = model.EntryStatus(req.Status)
 	if err := h.mongoRepo.UpdateEntry(ctx, entry); err != nil {
 		utils.InternalError(c, "failed to update entry")
 		return
 	}
 
+	// 发布时写入索引，其他状态时从索引移除
 	if h.syncSvc != nil {
 		h.syncSvc.SyncEntryAsync(entry)
 	}
@@ -197,8 +447,8 @@ func (h *EntryHandler) Delete(c *gin.Context) {
 
 	entry, err := h.mongoRepo.GetEntryByID(ctx, oid)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "entry not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeEntryNotFound, "entry not found")
 			return
 		}
 		utils.InternalError(c, "failed to get entry")
@@ -207,7 +457,7 @@ func (h *EntryHandler) Delete(c *gin.Context) {
 
 	userID, _ := c.Get("user_id")
 	userRole, _ := c.Get("user_role")
-	if entry.AuthorID != userID.(string) && userRole != "admin" {
+	if entry.AuthorID != userID.(string) && !isStaff(userRole) {
 		utils.Forbidden(c, "not authorized to delete this entry")
 		return
 	}
@@ -224,6 +474,117 @@ func (h *EntryHandler) Delete(c *gin.Context) {
 	utils.Success(c, nil)
 }
 
+type TransferEntryRequest struct {
+	NewAuthorID string `json:"new_author_id" binding:"required"`
+}
+
+// POST /api/v1/entries/:id/transfer - 转移 entry 的作者（仅管理员）
+func (h *EntryHandler) Transfer(c *gin.Context) {
+	oid, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid entry id")
+		return
+	}
+
+	var req TransferEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	entry, err := h.mongoRepo.GetEntryByID(ctx, oid)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeEntryNotFound, "entry not found")
+			return
+		}
+		utils.InternalError(c, "failed to get entry")
+		return
+	}
+
+	if !h.checkTransferTarget(ctx, c, req.NewAuthorID) {
+		return
+	}
+
+	// 转移记录随 entry 一起保存，日志便于运维追踪
+	operatorID := c.GetString("user_id")
+	entry.Transfers = append(entry.Transfers, model.OwnershipTransfer{
+		FromAuthorID: entry.AuthorID,
+		ToAuthorID:   req.NewAuthorID,
+		OperatorID:   operatorID,
+		At:           time.Now(),
+	})
+	entry.AuthorID = req.NewAuthorID
+	if err := h.mongoRepo.UpdateEntry(ctx, entry); err != nil {
+		utils.InternalError(c, "failed to update entry")
+		return
+	}
+	log.Printf("entry %s transferred to %s by %s", entry.ID.Hex(), entry.AuthorID, operatorID)
+
+	utils.Success(c, entry)
+}
+
+type TransferEntriesRequest struct {
+	FromAuthorID string `json:"from_author_id" binding:"required"`
+	NewAuthorID  string `json:"new_author_id" binding:"required"`
+}
+
+// POST /api/v1/entries/transfer - 将某个作者的全部 entry 转给另一个用户（仅管理员）
+func (h *EntryHandler) TransferByAuthor(c *gin.Context) {
+	var req TransferEntriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if req.FromAuthorID == req.NewAuthorID {
+		utils.BadRequest(c, "from_author_id and new_author_id must differ")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if !h.checkTransferTarget(ctx, c, req.NewAuthorID) {
+		return
+	}
+
+	operatorID := c.GetString("user_id")
+	transferred, err := h.mongoRepo.TransferEntries(ctx, req.FromAuthorID, req.NewAuthorID, operatorID)
+	if err != nil {
+		utils.InternalError(c, "failed to transfer entries")
+		return
+	}
+	log.Printf("%d entries transferred from %s to %s by %s", transferred, req.FromAuthorID, req.NewAuthorID, operatorID)
+
+	utils.Success(c, gin.H{"transferred": transferred})
+}
+
+// checkTransferTarget 确认转移目标用户存在且未注销；不满足时已写入错误响应
+func (h *EntryHandler) checkTransferTarget(ctx context.Context, c *gin.Context, userID string) bool {
+	userOID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		utils.BadRequest(c, "invalid new_author_id")
+		return false
+	}
+	user, err := h.mongoRepo.GetUserByID(ctx, userOID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeUserNotFound, "user not found")
+			return false
+		}
+		utils.InternalError(c, "failed to get user")
+		return false
+	}
+	if user.IsDeleted() {
+		utils.BadRequest(c, "cannot transfer entries to a deleted user")
+		return false
+	}
+	return true
+}
+
 func (h *EntryHandler) Get(c *gin.Context) {
 	id := c.Param("id")
 	oid, err := primitive.ObjectIDFromHex(id)
@@ -231,26 +592,290 @@ func (h *EntryHandler) Get(c *gin.Context) {
 		utils.BadRequest(c, "invalid entry id")
 		return
 	}
+	fields, err := parseEntryFields(c.Query("fields"))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
 	entry, err := h.mongoRepo.GetEntryByID(ctx, oid)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "entry not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeEntryNotFound, "entry not found")
 			return
 		}
 		utils.InternalError(c, "failed to get entry")
 		return
 	}
 
-	utils.Success(c, entry)
+	h.respondEntry(ctx, c, entry, fields)
+}
+
+// GET /api/v1/entries/slug/:schema_key/:slug - 按 slug 获取 entry，不区分大小写和重音
+func (h *EntryHandler) GetBySlug(c *gin.Context) {
+	fields, err := parseEntryFields(c.Query("fields"))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	entry, err := h.mongoRepo.GetEntryBySlug(ctx, c.Param("schema_key"), c.Param("slug"))
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeEntryNotFound, "entry not found")
+			return
+		}
+		utils.InternalError(c, "failed to get entry")
+		return
+	}
+
+	h.respondEntry(ctx, c, entry, fields)
+}
+
+// respondEntry 未发布的 entry 只对作者本人和 staff 可见，其他人得到 404；fields 非空时只返回所选字段。
+// 支持 If-None-Match / If-Modified-Since 条件请求，未变化时返回 304
+func (h *EntryHandler) respondEntry(ctx context.Context, c *gin.Context, entry *model.Entry, fields []string) {
+	userRole, _ := c.Get("user_role")
+	if entry.Base.Draft && entry.AuthorID != c.GetString("user_id") && !isStaff(userRole) {
+		utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeEntryNotFound, "entry not found")
+		return
+	}
+
+	shaped, err := h.shapeEntries(ctx, c, []model.Entry{*entry})
+	if err != nil {
+		utils.InternalError(c, "failed to get entry author")
+		return
+	}
+	if shaped, err = projectEntries(shaped, fields); err != nil {
+		utils.InternalError(c, "failed to get entry")
+		return
+	}
+
+	// ETag 取自实际返回的内容，不同身份和 fields 看到的表示各不相同
+	data, err := json.Marshal(shaped[0])
+	if err != nil {
+		utils.InternalError(c, "failed to get entry")
+		return
+	}
+	if utils.NotModified(c, utils.ETag(data), entry.Base.UpdatedAt) {
+		return
+	}
+
+	utils.Success(c, shaped[0])
+}
+
+// GET /api/v1/entries/mine - 当前用户自己的 entry，包含草稿；可按 status 或 draft=true/false 过滤
+func (h *EntryHandler) Mine(c *gin.Context) {
+	schemaKeys := c.QueryArray("schema_key")
+	sort := c.Query("sort")
+	if _, ok := repository.SearchSorts[sort]; sort != "" && !ok {
+		utils.BadRequest(c, "invalid sort: use newest or oldest")
+		return
+	}
+	var draft *bool
+	if draftParam := c.Query("draft"); draftParam != "" {
+		d := draftParam == "true"
+		draft = &d
+	}
+	status := model.EntryStatus(c.Query("status"))
+	if status != "" && !status.Valid() {
+		utils.BadRequest(c, "invalid status")
+		return
+	}
+
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	entries, total, err := h.mongoRepo.ListEntriesByAuthor(ctx, c.GetString("user_id"), schemaKeys, draft, status, sort == "oldest", limit, offset)
+	if err != nil {
+		utils.InternalError(c, "failed to list entries")
+		return
+	}
+	if entries == nil {
+		entries = []model.Entry{}
+	}
+
+	utils.SuccessWithPagination(c, entries, total, limit, offset)
+}
+
+// shapeEntries 按调用者身份裁剪返回内容：staff 和作者本人看到完整的 entry，
+// 其他人（包括匿名读者）看到 EntryPublic，作者以 UserPublic 展示而不暴露 author_id
+func (h *EntryHandler) shapeEntries(ctx context.Context, c *gin.Context, entries []model.Entry) ([]any, error) {
+	userID := c.GetString("user_id")
+	userRole, _ := c.Get("user_role")
+	staff := isStaff(userRole)
+
+	var authorIDs []primitive.ObjectID
+	if !staff {
+		seen := make(map[string]bool)
+		for _, entry := range entries {
+			if entry.AuthorID == userID || seen[entry.AuthorID] {
+				continue
+			}
+			seen[entry.AuthorID] = true
+			if oid, err := primitive.ObjectIDFromHex(entry.AuthorID); err == nil {
+				authorIDs = append(authorIDs, oid)
+			}
+		}
+	}
+
+	authors := make(map[string]*model.UserPublic, len(authorIDs))
+	if len(authorIDs) > 0 {
+		users, err := h.mongoRepo.GetUsersByIDs(ctx, authorIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			authors[users[i].ID.Hex()] = users[i].Public()
+		}
+	}
+
+	shaped := make([]any, len(entries))
+	for i := range entries {
+		if staff || (userID != "" && entries[i].AuthorID == userID) {
+			shaped[i] = entries[i]
+		} else {
+			shaped[i] = entries[i].Public(authors[entries[i].AuthorID])
+		}
+	}
+	return shaped, nil
+}
+
+// entryFieldPaths fields 参数可选择的字段路径，另外允许 attributes.<key> 选择单个属性。
+// 对普通读者不可见的字段（如 author_id）即使被选择也不会出现
+var entryFieldPaths = map[string]bool{
+	"id":               true,
+	"schema_id":        true,
+	"schema_key":       true,
+	"schema_version":   true,
+	"author_id":        true,
+	"author":           true,
+	"base":             true,
+	"base.title":       true,
+	"base.slug":        true,
+	"base.status":      true,
+	"base.draft":       true,
+	"base.created_at":  true,
+	"base.updated_at":  true,
+	"body":             true,
+	"body_format":      true,
+	"attributes":       true,
+	"comments_enabled": true,
+}
+
+// parseEntryFields 解析逗号分隔的 fields 参数，为空时返回 nil 表示返回全部字段
+func parseEntryFields(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, path := range strings.Split(raw, ",") {
+		path = strings.TrimSpace(path)
+		key, isAttr := strings.CutPrefix(path, "attributes.")
+		if !entryFieldPaths[path] && !(isAttr && uniqueKeyRegex.MatchString(key)) {
+			return nil, fmt.Errorf("invalid fields: unknown field '%s'", path)
+		}
+		if !slices.Contains(fields, path) {
+			fields = append(fields, path)
+		}
+	}
+	return fields, nil
+}
+
+// projectEntries 在 shapeEntries 之后裁剪响应，只保留 fields 中的路径；fields 为空时原样返回
+func projectEntries(shaped []any, fields []string) ([]any, error) {
+	if len(fields) == 0 {
+		return shaped, nil
+	}
+	projected := make([]any, len(shaped))
+	for i, item := range shaped {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var doc map[string]any
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		out := make(map[string]any, len(fields))
+		for _, path := range fields {
+			parent, child, nested := strings.Cut(path, ".")
+			value, ok := doc[parent]
+			if !ok {
+				continue
+			}
+			if !nested {
+				out[parent] = value
+				continue
+			}
+			obj, _ := value.(map[string]any)
+			childValue, ok := obj[child]
+			if !ok {
+				continue
+			}
+			sub, _ := out[parent].(map[string]any)
+			if sub == nil {
+				if _, whole := out[parent]; whole {
+					continue // 已选择了整个父字段
+				}
+				sub = make(map[string]any)
+				out[parent] = sub
+			}
+			sub[child] = childValue
+		}
+		projected[i] = out
+	}
+	return projected, nil
 }
 
 func (h *EntryHandler) List(c *gin.Context) {
 	query := c.Query("q")
-	schemaKey := c.Query("schema_key")
+	schemaKeys := c.QueryArray("schema_key")
+	termIDs := c.QueryArray("term_id")
+	// search_field 限定 q 只匹配这些字段（title、body 或 schema 的 searchable 属性），退化查询时忽略
+	searchFields := c.QueryArray("search_field")
+	highlight := c.Query("highlight") == "true"
+	// count_only / HEAD 只返回总数（X-Total-Count），不取 entry 文档，供分页控件单独加载
+	countOnly := c.Query("count_only") == "true" || c.Request.Method == http.MethodHead
+	sort := c.Query("sort")
+	if _, ok := repository.SearchSorts[sort]; sort != "" && !ok {
+		utils.BadRequest(c, "invalid sort: use newest or oldest")
+		return
+	}
+	for _, key := range schemaKeys {
+		if !repository.IsValidSchemaKey(key) {
+			utils.BadRequest(c, "invalid schema_key")
+			return
+		}
+	}
+	for _, termID := range termIDs {
+		if !primitive.IsValidObjectID(termID) {
+			utils.BadRequest(c, "invalid term_id")
+			return
+		}
+	}
+	fields, err := parseEntryFields(c.Query("fields"))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
 	draftParam := c.Query("draft")
 	limitStr := c.DefaultQuery("limit", "20")
 	offsetStr := c.DefaultQuery("offset", "0")
@@ -265,21 +890,19 @@ func (h *EntryHandler) List(c *gin.Context) {
 		offset = 0
 	}
 
-	// 处理 draft 过滤
+	// 处理 status / draft 过滤：只有管理员和编辑可以查看未发布的 entry，其他人始终只看到 published
 	var draft *bool
+	status := model.EntryStatus(c.Query("status"))
+	if status != "" && !status.Valid() {
+		utils.BadRequest(c, "invalid status")
+		return
+	}
 	userRole, _ := c.Get("user_role")
-	if draftParam != "" {
-		// 只有管理员可以查看草稿
-		if userRole == "admin" {
-			d := draftParam == "true"
-			draft = &d
-		}
-	} else {
-		// 默认只显示已发布的文章（非管理员）
-		if userRole != "admin" {
-			d := false
-			draft = &d
-		}
+	if !isStaff(userRole) {
+		status = model.EntryPublished
+	} else if draftParam != "" {
+		d := draftParam == "true"
+		draft = &d
 	}
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
@@ -287,60 +910,111 @@ func (h *EntryHandler) List(c *gin.Context) {
 
 	var entries []model.Entry
 	var total int64
-
-	if query != "" && h.meiliRepo != nil {
+	var snippets map[string]model.SearchSnippet
+	var degraded bool
+
+	// term_id 过滤依赖搜索索引中的 term_ids，未提供 q 时以空查询浏览
+	searched := false
+	// 搜索索引中只有已发布的 entry，按其他状态过滤时直接查 MongoDB
+	indexable := status == "" || status == model.EntryPublished
+	if (query != "" || len(termIDs) > 0) && h.meiliRepo != nil && indexable {
 		// Search via Meilisearch
-		ids, searchTotal, err := h.meiliRepo.Search(query, schemaKey, limit, offset)
-		if err != nil {
+		result, err := h.meiliRepo.Search(repository.SearchOptions{
+			Query:      query,
+			SchemaKeys: schemaKeys,
+			TermIDs:    termIDs,
+			Limit:      limit,
+			Offset:     offset,
+			Highlight:  highlight,
+			Sort:       sort,
+			Fields:     searchFields,
+		})
+		switch {
+		case errors.Is(err, repository.ErrInvalidSearchField):
+			utils.BadRequest(c, err.Error())
+			return
+		case err == nil:
+			searched = true
+			total = result.Total
+			snippets = result.Snippets
+
+			if len(result.IDs) > 0 && !countOnly {
+				oids := make([]primitive.ObjectID, 0, len(result.IDs))
+				for _, id := range result.IDs {
+					if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+						oids = append(oids, oid)
+					}
+				}
+				// 索引中只有已发布的 entry，无需再过滤草稿
+				entries, err = h.mongoRepo.GetEntriesByIDs(ctx, oids)
+				if err != nil {
+					utils.InternalError(c, "failed to get entries")
+					return
+				}
+			} else {
+				entries = []model.Entry{}
+			}
+		case h.cfg.SearchFallback:
+			// 退化查询仍按 term_id 过滤，但不支持高亮和排序，通过 meta.degraded 告知客户端
+			log.Printf("search failed, falling back to mongo: %v", err)
+			degraded = true
+		default:
 			utils.InternalError(c, "search failed")
 			return
 		}
-		total = searchTotal
+	}
 
-		if len(ids) > 0 {
-			oids := make([]primitive.ObjectID, 0, len(ids))
-			for _, id := range ids {
-				if oid, err := primitive.ObjectIDFromHex(id); err == nil {
-					oids = append(oids, oid)
-				}
-			}
-			entries, err = h.mongoRepo.GetEntriesByIDs(ctx, oids)
+	if !searched {
+		var err error
+		if query != "" {
+			// Meilisearch not configured, unavailable or not applicable: MongoDB text index
+			entries, total, err = h.mongoRepo.SearchEntries(ctx, query, schemaKeys, termIDs, draft, status, limit, offset)
 			if err != nil {
-				utils.InternalError(c, "failed to get entries")
+				utils.InternalError(c, "failed to search entries")
 				return
 			}
-			// 过滤草稿（搜索结果需要二次过滤）
-			if draft != nil && !*draft {
-				filtered := make([]model.Entry, 0)
-				for _, e := range entries {
-					if !e.Base.Draft {
-						filtered = append(filtered, e)
-					}
+		} else {
+			// Direct MongoDB query
+			if !countOnly {
+				entries, err = h.mongoRepo.ListEntries(ctx, schemaKeys, termIDs, draft, status, limit, offset)
+				if err != nil {
+					utils.InternalError(c, "failed to list entries")
+					return
 				}
-				entries = filtered
 			}
-		} else {
-			entries = []model.Entry{}
-		}
-	} else {
-		// Direct MongoDB query
-		var err error
-		entries, err = h.mongoRepo.ListEntries(ctx, schemaKey, draft, limit, offset)
-		if err != nil {
-			utils.InternalError(c, "failed to list entries")
-			return
-		}
-		total, err = h.mongoRepo.CountEntries(ctx, schemaKey, draft)
-		if err != nil {
-			utils.InternalError(c, "failed to count entries")
-			return
+			total, err = h.mongoRepo.CountEntries(ctx, schemaKeys, termIDs, draft, status)
+			if err != nil {
+				utils.InternalError(c, "failed to count entries")
+				return
+			}
 		}
 	}
 
+	if countOnly {
+		c.Header(utils.TotalCountHeader, strconv.FormatInt(total, 10))
+		utils.Success(c, gin.H{"total": total, "degraded": degraded})
+		return
+	}
+
 	// Always return array, never nil
 	if entries == nil {
 		entries = []model.Entry{}
 	}
 
-	utils.SuccessWithPagination(c, entries, total, limit, offset)
+	shaped, err := h.shapeEntries(ctx, c, entries)
+	if err != nil {
+		utils.InternalError(c, "failed to get entry authors")
+		return
+	}
+	if shaped, err = projectEntries(shaped, fields); err != nil {
+		utils.InternalError(c, "failed to list entries")
+		return
+	}
+
+	meta := utils.NewPaginationMeta(total, limit, offset)
+	if snippets != nil {
+		meta.Snippets = snippets
+	}
+	meta.Degraded = degraded
+	utils.SuccessWithMeta(c, shaped, meta)
 }
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"matter-core/internal/config"
+	"matter-core/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListEntriesRejectsInvalidSchemaKey(t *testing.T) {
+	h := NewEntryHandler(repository.NewMemoryRepo(), nil, nil, nil, &config.Config{})
+	r := gin.New()
+	r.GET("/entries", h.List)
+
+	// 无论是否经过搜索，非法的 schema_key 都是客户端错误
+	for _, path := range []string{
+		"/entries?schema_key=post&schema_key=bad%22key",
+		"/entries?q=hello&schema_key=bad%20key",
+	} {
+		if code, _ := doJSON(t, r, http.MethodGet, path, nil); code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, code)
+		}
+	}
+	if code, _ := doJSON(t, r, http.MethodGet, "/entries?schema_key=post", nil); code != http.StatusOK {
+		t.Errorf("GET /entries?schema_key=post = %d, want 200", code)
+	}
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"matter-core/internal/config"
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+	"matter-core/internal/service"
+	"matter-core/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultFeedLimit = 20
+	maxFeedLimit     = 50
+)
+
+type FeedHandler struct {
+	mongoRepo repository.Repository
+	cfg       *config.Config
+}
+
+func NewFeedHandler(mongoRepo repository.Repository, cfg *config.Config) *FeedHandler {
+	return &FeedHandler{mongoRepo: mongoRepo, cfg: cfg}
+}
+
+// GET /api/v1/feeds/:schema_key.xml - 某个 schema 最新已发布 entry 的订阅源，
+// 默认 RSS 2.0，?format=atom 返回 Atom；支持 ETag 条件请求
+func (h *FeedHandler) Schema(c *gin.Context) {
+	schemaKey, ok := strings.CutSuffix(c.Param("feed"), ".xml")
+	if !ok || schemaKey == "" {
+		utils.NotFound(c, "feed not found")
+		return
+	}
+	format := c.DefaultQuery("format", "rss")
+	if format != "rss" && format != "atom" {
+		utils.BadRequest(c, "format must be rss or atom")
+		return
+	}
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(defaultFeedLimit)), 10, 64)
+	if limit <= 0 || limit > maxFeedLimit {
+		limit = defaultFeedLimit
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	schema, err := h.mongoRepo.GetLatestSchema(ctx, schemaKey)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeSchemaNotFound, "schema not found")
+			return
+		}
+		utils.InternalError(c, "failed to get schema")
+		return
+	}
+
+	entries, err := h.mongoRepo.ListEntries(ctx, []string{schemaKey}, nil, nil, model.EntryPublished, limit, 0)
+	if err != nil {
+		utils.InternalError(c, "failed to list entries")
+		return
+	}
+
+	authors, err := h.authorNames(ctx, entries)
+	if err != nil {
+		utils.InternalError(c, "failed to get authors")
+		return
+	}
+
+	siteURL := strings.TrimRight(h.cfg.SiteURL, "/")
+	feed := &service.Feed{
+		Title:       h.cfg.SiteTitle + " - " + schema.Name,
+		Link:        siteURL,
+		Self:        h.feedURL(schemaKey, format),
+		Description: schema.Name,
+		Items:       make([]service.FeedItem, 0, len(entries)),
+		Updated:     schema.CreatedAt,
+	}
+	for _, entry := range entries {
+		ref := entry.Base.Slug
+		if ref == "" {
+			ref = entry.ID.Hex()
+		}
+		feed.Items = append(feed.Items, service.FeedItem{
+			Entry:  entry,
+			Link:   siteURL + "/" + url.PathEscape(schemaKey) + "/" + url.PathEscape(ref),
+			Author: authors[entry.AuthorID],
+		})
+	}
+
+	var body []byte
+	contentType := "application/rss+xml; charset=utf-8"
+	if format == "atom" {
+		body, err = feed.Atom()
+		contentType = "application/atom+xml; charset=utf-8"
+	} else {
+		body, err = feed.RSS()
+	}
+	if err != nil {
+		utils.InternalError(c, "failed to render feed")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	// 不发送 Last-Modified：entry 被删除或下线时最近修改时间可能不变甚至倒退，只有 ETag 能反映内容变化
+	if utils.NotModified(c, utils.ETag(body), time.Time{}) {
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// authorNames 批量查询作者昵称，按 author_id 索引
+func (h *FeedHandler) authorNames(ctx context.Context, entries []model.Entry) (map[string]string, error) {
+	ids := make([]primitive.ObjectID, 0, len(entries))
+	for _, entry := range entries {
+		if oid, err := primitive.ObjectIDFromHex(entry.AuthorID); err == nil {
+			ids = append(ids, oid)
+		}
+	}
+	names := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	users, err := h.mongoRepo.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		names[user.ID.Hex()] = user.Nickname
+	}
+	return names, nil
+}
+
+// feedURL 订阅源的规范地址，作为 self 链接和 Atom id：
+// 只由配置的 API 地址和路径组成，不受请求头和 limit 等查询参数影响
+func (h *FeedHandler) feedURL(schemaKey, format string) string {
+	u := h.cfg.APIBaseURL + "/api/v1/feeds/" + url.PathEscape(schemaKey) + ".xml"
+	if format == "atom" {
+		u += "?format=atom"
+	}
+	return u
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"matter-core/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	mongoRepo repository.Repository
+	meiliRepo *repository.MeiliRepo
+}
+
+func NewHealthHandler(mongoRepo repository.Repository, meiliRepo *repository.MeiliRepo) *HealthHandler {
+	return &HealthHandler{mongoRepo: mongoRepo, meiliRepo: meiliRepo}
+}
+
+// GET /health/live - 存活探针，进程能响应即可
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GET /health/ready - 就绪探针，检查 MongoDB 和 Meilisearch（未配置时记为 disabled）
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+	defer cancel()
+
+	healthy := true
+	checks := gin.H{}
+
+	if err := h.mongoRepo.Ping(ctx); err != nil {
+		healthy = false
+		checks["mongo"] = gin.H{"status": "error", "error": err.Error()}
+	} else {
+		checks["mongo"] = gin.H{"status": "ok"}
+	}
+
+	if h.meiliRepo == nil {
+		checks["meilisearch"] = gin.H{"status": "disabled"}
+	} else if err := h.meiliRepo.Health(ctx); err != nil {
+		healthy = false
+		checks["meilisearch"] = gin.H{"status": "error", "error": err.Error()}
+	} else {
+		checks["meilisearch"] = gin.H{"status": "ok"}
+	}
+
+	if !healthy {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+}
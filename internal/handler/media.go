@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"matter-core/internal/config"
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+	"matter-core/internal/service"
+	"matter-core/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mediaExtensions 常见类型的文件扩展名，其余类型取 mime 包给出的第一个
+var mediaExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+type MediaHandler struct {
+	mongoRepo repository.Repository
+	storage   service.Storage
+	cfg       *config.Config
+}
+
+func NewMediaHandler(mongoRepo repository.Repository, storage service.Storage, cfg *config.Config) *MediaHandler {
+	return &MediaHandler{mongoRepo: mongoRepo, storage: storage, cfg: cfg}
+}
+
+// POST /api/v1/media - 上传文件（multipart 字段 file），类型按文件内容识别而不是信任请求头
+func (h *MediaHandler) Upload(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.Error(c, http.StatusRequestEntityTooLarge, "file too large")
+			return
+		}
+		utils.BadRequest(c, "file is required")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > h.cfg.MediaMaxSize {
+		utils.Error(c, http.StatusRequestEntityTooLarge, "file too large")
+		return
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		utils.BadRequest(c, "failed to read file")
+		return
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !slices.Contains(h.cfg.MediaAllowedTypes, contentType) {
+		utils.ErrorWithCode(c, http.StatusUnsupportedMediaType, utils.CodeUnsupportedMediaType, "unsupported file type: "+contentType)
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	media := &model.Media{
+		ID:          primitive.NewObjectID(),
+		OwnerID:     userID.(string),
+		Filename:    filepath.Base(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+	}
+	media.StorageKey = time.Now().UTC().Format("2006/01/") + media.ID.Hex() + mediaExtension(contentType)
+	media.URL = h.storage.URL(media.StorageKey)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := h.storage.Put(ctx, media.StorageKey, io.MultiReader(bytes.NewReader(head), file), contentType); err != nil {
+		utils.InternalError(c, "failed to store file")
+		return
+	}
+	if err := h.mongoRepo.CreateMedia(ctx, media); err != nil {
+		if err := h.storage.Delete(context.Background(), media.StorageKey); err != nil {
+			log.Printf("Failed to remove orphaned media %s: %v", media.StorageKey, err)
+		}
+		utils.InternalError(c, "failed to save media")
+		return
+	}
+
+	utils.Created(c, media)
+}
+
+// GET /api/v1/media/:id - 获取媒体信息
+func (h *MediaHandler) Get(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid media id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	media, err := h.mongoRepo.GetMediaByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeMediaNotFound, "media not found")
+			return
+		}
+		utils.InternalError(c, "failed to get media")
+		return
+	}
+
+	utils.Success(c, media)
+}
+
+// DELETE /api/v1/media/:id - 删除媒体（上传者或 staff）
+func (h *MediaHandler) Delete(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid media id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	media, err := h.mongoRepo.GetMediaByID(ctx, id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeMediaNotFound, "media not found")
+			return
+		}
+		utils.InternalError(c, "failed to get media")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+	if media.OwnerID != userID.(string) && !isStaff(userRole) {
+		utils.Forbidden(c, "not allowed to delete this media")
+		return
+	}
+
+	if err := h.mongoRepo.DeleteMedia(ctx, id); err != nil {
+		utils.InternalError(c, "failed to delete media")
+		return
+	}
+	// The record is gone, so a leftover file is only wasted space
+	if err := h.storage.Delete(ctx, media.StorageKey); err != nil {
+		log.Printf("Failed to remove media file %s: %v", media.StorageKey, err)
+	}
+
+	utils.Success(c, nil)
+}
+
+func mediaExtension(contentType string) string {
+	if ext, ok := mediaExtensions[contentType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"matter-core/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "matter_http_requests_total",
+		Help: "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "matter_http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dependencyUpDesc = prometheus.NewDesc(
+		"matter_dependency_up",
+		"Whether a backing service answered its health check (1) or not (0).",
+		[]string{"dependency"}, nil,
+	)
+)
+
+// MetricsMiddleware 按路由模板（而非实际路径，避免 ID 撑爆标签基数）记录请求数和耗时
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestsTotal.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsHandler 暴露 Prometheus 指标，依赖健康状态在每次抓取时检查
+func MetricsHandler(mongoRepo repository.Repository, meiliRepo *repository.MeiliRepo) gin.HandlerFunc {
+	prometheus.MustRegister(&dependencyCollector{mongoRepo: mongoRepo, meiliRepo: meiliRepo})
+	return gin.WrapH(promhttp.Handler())
+}
+
+// dependencyCollector 在抓取时 ping MongoDB 和 Meilisearch（未配置时不输出）
+type dependencyCollector struct {
+	mongoRepo repository.Repository
+	meiliRepo *repository.MeiliRepo
+}
+
+func (d *dependencyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dependencyUpDesc
+}
+
+func (d *dependencyCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	ch <- prometheus.MustNewConstMetric(dependencyUpDesc, prometheus.GaugeValue, up(d.mongoRepo.Ping(ctx)), "mongo")
+	if d.meiliRepo != nil {
+		ch <- prometheus.MustNewConstMetric(dependencyUpDesc, prometheus.GaugeValue, up(d.meiliRepo.Health(ctx)), "meilisearch")
+	}
+}
+
+func up(err error) float64 {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
@@ -1,14 +1,75 @@
 package handler
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"matter-core/internal/model"
 	"matter-core/internal/service"
 	"matter-core/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(sessionStore *service.SessionStore) gin.HandlerFunc {
+const APIKeyHeader = "X-API-Key"
+
+// SessionValidator 校验 session token，*service.SessionStore 实现了该接口
+type SessionValidator interface {
+	IsValid(ctx context.Context, token string) (*model.Session, bool)
+	Touch(ctx context.Context, session *model.Session)
+	IsBanned(ctx context.Context, userID string) bool
+}
+
+// APIKeyValidator 校验 X-API-Key 请求头，*service.APIKeyStore 实现了该接口
+type APIKeyValidator interface {
+	Validate(ctx context.Context, plain string) (*model.APIKey, bool)
+}
+
+// TokenValidator 校验 Authorization: Bearer 中的 JWT，*service.AuthService 实现了该接口
+type TokenValidator interface {
+	ValidateJWT(tokenStr string) (*service.Claims, error)
+}
+
+// AuthMiddleware 依次尝试三种凭证，只使用请求中出现的第一种：
+// X-API-Key 请求头（APIKeyStore）、Authorization: Bearer <jwt>（未配置 JWT_SECRET 时一律无效）、
+// session cookie（SessionCookieName，SessionStore）
+func AuthMiddleware(sessionStore SessionValidator, apiKeys APIKeyValidator, tokens TokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if plain := c.GetHeader(APIKeyHeader); plain != "" {
+			key, valid := apiKeys.Validate(c.Request.Context(), plain)
+			if !valid {
+				utils.Unauthorized(c, "invalid api key")
+				c.Abort()
+				return
+			}
+			if rejectBanned(c, sessionStore, key.UserID.Hex()) {
+				return
+			}
+			c.Set("user_id", key.UserID.Hex())
+			c.Set("user_role", key.Role)
+			c.Next()
+			return
+		}
+
+		if token, ok := bearerToken(c); ok {
+			claims, err := tokens.ValidateJWT(token)
+			if err != nil {
+				utils.Unauthorized(c, "invalid token")
+				c.Abort()
+				return
+			}
+			if rejectBanned(c, sessionStore, claims.UserID) {
+				return
+			}
+			c.Set("user_id", claims.UserID)
+			c.Set("user_role", claims.Role)
+			c.Next()
+			return
+		}
+
 		token, err := c.Cookie(SessionCookieName)
 		if err != nil {
 			utils.Unauthorized(c, "not authenticated")
@@ -22,6 +83,10 @@ func AuthMiddleware(sessionStore *service.SessionStore) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if rejectBanned(c, sessionStore, session.UserID.Hex()) {
+			return
+		}
+		sessionStore.Touch(c.Request.Context(), session)
 
 		c.Set("user_id", session.UserID.Hex())
 		c.Set("user_role", session.Role)
@@ -29,6 +94,41 @@ func AuthMiddleware(sessionStore *service.SessionStore) gin.HandlerFunc {
 	}
 }
 
+// RateLimitMiddleware 进程内限流，已认证时按用户计数，否则按 IP；
+// 需放在认证中间件之后才能识别用户。limit <= 0 表示不限流
+func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
+	limiter := service.NewRateLimiter(limit, window)
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID := c.GetString("user_id"); userID != "" {
+			key = "user:" + userID
+		}
+		if ok, retryAfter := limiter.Allow(key); !ok {
+			utils.TooManyRequests(c, retryAfter, "rate limit exceeded")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// rawBodyKey 保存未经限制的原始请求体，路由级的 BodyLimitMiddleware 据此覆盖全局上限
+const rawBodyKey = "raw_body"
+
+// BodyLimitMiddleware 用 http.MaxBytesReader 限制请求体大小，超出时返回 413（绑定时由 utils.BindError 处理）。
+// 可以全局注册再在个别路由上以不同的 limit 再注册一次，后注册的生效
+func BodyLimitMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, ok := c.Get(rawBodyKey)
+		if !ok {
+			body = c.Request.Body
+			c.Set(rawBodyKey, body)
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, body.(io.ReadCloser), limit)
+		c.Next()
+	}
+}
+
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("user_role")
@@ -41,8 +141,47 @@ func AdminMiddleware() gin.HandlerFunc {
 	}
 }
 
-func OptionalAuthMiddleware(sessionStore *service.SessionStore) gin.HandlerFunc {
+// RoleMiddleware 要求当前用户的角色属于 roles 之一
+func RoleMiddleware(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("user_role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		utils.Forbidden(c, "insufficient role")
+		c.Abort()
+	}
+}
+
+// isStaff 管理员和编辑可以管理任意 entry 与评论
+func isStaff(role any) bool {
+	return role == string(model.RoleAdmin) || role == string(model.RoleEditor)
+}
+
+// OptionalAuthMiddleware 与 AuthMiddleware 使用相同的凭证和顺序，未登录或凭证无效时按匿名放行
+func OptionalAuthMiddleware(sessionStore SessionValidator, apiKeys APIKeyValidator, tokens TokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if plain := c.GetHeader(APIKeyHeader); plain != "" {
+			if key, valid := apiKeys.Validate(c.Request.Context(), plain); valid && !sessionStore.IsBanned(c.Request.Context(), key.UserID.Hex()) {
+				c.Set("user_id", key.UserID.Hex())
+				c.Set("user_role", key.Role)
+			}
+			c.Next()
+			return
+		}
+
+		if token, ok := bearerToken(c); ok {
+			if claims, err := tokens.ValidateJWT(token); err == nil && !sessionStore.IsBanned(c.Request.Context(), claims.UserID) {
+				c.Set("user_id", claims.UserID)
+				c.Set("user_role", claims.Role)
+			}
+			c.Next()
+			return
+		}
+
 		token, err := c.Cookie(SessionCookieName)
 		if err != nil {
 			c.Next()
@@ -50,10 +189,31 @@ func OptionalAuthMiddleware(sessionStore *service.SessionStore) gin.HandlerFunc
 		}
 
 		session, valid := sessionStore.IsValid(c.Request.Context(), token)
-		if valid {
+		// 被封禁的用户按匿名访问处理
+		if valid && !sessionStore.IsBanned(c.Request.Context(), session.UserID.Hex()) {
+			sessionStore.Touch(c.Request.Context(), session)
 			c.Set("user_id", session.UserID.Hex())
 			c.Set("user_role", session.Role)
 		}
 		c.Next()
 	}
 }
+
+// rejectBanned 用户被封禁时返回 403 并中止请求
+func rejectBanned(c *gin.Context, sessionStore SessionValidator, userID string) bool {
+	if !sessionStore.IsBanned(c.Request.Context(), userID) {
+		return false
+	}
+	utils.Forbidden(c, "account is banned")
+	c.Abort()
+	return true
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
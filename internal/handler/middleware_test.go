@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"matter-core/internal/config"
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+	"matter-core/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeSessions 以内存中的 token 表代替 SessionStore
+type fakeSessions struct {
+	sessions map[string]*model.Session
+	banned   map[string]bool
+	touched  int
+}
+
+func (f *fakeSessions) IsValid(ctx context.Context, token string) (*model.Session, bool) {
+	session, ok := f.sessions[token]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return nil, false
+	}
+	return session, true
+}
+
+func (f *fakeSessions) Touch(ctx context.Context, session *model.Session) { f.touched++ }
+
+func (f *fakeSessions) IsBanned(ctx context.Context, userID string) bool { return f.banned[userID] }
+
+// fakeAPIKeys 以明文 key 为索引的 API Key 表
+type fakeAPIKeys map[string]*model.APIKey
+
+func (f fakeAPIKeys) Validate(ctx context.Context, plain string) (*model.APIKey, bool) {
+	key, ok := f[plain]
+	return key, ok
+}
+
+type authFixture struct {
+	sessions *fakeSessions
+	apiKeys  fakeAPIKeys
+	tokens   *service.AuthService
+	userID   primitive.ObjectID
+}
+
+func newAuthFixture() *authFixture {
+	userID := primitive.NewObjectID()
+	return &authFixture{
+		sessions: &fakeSessions{
+			sessions: map[string]*model.Session{
+				"valid":   {UserID: userID, Role: "editor", ExpiresAt: time.Now().Add(time.Hour)},
+				"expired": {UserID: userID, Role: "editor", ExpiresAt: time.Now().Add(-time.Hour)},
+			},
+			banned: map[string]bool{},
+		},
+		apiKeys: fakeAPIKeys{"key": {UserID: userID, Role: "user"}},
+		tokens:  service.NewAuthService(repository.NewMemoryRepo(), &config.Config{JWTSecret: "test-secret"}),
+		userID:  userID,
+	}
+}
+
+// serve 用给定中间件处理一次请求，返回状态码和写入上下文的用户信息
+func (f *authFixture) serve(mw gin.HandlerFunc, setup func(*http.Request)) (int, string, string) {
+	var userID, role string
+	r := gin.New()
+	r.GET("/", mw, func(c *gin.Context) {
+		userID = c.GetString("user_id")
+		role = c.GetString("user_role")
+		c.Status(http.StatusNoContent)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if setup != nil {
+		setup(req)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, userID, role
+}
+
+func withCookie(token string) func(*http.Request) {
+	return func(req *http.Request) {
+		req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: token})
+	}
+}
+
+func withHeader(name, value string) func(*http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set(name, value)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	f := newAuthFixture()
+	jwt, err := f.tokens.IssueJWT(f.userID, "admin", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredJWT, err := f.tokens.IssueJWT(f.userID, "admin", -time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(*http.Request)
+		banned   bool
+		wantCode int
+		wantRole string
+	}{
+		{"no credentials", nil, false, http.StatusUnauthorized, ""},
+		{"valid cookie", withCookie("valid"), false, http.StatusNoContent, "editor"},
+		{"expired cookie", withCookie("expired"), false, http.StatusUnauthorized, ""},
+		{"unknown cookie", withCookie("nope"), false, http.StatusUnauthorized, ""},
+		{"banned cookie user", withCookie("valid"), true, http.StatusForbidden, ""},
+		{"valid api key", withHeader(APIKeyHeader, "key"), false, http.StatusNoContent, "user"},
+		{"invalid api key", withHeader(APIKeyHeader, "bad"), false, http.StatusUnauthorized, ""},
+		{"valid bearer", withHeader("Authorization", "Bearer "+jwt), false, http.StatusNoContent, "admin"},
+		{"expired bearer", withHeader("Authorization", "Bearer "+expiredJWT), false, http.StatusUnauthorized, ""},
+		{"forged bearer", withHeader("Authorization", "Bearer "+jwt+"x"), false, http.StatusUnauthorized, ""},
+		{"banned bearer user", withHeader("Authorization", "Bearer "+jwt), true, http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.sessions.banned[f.userID.Hex()] = tt.banned
+			code, userID, role := f.serve(AuthMiddleware(f.sessions, f.apiKeys, f.tokens), tt.setup)
+			if code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", code, tt.wantCode)
+			}
+			if tt.wantRole != "" && (userID != f.userID.Hex() || role != tt.wantRole) {
+				t.Errorf("user = %q/%q, want %q/%q", userID, role, f.userID.Hex(), tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+	f := newAuthFixture()
+	jwt, err := f.tokens.IssueJWT(f.userID, "admin", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(*http.Request)
+		banned   bool
+		wantRole string // 为空表示按匿名处理
+	}{
+		{"anonymous", nil, false, ""},
+		{"valid cookie", withCookie("valid"), false, "editor"},
+		{"expired cookie", withCookie("expired"), false, ""},
+		{"banned cookie user", withCookie("valid"), true, ""},
+		{"valid bearer", withHeader("Authorization", "Bearer "+jwt), false, "admin"},
+		{"invalid bearer", withHeader("Authorization", "Bearer nope"), false, ""},
+		{"invalid api key", withHeader(APIKeyHeader, "bad"), false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.sessions.banned[f.userID.Hex()] = tt.banned
+			code, userID, role := f.serve(OptionalAuthMiddleware(f.sessions, f.apiKeys, f.tokens), tt.setup)
+			if code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d", code, http.StatusNoContent)
+			}
+			if role != tt.wantRole {
+				t.Errorf("role = %q, want %q", role, tt.wantRole)
+			}
+			if tt.wantRole != "" && userID != f.userID.Hex() {
+				t.Errorf("user_id = %q, want %q", userID, f.userID.Hex())
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareTouchesValidSession(t *testing.T) {
+	f := newAuthFixture()
+	f.serve(AuthMiddleware(f.sessions, f.apiKeys, f.tokens), withCookie("valid"))
+	f.serve(AuthMiddleware(f.sessions, f.apiKeys, f.tokens), withCookie("expired"))
+	if f.sessions.touched != 1 {
+		t.Errorf("touched = %d, want 1", f.sessions.touched)
+	}
+}
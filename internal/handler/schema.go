@@ -2,34 +2,45 @@ package handler
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
 	"time"
 
 	"matter-core/internal/model"
 	"matter-core/internal/repository"
+	"matter-core/internal/service"
 	"matter-core/pkg/utils"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type SchemaHandler struct {
-	mongoRepo *repository.MongoRepo
+	mongoRepo repository.Repository
+	syncSvc   *service.SyncService
 }
 
-func NewSchemaHandler(mongoRepo *repository.MongoRepo) *SchemaHandler {
-	return &SchemaHandler{mongoRepo: mongoRepo}
+func NewSchemaHandler(mongoRepo repository.Repository, syncSvc *service.SyncService) *SchemaHandler {
+	return &SchemaHandler{mongoRepo: mongoRepo, syncSvc: syncSvc}
 }
 
 type CreateSchemaRequest struct {
 	Key    string              `json:"key" binding:"required,max=50,alphanum"`
 	Name   string              `json:"name" binding:"required,max=100"`
 	Fields []model.FieldSchema `json:"fields" binding:"required"`
+	Strict bool                `json:"strict"`
+	// CommentsDisabled 新 entry 默认不接受评论，entry 可通过 comments_enabled 覆盖
+	CommentsDisabled bool `json:"comments_disabled"`
+	// Searchable 在搜索索引中单独建字段的顶层属性 key，按权重从高到低
+	Searchable []string `json:"searchable"`
 }
 
 func (h *SchemaHandler) Create(c *gin.Context) {
 	var req CreateSchemaRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
 		return
 	}
 
@@ -41,17 +52,70 @@ func (h *SchemaHandler) Create(c *gin.Context) {
 	version := 1
 	if err == nil && existing != nil {
 		version = existing.Version + 1
-	} else if err != nil && err != mongo.ErrNoDocuments {
+	} else if err != nil && err != repository.ErrNotFound {
 		utils.InternalError(c, "failed to check existing schema")
 		return
 	}
 
+	// Every taxonomy field must point at an existing taxonomy
+	var badRefs []utils.FieldError
+	checked := make(map[string]bool)
+	var lookupErr error
+	model.WalkTaxonomyFields("", req.Fields, func(path, taxonomyKey string) {
+		exists, ok := checked[taxonomyKey]
+		if !ok && lookupErr == nil {
+			_, err := h.mongoRepo.GetTaxonomyByKey(ctx, taxonomyKey)
+			if err != nil && err != repository.ErrNotFound {
+				lookupErr = err
+				return
+			}
+			exists = err == nil
+			checked[taxonomyKey] = exists
+		}
+		if !exists {
+			badRefs = append(badRefs, utils.FieldError{Field: path, Message: "taxonomy '" + taxonomyKey + "' not found"})
+		}
+	})
+	if lookupErr != nil {
+		utils.InternalError(c, "failed to check taxonomies")
+		return
+	}
+	if len(badRefs) > 0 {
+		utils.ErrorWithDetails(c, http.StatusBadRequest, utils.CodeValidationFailed, "unknown taxonomy in schema fields", badRefs)
+		return
+	}
+	if bad := invalidTaxonomyFields(req.Fields); len(bad) > 0 {
+		utils.ErrorWithDetails(c, http.StatusBadRequest, utils.CodeValidationFailed, "invalid taxonomy field", bad)
+		return
+	}
+	if bad := invalidUniqueFields(req.Fields); len(bad) > 0 {
+		utils.ErrorWithDetails(c, http.StatusBadRequest, utils.CodeValidationFailed, "invalid unique field", bad)
+		return
+	}
+	if bad := invalidSearchableFields(req.Fields, req.Searchable); len(bad) > 0 {
+		utils.ErrorWithDetails(c, http.StatusBadRequest, utils.CodeValidationFailed, "invalid searchable field", bad)
+		return
+	}
+
 	schema := &model.Schema{
-		Key:       req.Key,
-		Version:   version,
-		Name:      req.Name,
-		Fields:    req.Fields,
-		CreatedAt: time.Now(),
+		Key:              req.Key,
+		Version:          version,
+		Name:             req.Name,
+		Fields:           req.Fields,
+		Strict:           req.Strict,
+		CommentsDisabled: req.CommentsDisabled,
+		Searchable:       req.Searchable,
+		CreatedAt:        time.Now(),
+	}
+
+	// 先建索引：已有重复数据时不产生新版本
+	if err := h.mongoRepo.SyncUniqueFieldIndexes(ctx, schema); err != nil {
+		if err == repository.ErrConflict {
+			utils.Conflict(c, "existing entries have duplicate values for a unique field")
+			return
+		}
+		utils.InternalError(c, "failed to update unique indexes")
+		return
 	}
 
 	if err := h.mongoRepo.CreateSchema(ctx, schema); err != nil {
@@ -59,9 +123,95 @@ func (h *SchemaHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if h.syncSvc != nil {
+		h.syncSvc.SchemaChanged(schema.Key)
+	}
+
 	utils.Created(c, schema)
 }
 
+var uniqueKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// invalidSearchableFields Searchable 中的 key 须为顶层的非 taxonomy 字段（taxonomy 只保存 term ID），且不可重复
+func invalidSearchableFields(fields []model.FieldSchema, searchable []string) []utils.FieldError {
+	var bad []utils.FieldError
+	seen := make(map[string]bool, len(searchable))
+	for i, key := range searchable {
+		path := fmt.Sprintf("searchable[%d]", i)
+		idx := slices.IndexFunc(fields, func(f model.FieldSchema) bool { return f.Key == key })
+		switch {
+		case seen[key]:
+			bad = append(bad, utils.FieldError{Field: path, Message: "duplicate searchable field '" + key + "'"})
+		case idx < 0:
+			bad = append(bad, utils.FieldError{Field: path, Message: "unknown top-level field '" + key + "'"})
+		case fields[idx].Type == model.TypeTaxonomy:
+			bad = append(bad, utils.FieldError{Field: path, Message: "taxonomy fields cannot be searchable"})
+		case !uniqueKeyRegex.MatchString(key):
+			bad = append(bad, utils.FieldError{Field: path, Message: "searchable field key may only contain letters, digits, - and _"})
+		}
+		seen[key] = true
+	}
+	return bad
+}
+
+// invalidUniqueFields Unique 只支持顶层的 string/number/date 字段，且 key 可直接用作索引路径
+func invalidUniqueFields(fields []model.FieldSchema) []utils.FieldError {
+	var bad []utils.FieldError
+	var walk func(path string, field model.FieldSchema, top bool)
+	walk = func(path string, field model.FieldSchema, top bool) {
+		if field.Unique {
+			switch {
+			case !top:
+				bad = append(bad, utils.FieldError{Field: path, Message: "unique is only supported on top-level fields"})
+			case field.Type != model.TypeString && field.Type != model.TypeNumber && field.Type != model.TypeDate:
+				bad = append(bad, utils.FieldError{Field: path, Message: "unique is only supported on string, number and date fields"})
+			case !uniqueKeyRegex.MatchString(field.Key):
+				bad = append(bad, utils.FieldError{Field: path, Message: "unique field key may only contain letters, digits, - and _"})
+			}
+		}
+		for _, child := range field.Children {
+			walk(path+"."+child.Key, child, false)
+		}
+		if field.ItemType != nil {
+			walk(path+"[]", *field.ItemType, false)
+		}
+	}
+	for _, field := range fields {
+		walk(field.Key, field, true)
+	}
+	return bad
+}
+
+// invalidTaxonomyFields 多值 taxonomy 只能表示为 allow_multiple 的 taxonomy 字段：
+// 拒绝元素类型为 taxonomy 的数组、缺少 taxonomy_key 的 taxonomy 字段，以及在非 taxonomy 字段上设置 allow_multiple 或 taxonomy_key
+func invalidTaxonomyFields(fields []model.FieldSchema) []utils.FieldError {
+	var bad []utils.FieldError
+	var walk func(path string, field model.FieldSchema)
+	walk = func(path string, field model.FieldSchema) {
+		switch {
+		case field.Type == model.TypeArray && field.ItemType != nil && field.ItemType.Type == model.TypeTaxonomy:
+			bad = append(bad, utils.FieldError{Field: path, Message: "use a taxonomy field with allow_multiple instead of an array of taxonomy items"})
+			return
+		case field.Type == model.TypeTaxonomy && field.TaxonomyKey == "":
+			bad = append(bad, utils.FieldError{Field: path, Message: "taxonomy_key is required on taxonomy fields"})
+		case field.Type != model.TypeTaxonomy && field.AllowMultiple:
+			bad = append(bad, utils.FieldError{Field: path, Message: "allow_multiple is only valid on taxonomy fields"})
+		case field.Type != model.TypeTaxonomy && field.TaxonomyKey != "":
+			bad = append(bad, utils.FieldError{Field: path, Message: "taxonomy_key is only valid on taxonomy fields"})
+		}
+		for _, child := range field.Children {
+			walk(path+"."+child.Key, child)
+		}
+		if field.ItemType != nil {
+			walk(path+"[]", *field.ItemType)
+		}
+	}
+	for _, field := range fields {
+		walk(field.Key, field)
+	}
+	return bad
+}
+
 func (h *SchemaHandler) Get(c *gin.Context) {
 	key := c.Param("key")
 
@@ -70,8 +220,8 @@ func (h *SchemaHandler) Get(c *gin.Context) {
 
 	schema, err := h.mongoRepo.GetLatestSchema(ctx, key)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "schema not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeSchemaNotFound, "schema not found")
 			return
 		}
 		utils.InternalError(c, "failed to get schema")
@@ -103,8 +253,8 @@ func (h *SchemaHandler) Delete(c *gin.Context) {
 	// Check if schema exists
 	_, err := h.mongoRepo.GetLatestSchema(ctx, key)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "schema not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeSchemaNotFound, "schema not found")
 			return
 		}
 		utils.InternalError(c, "failed to get schema")
@@ -112,7 +262,7 @@ func (h *SchemaHandler) Delete(c *gin.Context) {
 	}
 
 	// Check if any entries are using this schema
-	entryCount, err := h.mongoRepo.CountEntries(ctx, key, nil)
+	entryCount, err := h.mongoRepo.CountEntries(ctx, []string{key}, nil, nil, "")
 	if err != nil {
 		utils.InternalError(c, "failed to check entries")
 		return
@@ -127,6 +277,14 @@ func (h *SchemaHandler) Delete(c *gin.Context) {
 		utils.InternalError(c, "failed to delete schema")
 		return
 	}
+	// 没有 Unique 字段的 schema 即删除该 key 遗留的唯一索引，避免同名 schema 重建后受其约束
+	if err := h.mongoRepo.SyncUniqueFieldIndexes(ctx, &model.Schema{Key: key}); err != nil {
+		log.Printf("failed to drop unique indexes of schema %s: %v", key, err)
+	}
+
+	if h.syncSvc != nil {
+		h.syncSvc.SchemaChanged(key)
+	}
 
 	utils.Success(c, nil)
 }
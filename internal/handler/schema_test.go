@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+
+	"matter-core/internal/model"
+)
+
+func TestInvalidTaxonomyFields(t *testing.T) {
+	fields := []model.FieldSchema{
+		{Key: "category", Type: model.TypeTaxonomy, TaxonomyKey: "category"},
+		{Key: "tag", Type: model.TypeTaxonomy},
+		{Key: "specs", Type: model.TypeObject, Children: []model.FieldSchema{
+			{Key: "brand", Type: model.TypeTaxonomy, AllowMultiple: true},
+		}},
+		{Key: "tags", Type: model.TypeArray, ItemType: &model.FieldSchema{Type: model.TypeTaxonomy, TaxonomyKey: "tag"}},
+		{Key: "title", Type: model.TypeString, TaxonomyKey: "tag"},
+	}
+
+	var got []string
+	for _, e := range invalidTaxonomyFields(fields) {
+		got = append(got, e.Field)
+	}
+	want := []string{"tag", "specs.brand", "tags", "title"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid fields = %v, want %v", got, want)
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"matter-core/internal/model"
@@ -9,14 +10,14 @@ import (
 	"matter-core/pkg/utils"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type TaxonomyHandler struct {
-	mongoRepo *repository.MongoRepo
+	mongoRepo repository.Repository
 }
 
-func NewTaxonomyHandler(mongoRepo *repository.MongoRepo) *TaxonomyHandler {
+func NewTaxonomyHandler(mongoRepo repository.Repository) *TaxonomyHandler {
 	return &TaxonomyHandler{mongoRepo: mongoRepo}
 }
 
@@ -29,7 +30,7 @@ type CreateTaxonomyRequest struct {
 func (h *TaxonomyHandler) Create(c *gin.Context) {
 	var req CreateTaxonomyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
 		return
 	}
 
@@ -43,7 +44,7 @@ func (h *TaxonomyHandler) Create(c *gin.Context) {
 	}
 
 	if err := h.mongoRepo.CreateTaxonomy(ctx, tax); err != nil {
-		if mongo.IsDuplicateKeyError(err) {
+		if err == repository.ErrConflict {
 			utils.BadRequest(c, "taxonomy key already exists")
 			return
 		}
@@ -75,8 +76,8 @@ func (h *TaxonomyHandler) Get(c *gin.Context) {
 
 	tax, err := h.mongoRepo.GetTaxonomyByKey(ctx, key)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "taxonomy not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTaxonomyNotFound, "taxonomy not found")
 			return
 		}
 		utils.InternalError(c, "failed to get taxonomy")
@@ -86,6 +87,85 @@ func (h *TaxonomyHandler) Get(c *gin.Context) {
 	utils.Success(c, tax)
 }
 
+func (h *TaxonomyHandler) Stats(c *gin.Context) {
+	key := c.Param("key")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, err := h.mongoRepo.GetTaxonomyByKey(ctx, key); err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTaxonomyNotFound, "taxonomy not found")
+			return
+		}
+		utils.InternalError(c, "failed to get taxonomy")
+		return
+	}
+
+	terms, err := h.mongoRepo.GetTermsByTaxonomy(ctx, key)
+	if err != nil {
+		utils.InternalError(c, "failed to list terms")
+		return
+	}
+
+	usage, err := h.mongoRepo.CountTermUsage(ctx, key)
+	if err != nil {
+		utils.InternalError(c, "failed to count term usage")
+		return
+	}
+
+	stats := model.TaxonomyStats{
+		TaxonomyKey: key,
+		TermCount:   len(terms),
+		MaxDepth:    maxTermDepth(terms),
+	}
+	for _, t := range terms {
+		if t.ParentID.IsZero() {
+			stats.TopLevelCount++
+		}
+		stats.EntryReferences += usage[t.ID.Hex()]
+	}
+
+	utils.Success(c, stats)
+}
+
+// maxTermDepth 沿 parent_id 计算树的最大深度，顶层 term 深度为 1
+func maxTermDepth(terms []model.Term) int {
+	parents := make(map[primitive.ObjectID]primitive.ObjectID, len(terms))
+	for _, t := range terms {
+		parents[t.ID] = t.ParentID
+	}
+
+	depths := make(map[primitive.ObjectID]int, len(terms))
+	var depthOf func(id primitive.ObjectID, visiting map[primitive.ObjectID]bool) int
+	depthOf = func(id primitive.ObjectID, visiting map[primitive.ObjectID]bool) int {
+		if d, ok := depths[id]; ok {
+			return d
+		}
+		parentID, ok := parents[id]
+		if !ok {
+			return 0
+		}
+		// Missing parents and cycles are treated as top level
+		if _, exists := parents[parentID]; parentID.IsZero() || !exists || visiting[id] {
+			depths[id] = 1
+			return 1
+		}
+		visiting[id] = true
+		d := depthOf(parentID, visiting) + 1
+		depths[id] = d
+		return d
+	}
+
+	deepest := 0
+	for _, t := range terms {
+		if d := depthOf(t.ID, map[primitive.ObjectID]bool{}); d > deepest {
+			deepest = d
+		}
+	}
+	return deepest
+}
+
 type UpdateTaxonomyRequest struct {
 	Name           string `json:"name" binding:"required,max=100"`
 	IsHierarchical *bool  `json:"is_hierarchical"`
@@ -96,7 +176,7 @@ func (h *TaxonomyHandler) Update(c *gin.Context) {
 
 	var req UpdateTaxonomyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
 		return
 	}
 
@@ -105,8 +185,8 @@ func (h *TaxonomyHandler) Update(c *gin.Context) {
 
 	tax, err := h.mongoRepo.GetTaxonomyByKey(ctx, key)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "taxonomy not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTaxonomyNotFound, "taxonomy not found")
 			return
 		}
 		utils.InternalError(c, "failed to get taxonomy")
@@ -135,8 +215,8 @@ func (h *TaxonomyHandler) Delete(c *gin.Context) {
 	// Check if taxonomy exists
 	_, err := h.mongoRepo.GetTaxonomyByKey(ctx, key)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "taxonomy not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTaxonomyNotFound, "taxonomy not found")
 			return
 		}
 		utils.InternalError(c, "failed to get taxonomy")
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTaxonomyRouter 按 main.go 的路由注册 taxonomy 和 term 的 handler，省略鉴权中间件
+func newTaxonomyRouter(repo repository.Repository) *gin.Engine {
+	taxonomyHandler := NewTaxonomyHandler(repo)
+	termHandler := NewTermHandler(repo, nil)
+
+	r := gin.New()
+	taxonomies := r.Group("/taxonomies")
+	taxonomies.GET("", taxonomyHandler.List)
+	taxonomies.GET("/:key", taxonomyHandler.Get)
+	taxonomies.POST("", taxonomyHandler.Create)
+	taxonomies.PUT("/:key", taxonomyHandler.Update)
+	taxonomies.DELETE("/:key", taxonomyHandler.Delete)
+	r.POST("/terms", termHandler.Create)
+	return r
+}
+
+func TestTaxonomyLifecycle(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	r := newTaxonomyRouter(repo)
+
+	steps := []struct {
+		name     string
+		method   string
+		path     string
+		body     any
+		wantCode int
+	}{
+		{"create", http.MethodPost, "/taxonomies", CreateTaxonomyRequest{Key: "category", Name: "Category", IsHierarchical: true}, http.StatusCreated},
+		{"duplicate key", http.MethodPost, "/taxonomies", CreateTaxonomyRequest{Key: "category", Name: "Again"}, http.StatusBadRequest},
+		{"invalid key", http.MethodPost, "/taxonomies", CreateTaxonomyRequest{Key: "no spaces", Name: "Bad"}, http.StatusBadRequest},
+		{"get", http.MethodGet, "/taxonomies/category", nil, http.StatusOK},
+		{"get missing", http.MethodGet, "/taxonomies/tag", nil, http.StatusNotFound},
+		{"update", http.MethodPut, "/taxonomies/category", UpdateTaxonomyRequest{Name: "Categories"}, http.StatusOK},
+		{"update missing", http.MethodPut, "/taxonomies/tag", UpdateTaxonomyRequest{Name: "Tags"}, http.StatusNotFound},
+		{"create term", http.MethodPost, "/terms", CreateTermRequest{TaxonomyKey: "category", Name: "Go"}, http.StatusCreated},
+		{"term in missing taxonomy", http.MethodPost, "/terms", CreateTermRequest{TaxonomyKey: "tag", Name: "Go"}, http.StatusNotFound},
+		{"delete", http.MethodDelete, "/taxonomies/category", nil, http.StatusOK},
+		{"get deleted", http.MethodGet, "/taxonomies/category", nil, http.StatusNotFound},
+		{"delete missing", http.MethodDelete, "/taxonomies/category", nil, http.StatusNotFound},
+	}
+	for _, step := range steps {
+		code, resp := doJSON(t, r, step.method, step.path, step.body)
+		if code != step.wantCode {
+			t.Fatalf("%s: status = %d (%s), want %d", step.name, code, resp.Message, step.wantCode)
+		}
+	}
+
+	// 删除 taxonomy 时其下的 term 一并删除
+	terms, err := repo.GetTermsByTaxonomy(context.Background(), "category")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(terms) != 0 {
+		t.Errorf("terms left after delete: %v", terms)
+	}
+}
+
+func TestTaxonomyUpdateKeepsHierarchy(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	r := newTaxonomyRouter(repo)
+	if err := repo.CreateTaxonomy(context.Background(), &model.Taxonomy{Key: "category", Name: "Category", IsHierarchical: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	flat := false
+	for _, step := range []struct {
+		req  UpdateTaxonomyRequest
+		want bool
+	}{
+		{UpdateTaxonomyRequest{Name: "Renamed"}, true}, // 省略 is_hierarchical 时保持不变
+		{UpdateTaxonomyRequest{Name: "Flat", IsHierarchical: &flat}, false},
+	} {
+		if code, resp := doJSON(t, r, http.MethodPut, "/taxonomies/category", step.req); code != http.StatusOK {
+			t.Fatalf("update: status = %d (%s)", code, resp.Message)
+		}
+		tax, err := repo.GetTaxonomyByKey(context.Background(), "category")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tax.Name != step.req.Name || tax.IsHierarchical != step.want {
+			t.Errorf("taxonomy = %q hierarchical=%v, want %q %v", tax.Name, tax.IsHierarchical, step.req.Name, step.want)
+		}
+	}
+}
+
+func TestTaxonomyStatsCountsNestedReferences(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	if err := repo.CreateTaxonomy(ctx, &model.Taxonomy{Key: "brand", Name: "Brand"}); err != nil {
+		t.Fatal(err)
+	}
+	acme := &model.Term{TaxonomyKey: "brand", Name: "Acme", Slug: "acme"}
+	globex := &model.Term{TaxonomyKey: "brand", Name: "Globex", Slug: "globex"}
+	for _, term := range []*model.Term{acme, globex} {
+		if err := repo.CreateTerm(ctx, term); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schema := &model.Schema{Key: "product", Version: 1, Fields: []model.FieldSchema{
+		{Key: "maker", Type: model.TypeTaxonomy, TaxonomyKey: "brand"},
+		{Key: "variants", Type: model.TypeArray, ItemType: &model.FieldSchema{Type: model.TypeObject, Children: []model.FieldSchema{
+			{Key: "brands", Type: model.TypeTaxonomy, TaxonomyKey: "brand", AllowMultiple: true},
+		}}},
+	}}
+	if err := repo.CreateSchema(ctx, schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, attrs := range []map[string]any{
+		{"maker": acme.ID.Hex()},
+		{"variants": []any{map[string]any{"brands": []any{acme.ID.Hex(), globex.ID.Hex()}}}},
+		{"brand": globex.ID.Hex()}, // 与 taxonomy 同名但 schema 未声明的属性不算引用
+	} {
+		if err := repo.CreateEntry(ctx, &model.Entry{SchemaKey: "product", SchemaID: schema.ID, Attributes: attrs}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := gin.New()
+	r.GET("/taxonomies/:key/stats", NewTaxonomyHandler(repo).Stats)
+	code, resp := doJSON(t, r, http.MethodGet, "/taxonomies/brand/stats", nil)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", code, resp.Message)
+	}
+	stats, _ := resp.Data.(map[string]any)
+	if got := stats["entry_references"]; got != float64(3) {
+		t.Errorf("entry_references = %v, want 3", got)
+	}
+}
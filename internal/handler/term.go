@@ -2,37 +2,53 @@ package handler
 
 import (
 	"context"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"matter-core/internal/model"
 	"matter-core/internal/repository"
+	"matter-core/internal/service"
 	"matter-core/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+const termCountsTTL = 1 * time.Minute
+
 type TermHandler struct {
-	mongoRepo *repository.MongoRepo
+	mongoRepo   repository.Repository
+	syncSvc     *service.SyncService
+	countsCache *utils.TTLCache[[]model.TermUsage]
 }
 
-func NewTermHandler(mongoRepo *repository.MongoRepo) *TermHandler {
-	return &TermHandler{mongoRepo: mongoRepo}
+func NewTermHandler(mongoRepo repository.Repository, syncSvc *service.SyncService) *TermHandler {
+	return &TermHandler{
+		mongoRepo:   mongoRepo,
+		syncSvc:     syncSvc,
+		countsCache: utils.NewTTLCache[[]model.TermUsage](termCountsTTL),
+	}
 }
 
 type CreateTermRequest struct {
 	TaxonomyKey string `json:"taxonomy_key" binding:"required,max=50"`
 	Name        string `json:"name" binding:"required,max=100"`
-	Slug        string `json:"slug" binding:"required,max=100"`
-	Color       string `json:"color" binding:"max=20"`
+	Slug        string `json:"slug" binding:"max=100"` // 留空则根据 name 自动生成
+	Description string `json:"description" binding:"max=1000"`
+	Color       string `json:"color" binding:"max=7"` // #RGB 或 #RRGGBB，留空表示无颜色
 	ParentID    string `json:"parent_id"`
 }
 
 func (h *TermHandler) Create(c *gin.Context) {
 	var req CreateTermRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
+		return
+	}
+	if !utils.IsValidColor(req.Color) {
+		utils.BadRequest(c, "invalid color: use #RGB or #RRGGBB")
 		return
 	}
 
@@ -40,31 +56,50 @@ func (h *TermHandler) Create(c *gin.Context) {
 	defer cancel()
 
 	// Verify taxonomy exists
-	_, err := h.mongoRepo.GetTaxonomyByKey(ctx, req.TaxonomyKey)
+	tax, err := h.mongoRepo.GetTaxonomyByKey(ctx, req.TaxonomyKey)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "taxonomy not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTaxonomyNotFound, "taxonomy not found")
 			return
 		}
 		utils.InternalError(c, "failed to verify taxonomy")
 		return
 	}
-
-	// Check slug uniqueness
-	exists, err := h.mongoRepo.IsTermSlugExists(ctx, req.TaxonomyKey, req.Slug, primitive.NilObjectID)
-	if err != nil {
-		utils.InternalError(c, "failed to check slug")
+	if req.ParentID != "" && !tax.IsHierarchical {
+		utils.BadRequest(c, "taxonomy is not hierarchical: parent_id is not allowed")
 		return
 	}
-	if exists {
-		utils.BadRequest(c, "slug already exists in this taxonomy")
-		return
+
+	if req.Slug == "" {
+		slug, err := h.uniqueSlug(ctx, req.TaxonomyKey, req.Name)
+		if err != nil {
+			utils.InternalError(c, "failed to generate slug")
+			return
+		}
+		req.Slug = slug
+	} else {
+		if !utils.IsValidSlug(req.Slug) {
+			utils.BadRequest(c, "invalid slug format")
+			return
+		}
+
+		// Check slug uniqueness
+		exists, err := h.mongoRepo.IsTermSlugExists(ctx, req.TaxonomyKey, req.Slug, primitive.NilObjectID)
+		if err != nil {
+			utils.InternalError(c, "failed to check slug")
+			return
+		}
+		if exists {
+			utils.BadRequest(c, "slug already exists in this taxonomy")
+			return
+		}
 	}
 
 	term := &model.Term{
 		TaxonomyKey: req.TaxonomyKey,
 		Name:        req.Name,
 		Slug:        req.Slug,
+		Description: req.Description,
 		Color:       req.Color,
 	}
 
@@ -74,10 +109,27 @@ func (h *TermHandler) Create(c *gin.Context) {
 			utils.BadRequest(c, "invalid parent_id")
 			return
 		}
+		parent, err := h.mongoRepo.GetTermByID(ctx, parentOID)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				utils.BadRequest(c, "parent term not found")
+				return
+			}
+			utils.InternalError(c, "failed to get parent term")
+			return
+		}
+		if parent.TaxonomyKey != req.TaxonomyKey {
+			utils.BadRequest(c, "parent term belongs to a different taxonomy")
+			return
+		}
 		term.ParentID = parentOID
 	}
 
 	if err := h.mongoRepo.CreateTerm(ctx, term); err != nil {
+		if err == repository.ErrConflict {
+			utils.Conflict(c, "slug already exists in this taxonomy")
+			return
+		}
 		utils.InternalError(c, "failed to create term")
 		return
 	}
@@ -85,6 +137,28 @@ func (h *TermHandler) Create(c *gin.Context) {
 	utils.Created(c, term)
 }
 
+// uniqueSlug 根据 name 生成分类内唯一的 slug，冲突时追加数字后缀
+func (h *TermHandler) uniqueSlug(ctx context.Context, taxonomyKey, name string) (string, error) {
+	base := utils.Slugify(name)
+	if base == "" {
+		base = "term"
+	}
+	if runes := []rune(base); len(runes) > 90 {
+		base = strings.Trim(string(runes[:90]), "-")
+	}
+	slug := base
+	for i := 2; ; i++ {
+		exists, err := h.mongoRepo.IsTermSlugExists(ctx, taxonomyKey, slug, primitive.NilObjectID)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+		slug = base + "-" + strconv.Itoa(i)
+	}
+}
+
 func (h *TermHandler) ListByTaxonomy(c *gin.Context) {
 	taxonomyKey := c.Param("key")
 
@@ -100,6 +174,80 @@ func (h *TermHandler) ListByTaxonomy(c *gin.Context) {
 	utils.Success(c, terms)
 }
 
+// Tree 返回分类下所有 term 组成的树，从一次平铺查询在内存中构建
+func (h *TermHandler) Tree(c *gin.Context) {
+	taxonomyKey := c.Param("key")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	terms, err := h.mongoRepo.GetTermsByTaxonomy(ctx, taxonomyKey)
+	if err != nil {
+		utils.InternalError(c, "failed to list terms")
+		return
+	}
+
+	utils.Success(c, buildTermTree(terms))
+}
+
+// Counts 返回分类下每个 term 的 entry 引用数，结果短暂缓存
+func (h *TermHandler) Counts(c *gin.Context) {
+	taxonomyKey := c.Param("key")
+
+	if cached, ok := h.countsCache.Get(taxonomyKey); ok {
+		utils.Success(c, cached)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	terms, err := h.mongoRepo.GetTermsByTaxonomy(ctx, taxonomyKey)
+	if err != nil {
+		utils.InternalError(c, "failed to list terms")
+		return
+	}
+
+	counts, err := h.mongoRepo.CountTermUsage(ctx, taxonomyKey)
+	if err != nil {
+		utils.InternalError(c, "failed to count term usage")
+		return
+	}
+
+	usage := make([]model.TermUsage, 0, len(terms))
+	for _, t := range terms {
+		usage = append(usage, model.TermUsage{TermID: t.ID, Count: counts[t.ID.Hex()]})
+	}
+	h.countsCache.Set(taxonomyKey, usage)
+
+	utils.Success(c, usage)
+}
+
+func buildTermTree(terms []model.Term) []*model.TermNode {
+	nodes := make(map[primitive.ObjectID]*model.TermNode, len(terms))
+	for _, t := range terms {
+		nodes[t.ID] = &model.TermNode{Term: t, Children: []*model.TermNode{}}
+	}
+
+	roots := make([]*model.TermNode, 0)
+	for _, t := range terms {
+		node := nodes[t.ID]
+		if t.ParentID.IsZero() {
+			roots = append(roots, node)
+			continue
+		}
+		parent, ok := nodes[t.ParentID]
+		if !ok || parent == node {
+			// Parent missing (or self-referencing), surface at root level
+			node.Orphan = true
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return roots
+}
+
 func (h *TermHandler) Get(c *gin.Context) {
 	id := c.Param("id")
 	oid, err := primitive.ObjectIDFromHex(id)
@@ -113,8 +261,26 @@ func (h *TermHandler) Get(c *gin.Context) {
 
 	term, err := h.mongoRepo.GetTermByID(ctx, oid)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "term not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTermNotFound, "term not found")
+			return
+		}
+		utils.InternalError(c, "failed to get term")
+		return
+	}
+
+	utils.Success(c, term)
+}
+
+// GET /api/v1/terms/taxonomy/:key/slug/:slug - 按 slug 获取 term，不区分大小写和重音
+func (h *TermHandler) GetBySlug(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	term, err := h.mongoRepo.GetTermBySlug(ctx, c.Param("key"), c.Param("slug"))
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTermNotFound, "term not found")
 			return
 		}
 		utils.InternalError(c, "failed to get term")
@@ -125,10 +291,11 @@ func (h *TermHandler) Get(c *gin.Context) {
 }
 
 type UpdateTermRequest struct {
-	Name     string `json:"name" binding:"required,max=100"`
-	Slug     string `json:"slug" binding:"required,max=100,alphanumunicode"`
-	Color    string `json:"color" binding:"max=20"`
-	ParentID string `json:"parent_id"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Slug        string `json:"slug" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+	Color       string `json:"color" binding:"max=7"` // #RGB 或 #RRGGBB，留空表示无颜色
+	ParentID    string `json:"parent_id"`
 }
 
 func (h *TermHandler) Update(c *gin.Context) {
@@ -141,7 +308,11 @@ func (h *TermHandler) Update(c *gin.Context) {
 
 	var req UpdateTermRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.BadRequest(c, err.Error())
+		utils.BindError(c, err)
+		return
+	}
+	if !utils.IsValidColor(req.Color) {
+		utils.BadRequest(c, "invalid color: use #RGB or #RRGGBB")
 		return
 	}
 
@@ -150,14 +321,19 @@ func (h *TermHandler) Update(c *gin.Context) {
 
 	term, err := h.mongoRepo.GetTermByID(ctx, oid)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "term not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTermNotFound, "term not found")
 			return
 		}
 		utils.InternalError(c, "failed to get term")
 		return
 	}
 
+	if !utils.IsValidSlug(req.Slug) {
+		utils.BadRequest(c, "invalid slug format")
+		return
+	}
+
 	// Check slug uniqueness (exclude current term)
 	if req.Slug != term.Slug {
 		exists, err := h.mongoRepo.IsTermSlugExists(ctx, term.TaxonomyKey, req.Slug, oid)
@@ -171,29 +347,216 @@ func (h *TermHandler) Update(c *gin.Context) {
 		}
 	}
 
+	renamed := term.Name != req.Name
 	term.Name = req.Name
 	term.Slug = req.Slug
+	term.Description = req.Description
 	term.Color = req.Color
 
 	if req.ParentID != "" {
+		tax, err := h.mongoRepo.GetTaxonomyByKey(ctx, term.TaxonomyKey)
+		if err != nil {
+			utils.InternalError(c, "failed to get taxonomy")
+			return
+		}
+		if !tax.IsHierarchical {
+			utils.BadRequest(c, "taxonomy is not hierarchical: parent_id is not allowed")
+			return
+		}
+
 		parentOID, err := primitive.ObjectIDFromHex(req.ParentID)
 		if err != nil {
 			utils.BadRequest(c, "invalid parent_id")
 			return
 		}
+		if parentOID == oid {
+			utils.BadRequest(c, "term cannot be its own parent")
+			return
+		}
+
+		parent, err := h.mongoRepo.GetTermByID(ctx, parentOID)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				utils.BadRequest(c, "parent term not found")
+				return
+			}
+			utils.InternalError(c, "failed to get parent term")
+			return
+		}
+		if parent.TaxonomyKey != term.TaxonomyKey {
+			utils.BadRequest(c, "parent term belongs to a different taxonomy")
+			return
+		}
+
+		// Reject if this term is already an ancestor of the new parent (A→B→A)
+		ancestors, err := h.mongoRepo.GetTermAncestors(ctx, parentOID)
+		if err != nil {
+			utils.InternalError(c, "failed to check term ancestry")
+			return
+		}
+		for _, a := range ancestors {
+			if a.ID == oid {
+				utils.BadRequest(c, "parent_id would create a cycle")
+				return
+			}
+		}
 		term.ParentID = parentOID
 	} else {
 		term.ParentID = primitive.NilObjectID
 	}
 
 	if err := h.mongoRepo.UpdateTerm(ctx, term); err != nil {
+		if err == repository.ErrConflict {
+			utils.Conflict(c, "slug already exists in this taxonomy")
+			return
+		}
 		utils.InternalError(c, "failed to update term")
 		return
 	}
 
+	// Term names are part of the indexed text of entries that use them
+	if renamed && h.syncSvc != nil {
+		h.syncSvc.ResyncByTerm(term)
+	}
+
 	utils.Success(c, term)
 }
 
+type ReorderTermsRequest struct {
+	TermIDs []string `json:"term_ids" binding:"required,min=1,max=1000"`
+}
+
+func (h *TermHandler) Reorder(c *gin.Context) {
+	taxonomyKey := c.Param("key")
+
+	var req ReorderTermsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(req.TermIDs))
+	oids := make([]primitive.ObjectID, 0, len(req.TermIDs))
+	for _, id := range req.TermIDs {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			utils.BadRequest(c, "invalid term id: "+id)
+			return
+		}
+		if seen[oid] {
+			utils.BadRequest(c, "duplicate term id: "+id)
+			return
+		}
+		seen[oid] = true
+		oids = append(oids, oid)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	// All terms must belong to this taxonomy
+	count, err := h.mongoRepo.CountTermsInTaxonomy(ctx, taxonomyKey, oids)
+	if err != nil {
+		utils.InternalError(c, "failed to verify terms")
+		return
+	}
+	if count != int64(len(oids)) {
+		utils.BadRequest(c, "some terms do not belong to this taxonomy")
+		return
+	}
+
+	if err := h.mongoRepo.ReorderTerms(ctx, taxonomyKey, oids); err != nil {
+		utils.InternalError(c, "failed to reorder terms")
+		return
+	}
+
+	terms, err := h.mongoRepo.GetTermsByTaxonomy(ctx, taxonomyKey)
+	if err != nil {
+		utils.InternalError(c, "failed to list terms")
+		return
+	}
+
+	utils.Success(c, terms)
+}
+
+type MergeTermsRequest struct {
+	SourceID string `json:"source_id" binding:"required"`
+	TargetID string `json:"target_id" binding:"required"`
+}
+
+func (h *TermHandler) Merge(c *gin.Context) {
+	var req MergeTermsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+
+	sourceOID, err := primitive.ObjectIDFromHex(req.SourceID)
+	if err != nil {
+		utils.BadRequest(c, "invalid source_id")
+		return
+	}
+	targetOID, err := primitive.ObjectIDFromHex(req.TargetID)
+	if err != nil {
+		utils.BadRequest(c, "invalid target_id")
+		return
+	}
+	if sourceOID == targetOID {
+		utils.BadRequest(c, "source and target must be different terms")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	source, err := h.mongoRepo.GetTermByID(ctx, sourceOID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTermNotFound, "source term not found")
+			return
+		}
+		utils.InternalError(c, "failed to get source term")
+		return
+	}
+	target, err := h.mongoRepo.GetTermByID(ctx, targetOID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTermNotFound, "target term not found")
+			return
+		}
+		utils.InternalError(c, "failed to get target term")
+		return
+	}
+	if source.TaxonomyKey != target.TaxonomyKey {
+		utils.BadRequest(c, "terms belong to different taxonomies")
+		return
+	}
+
+	// Merging into a deeper descendant would turn the re-parented children into a cycle
+	if target.ParentID != sourceOID {
+		ancestors, err := h.mongoRepo.GetTermAncestors(ctx, targetOID)
+		if err != nil {
+			utils.InternalError(c, "failed to check term ancestry")
+			return
+		}
+		for _, a := range ancestors {
+			if a.ID == sourceOID {
+				utils.BadRequest(c, "cannot merge a term into its own descendant")
+				return
+			}
+		}
+	}
+
+	rewritten, err := h.mongoRepo.MergeTerms(ctx, source, target)
+	if err != nil {
+		utils.InternalError(c, "failed to merge terms")
+		return
+	}
+	h.countsCache.Delete(source.TaxonomyKey)
+
+	utils.Success(c, gin.H{"target": target.ID, "entries_rewritten": rewritten})
+}
+
 func (h *TermHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 	oid, err := primitive.ObjectIDFromHex(id)
@@ -208,8 +571,8 @@ func (h *TermHandler) Delete(c *gin.Context) {
 	// Check if term exists
 	term, err := h.mongoRepo.GetTermByID(ctx, oid)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			utils.NotFound(c, "term not found")
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeTermNotFound, "term not found")
 			return
 		}
 		utils.InternalError(c, "failed to get term")
@@ -217,26 +580,37 @@ func (h *TermHandler) Delete(c *gin.Context) {
 	}
 
 	// Check if this term has children
-	hasChildren, err := h.mongoRepo.HasChildTerms(ctx, oid)
+	childCount, err := h.mongoRepo.CountChildTerms(ctx, oid)
 	if err != nil {
 		utils.InternalError(c, "failed to check child terms")
 		return
 	}
-	if hasChildren {
-		utils.BadRequest(c, "cannot delete term: has child terms")
-		return
-	}
 
 	// Check if any entries reference this term
-	hasRefs, err := h.mongoRepo.HasTermReferences(ctx, term.TaxonomyKey, oid)
+	refCount, err := h.mongoRepo.CountTermReferences(ctx, term.TaxonomyKey, oid)
 	if err != nil {
 		utils.InternalError(c, "failed to check term references")
 		return
 	}
-	if hasRefs {
-		utils.BadRequest(c, "cannot delete term: entries are referencing this term")
+
+	usage := gin.H{"children": childCount, "references": refCount}
+	if childCount > 0 {
+		utils.ErrorWithData(c, http.StatusConflict, "cannot delete term: has child terms", usage)
 		return
 	}
+	// ?force=true 时允许删除被引用的 term，并清理 entry 中的引用
+	force := c.Query("force") == "true"
+	if refCount > 0 && !force {
+		utils.ErrorWithData(c, http.StatusConflict, "cannot delete term: entries are referencing this term", usage)
+		return
+	}
+
+	if refCount > 0 {
+		if _, err := h.mongoRepo.RemoveTermReferences(ctx, term.TaxonomyKey, oid); err != nil {
+			utils.InternalError(c, "failed to remove term references")
+			return
+		}
+	}
 
 	if err := h.mongoRepo.DeleteTerm(ctx, oid); err != nil {
 		utils.InternalError(c, "failed to delete term")
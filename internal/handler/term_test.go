@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+	"matter-core/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// serveJSON 以 JSON 请求体调用一次 handler，返回状态码和解码后的响应
+func serveJSON(t *testing.T, method string, body any, handlers ...gin.HandlerFunc) (int, utils.Response) {
+	t.Helper()
+	r := gin.New()
+	r.Handle(method, "/", handlers...)
+	return doJSON(t, r, method, "/", body)
+}
+
+// doJSON 向路由发送一次请求，body 为 nil 时不带请求体
+func doJSON(t *testing.T, r http.Handler, method, path string, body any) (int, utils.Response) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp utils.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, resp
+}
+
+func TestTermCreateParent(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	for _, tax := range []*model.Taxonomy{
+		{Key: "category", Name: "Category", IsHierarchical: true},
+		{Key: "region", Name: "Region", IsHierarchical: true},
+		{Key: "tag", Name: "Tag"},
+	} {
+		if err := repo.CreateTaxonomy(ctx, tax); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parent := &model.Term{TaxonomyKey: "category", Name: "Go", Slug: "go"}
+	foreign := &model.Term{TaxonomyKey: "region", Name: "Asia", Slug: "asia"}
+	for _, term := range []*model.Term{parent, foreign} {
+		if err := repo.CreateTerm(ctx, term); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewTermHandler(repo, nil)
+
+	tests := []struct {
+		name     string
+		req      CreateTermRequest
+		wantCode int
+		wantMsg  string
+	}{
+		{"same taxonomy parent", CreateTermRequest{TaxonomyKey: "category", Name: "Generics", ParentID: parent.ID.Hex()}, http.StatusCreated, "created"},
+		{"cross-taxonomy parent", CreateTermRequest{TaxonomyKey: "category", Name: "Tokyo", ParentID: foreign.ID.Hex()}, http.StatusBadRequest, "parent term belongs to a different taxonomy"},
+		{"missing parent", CreateTermRequest{TaxonomyKey: "category", Name: "Orphan", ParentID: primitive.NewObjectID().Hex()}, http.StatusBadRequest, "parent term not found"},
+		{"malformed parent", CreateTermRequest{TaxonomyKey: "category", Name: "Orphan", ParentID: "nope"}, http.StatusBadRequest, "invalid parent_id"},
+		{"flat taxonomy", CreateTermRequest{TaxonomyKey: "tag", Name: "Child", ParentID: parent.ID.Hex()}, http.StatusBadRequest, "taxonomy is not hierarchical: parent_id is not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := serveJSON(t, http.MethodPost, tt.req, h.Create)
+			if code != tt.wantCode || resp.Message != tt.wantMsg {
+				t.Errorf("Create() = %d %q, want %d %q", code, resp.Message, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
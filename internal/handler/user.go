@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+	"matter-core/internal/service"
+	"matter-core/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UserHandler struct {
+	mongoRepo    repository.Repository
+	sessionStore *service.SessionStore
+}
+
+func NewUserHandler(mongoRepo repository.Repository, sessionStore *service.SessionStore) *UserHandler {
+	return &UserHandler{mongoRepo: mongoRepo, sessionStore: sessionStore}
+}
+
+type BanUserRequest struct {
+	Reason string     `json:"reason" binding:"max=500"`
+	Until  *time.Time `json:"until"` // 留空表示永久封禁
+}
+
+// POST /api/v1/users/:id/ban - 封禁用户并注销其所有 session
+func (h *UserHandler) Ban(c *gin.Context) {
+	var req BanUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindError(c, err)
+		return
+	}
+	if req.Until != nil && req.Until.Before(time.Now()) {
+		utils.BadRequest(c, "until must be in the future")
+		return
+	}
+
+	userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid user id")
+		return
+	}
+	if currentUserID, _ := c.Get("user_id"); currentUserID == userOID.Hex() {
+		utils.BadRequest(c, "cannot ban yourself")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.mongoRepo.GetUserByID(ctx, userOID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeUserNotFound, "user not found")
+			return
+		}
+		utils.InternalError(c, "failed to get user")
+		return
+	}
+	if user.Role == string(model.RoleAdmin) {
+		utils.Forbidden(c, "cannot ban an administrator")
+		return
+	}
+
+	if err := h.mongoRepo.SetUserBan(ctx, userOID, true, req.Until, req.Reason); err != nil {
+		utils.InternalError(c, "failed to ban user")
+		return
+	}
+	if err := h.sessionStore.RevokeAll(ctx, userOID); err != nil {
+		utils.InternalError(c, "failed to revoke sessions")
+		return
+	}
+
+	user.Banned = true
+	user.BannedUntil = req.Until
+	user.BanReason = req.Reason
+	utils.Success(c, user)
+}
+
+// POST /api/v1/users/:id/unban - 解除封禁
+func (h *UserHandler) Unban(c *gin.Context) {
+	userOID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid user id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.mongoRepo.GetUserByID(ctx, userOID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			utils.ErrorWithCode(c, http.StatusNotFound, utils.CodeUserNotFound, "user not found")
+			return
+		}
+		utils.InternalError(c, "failed to get user")
+		return
+	}
+
+	if err := h.mongoRepo.SetUserBan(ctx, userOID, false, nil, ""); err != nil {
+		utils.InternalError(c, "failed to unban user")
+		return
+	}
+	h.sessionStore.ForgetBan(userOID)
+
+	user.Banned = false
+	user.BannedUntil = nil
+	user.BanReason = ""
+	utils.Success(c, user)
+}
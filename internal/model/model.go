@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,13 +20,62 @@ const (
 	TypeTaxonomy FieldType = "taxonomy"
 )
 
+// BodyFormat entry 正文的格式，决定搜索索引和摘要如何提取纯文本
+type BodyFormat string
+
+const (
+	BodyMarkdown BodyFormat = "markdown"
+	BodyHTML     BodyFormat = "html"
+	BodyPlain    BodyFormat = "plain"
+)
+
+// EntryStatus entry 的编辑状态，只有 published 对所有人可见
+type EntryStatus string
+
+const (
+	EntryDraft     EntryStatus = "draft"
+	EntryInReview  EntryStatus = "in_review"
+	EntryPublished EntryStatus = "published"
+	EntryArchived  EntryStatus = "archived"
+)
+
+// Valid 判断是否为已定义的状态
+func (s EntryStatus) Valid() bool {
+	switch s {
+	case EntryDraft, EntryInReview, EntryPublished, EntryArchived:
+		return true
+	}
+	return false
+}
+
+type CommentStatus string
+
+const (
+	CommentPending  CommentStatus = "pending"
+	CommentApproved CommentStatus = "approved"
+	CommentRejected CommentStatus = "rejected"
+)
+
 type UserRole string
 
 const (
-	RoleAdmin UserRole = "admin"
-	RoleUser  UserRole = "user"
+	RoleAdmin  UserRole = "admin"
+	RoleEditor UserRole = "editor" // 可管理所有 entry 和评论，但不能管理 schema / taxonomy
+	RoleUser   UserRole = "user"
 )
 
+// Rank 角色的权限等级，数值越大权限越高
+func (r UserRole) Rank() int {
+	switch r {
+	case RoleAdmin:
+		return 2
+	case RoleEditor:
+		return 1
+	default:
+		return 0
+	}
+}
+
 // --- 1. Schema (Immutable, Versioned) ---
 type FieldSchema struct {
 	Key      string    `bson:"key" json:"key"`
@@ -33,30 +83,88 @@ type FieldSchema struct {
 	Type     FieldType `bson:"type" json:"type"`
 	Required bool      `bson:"required" json:"required"`
 	Default  any       `bson:"default,omitempty" json:"default,omitempty"`
+	Integer  bool      `bson:"integer,omitempty" json:"integer,omitempty"`   // TypeNumber 仅接受整数，按 int64 存储
+	Unique   bool      `bson:"unique,omitempty" json:"unique,omitempty"`     // 同一 schema 的 entry 间取值唯一，仅限顶层 string/number/date 字段
+	Nullable bool      `bson:"nullable,omitempty" json:"nullable,omitempty"` // 允许显式传入 null，仅对非必填字段有效；否则 null 被拒绝
 
 	// Complex Types
 	Children      []FieldSchema `bson:"children,omitempty" json:"children,omitempty"`
 	ItemType      *FieldSchema  `bson:"item_type,omitempty" json:"item_type,omitempty"`
+	MinItems      *int          `bson:"min_items,omitempty" json:"min_items,omitempty"` // TypeArray 的最少元素数
+	MaxItems      *int          `bson:"max_items,omitempty" json:"max_items,omitempty"` // TypeArray 的最多元素数，另受全局上限约束
 	TaxonomyKey   string        `bson:"taxonomy_key,omitempty" json:"taxonomy_key,omitempty"`
-	AllowMultiple bool          `bson:"allow_multiple,omitempty" json:"allow_multiple,omitempty"`
+	AllowMultiple bool          `bson:"allow_multiple,omitempty" json:"allow_multiple,omitempty"` // 多值 taxonomy 的唯一表示方式（存为 term ID 数组），不支持元素为 taxonomy 的数组字段
+}
+
+// WalkTaxonomyFields 递归遍历对象子字段和数组元素类型，回调每个指定了 TaxonomyKey 的 taxonomy 字段；
+// 路径形如 "specs.brand"，数组元素记为 "items[]"
+func WalkTaxonomyFields(prefix string, fields []FieldSchema, fn func(path, taxonomyKey string)) {
+	for _, field := range fields {
+		walkTaxonomyField(prefix+field.Key, field, fn)
+	}
+}
+
+func walkTaxonomyField(path string, field FieldSchema, fn func(path, taxonomyKey string)) {
+	switch field.Type {
+	case TypeTaxonomy:
+		if field.TaxonomyKey != "" {
+			fn(path, field.TaxonomyKey)
+		}
+	case TypeObject:
+		WalkTaxonomyFields(path+".", field.Children, fn)
+	case TypeArray:
+		if field.ItemType != nil {
+			walkTaxonomyField(path+"[]", *field.ItemType, fn)
+		}
+	}
+}
+
+// TaxonomyFieldPaths 返回引用该 taxonomy 的字段在 attributes 中的路径，途经的数组记为 "[]"，
+// 例如 "variants[].brands"；旧 schema 中元素为 taxonomy 的数组与多值字段存储方式相同，记为数组本身的路径
+func TaxonomyFieldPaths(fields []FieldSchema, taxonomyKey string) []string {
+	var paths []string
+	WalkTaxonomyFields("", fields, func(path, key string) {
+		if key == taxonomyKey {
+			paths = append(paths, strings.TrimSuffix(path, "[]"))
+		}
+	})
+	return paths
 }
 
 type Schema struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Key       string             `bson:"key" json:"key"`
-	Version   int                `bson:"version" json:"version"`
-	Name      string             `bson:"name" json:"name"`
-	Fields    []FieldSchema      `bson:"fields" json:"fields"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Key     string             `bson:"key" json:"key"`
+	Version int                `bson:"version" json:"version"`
+	Name    string             `bson:"name" json:"name"`
+	Fields  []FieldSchema      `bson:"fields" json:"fields"`
+	Strict  bool               `bson:"strict,omitempty" json:"strict"` // 拒绝 schema 中未声明的属性（含对象子字段）
+	// CommentsDisabled 该 schema 下 entry 默认不接受评论（例如页面），entry 可单独覆盖
+	CommentsDisabled bool      `bson:"comments_disabled,omitempty" json:"comments_disabled"`
+	Searchable       []string  `bson:"searchable,omitempty" json:"searchable,omitempty"` // 在搜索索引中单独建字段的顶层属性，按权重从高到低
+	CreatedAt        time.Time `bson:"created_at" json:"created_at"`
 }
 
 // --- 2. Entry (Dynamic Content) ---
 type BaseMeta struct {
-	Title     string    `bson:"title" json:"title"`
-	Slug      string    `bson:"slug" json:"slug"`
-	Draft     bool      `bson:"draft" json:"draft"`
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	Title string `bson:"title" json:"title"`
+	Slug  string `bson:"slug" json:"slug"`
+	// SlugNormalized 由仓储层在写入时根据 Slug 生成，用于不区分大小写和重音的查找
+	SlugNormalized string      `bson:"slug_normalized" json:"-"`
+	Status         EntryStatus `bson:"status" json:"status"`
+	Draft          bool        `bson:"draft" json:"draft"` // 由 Status 推导（非 published 即为草稿），保留以兼容旧客户端和查询
+	CreatedAt      time.Time   `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time   `bson:"updated_at" json:"updated_at"`
+}
+
+// SyncStatus 以 Status 为准同步 Draft；没有 Status 时（旧数据或只传了 draft 的请求）由 Draft 推导
+func (b *BaseMeta) SyncStatus() {
+	if b.Status == "" {
+		b.Status = EntryPublished
+		if b.Draft {
+			b.Status = EntryDraft
+		}
+	}
+	b.Draft = b.Status != EntryPublished
 }
 
 type Entry struct {
@@ -68,7 +176,56 @@ type Entry struct {
 
 	Base       BaseMeta       `bson:"base" json:"base"`
 	Body       string         `bson:"body" json:"body"`
+	BodyFormat BodyFormat     `bson:"body_format" json:"body_format"` // 旧数据可能为空，视为 markdown
 	Attributes map[string]any `bson:"attributes" json:"attributes"`
+	// CommentsEnabled 为空时沿用 schema 的默认设置
+	CommentsEnabled *bool `bson:"comments_enabled,omitempty" json:"comments_enabled,omitempty"`
+	// Transfers 所有权转移记录，按时间顺序追加，不在公开视图中返回
+	Transfers []OwnershipTransfer `bson:"transfers,omitempty" json:"transfers,omitempty"`
+}
+
+// OwnershipTransfer 一次 entry 所有权转移：由 OperatorID 在 At 时刻从 FromAuthorID 转给 ToAuthorID
+type OwnershipTransfer struct {
+	FromAuthorID string    `bson:"from_author_id" json:"from_author_id"`
+	ToAuthorID   string    `bson:"to_author_id" json:"to_author_id"`
+	OperatorID   string    `bson:"operator_id" json:"operator_id"`
+	At           time.Time `bson:"at" json:"at"`
+}
+
+// AcceptsComments entry 是否接受评论，schema 为 entry 所属 schema 的最新版本
+func (e *Entry) AcceptsComments(schema *Schema) bool {
+	if e.CommentsEnabled != nil {
+		return *e.CommentsEnabled
+	}
+	return schema == nil || !schema.CommentsDisabled
+}
+
+// EntryPublic 面向普通读者的 entry 视图：不暴露 author_id，作者解析为 UserPublic（作者不存在时为空）
+type EntryPublic struct {
+	ID              primitive.ObjectID `json:"id"`
+	SchemaKey       string             `json:"schema_key"`
+	SchemaVersion   int                `json:"schema_version"`
+	Author          *UserPublic        `json:"author,omitempty"`
+	Base            BaseMeta           `json:"base"`
+	Body            string             `json:"body"`
+	BodyFormat      BodyFormat         `json:"body_format"`
+	Attributes      map[string]any     `json:"attributes"`
+	CommentsEnabled *bool              `json:"comments_enabled,omitempty"`
+}
+
+// Public 转换为 EntryPublic
+func (e *Entry) Public(author *UserPublic) EntryPublic {
+	return EntryPublic{
+		ID:              e.ID,
+		SchemaKey:       e.SchemaKey,
+		SchemaVersion:   e.SchemaVersion,
+		Author:          author,
+		Base:            e.Base,
+		Body:            e.Body,
+		BodyFormat:      e.BodyFormat,
+		Attributes:      e.Attributes,
+		CommentsEnabled: e.CommentsEnabled,
+	}
 }
 
 // --- 3. Taxonomy & Terms ---
@@ -77,6 +234,8 @@ type Taxonomy struct {
 	Key            string             `bson:"key" json:"key"`
 	Name           string             `bson:"name" json:"name"`
 	IsHierarchical bool               `bson:"is_hierarchical" json:"is_hierarchical"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
 type Term struct {
@@ -84,8 +243,58 @@ type Term struct {
 	TaxonomyKey string             `bson:"taxonomy_key" json:"taxonomy_key"`
 	Name        string             `bson:"name" json:"name"`
 	Slug        string             `bson:"slug" json:"slug"`
-	Color       string             `bson:"color" json:"color"`
-	ParentID    primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id"`
+	// SlugNormalized 由仓储层在写入时根据 Slug 生成，同一 taxonomy 内唯一
+	SlugNormalized string             `bson:"slug_normalized" json:"-"`
+	Description    string             `bson:"description" json:"description"`
+	Color          string             `bson:"color" json:"color"`
+	ParentID       primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id"`
+	Order          int                `bson:"order" json:"order"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// TermNode 层级分类的树形节点
+type TermNode struct {
+	Term
+	Orphan   bool        `json:"orphan,omitempty"` // 父节点不存在，已挂到根层级
+	Children []*TermNode `json:"children"`
+}
+
+// TaxonomyStats 分类的统计信息
+type TaxonomyStats struct {
+	TaxonomyKey     string `json:"taxonomy_key"`
+	TermCount       int    `json:"term_count"`
+	TopLevelCount   int    `json:"top_level_count"`
+	MaxDepth        int    `json:"max_depth"`
+	EntryReferences int64  `json:"entry_references"` // 各 term 被引用的 entry 数之和，覆盖所有引用该 taxonomy 的字段路径
+}
+
+// AdminStats 管理后台概览数据
+type AdminStats struct {
+	Entries         EntryStats       `json:"entries"`
+	Users           int64            `json:"users"`
+	Comments        int64            `json:"comments"`
+	TermsByTaxonomy map[string]int64 `json:"terms_by_taxonomy"`
+	GeneratedAt     time.Time        `json:"generated_at"`
+}
+
+type EntryStats struct {
+	Total     int64              `json:"total"`
+	Published int64              `json:"published"`
+	Drafts    int64              `json:"drafts"`
+	BySchema  []SchemaEntryStats `json:"by_schema"`
+}
+
+type SchemaEntryStats struct {
+	SchemaKey string `bson:"_id" json:"schema_key"`
+	Total     int64  `bson:"total" json:"total"`
+	Drafts    int64  `bson:"drafts" json:"drafts"`
+}
+
+// TermUsage term 被 entry 引用的次数
+type TermUsage struct {
+	TermID primitive.ObjectID `bson:"_id" json:"term_id"`
+	Count  int64              `bson:"count" json:"count"`
 }
 
 // --- 4. Comments (Two-Level Flat) ---
@@ -98,15 +307,49 @@ type Comment struct {
 	ParentID   primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id"`
 	ReplyToUID string             `bson:"reply_to_uid,omitempty" json:"reply_to_uid"`
 
-	Content   string    `bson:"content" json:"content"`
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	Content   string        `bson:"content" json:"content"`
+	Status    CommentStatus `bson:"status,omitempty" json:"status,omitempty"`
+	LikeCount int64         `bson:"like_count" json:"like_count"`
+	Edited    bool          `bson:"edited" json:"edited"`
+	Deleted   bool          `bson:"deleted" json:"deleted"` // 软删除，保留回复的线程结构
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
 }
 
+// DeletedCommentContent 软删除后评论内容的占位文本
+const DeletedCommentContent = "[deleted]"
+
 // CommentWithAuthor 包含作者信息的评论
 type CommentWithAuthor struct {
-	Comment `bson:",inline"`
-	Author  *UserPublic `bson:"author" json:"author"`
+	Comment     `bson:",inline"`
+	Author      *UserPublic `bson:"author" json:"author"`
+	ReplyTo     *UserPublic `bson:"reply_to,omitempty" json:"reply_to,omitempty"` // 被回复用户，用于展示“回复 @X”；用户不存在时为空
+	ReplyCount  int64       `bson:"reply_count,omitempty" json:"reply_count"`     // 仅顶层评论
+	Liked       bool        `bson:"-" json:"liked"`                               // 当前用户是否已点赞
+	ContentHTML string      `bson:"-" json:"content_html,omitempty"`              // ?format=html 时渲染的内容
+}
+
+// CommentVote 评论点赞记录，(comment_id, user_id) 唯一
+type CommentVote struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CommentID primitive.ObjectID `bson:"comment_id" json:"comment_id"`
+	UserID    string             `bson:"user_id" json:"user_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CommentReport 用户对评论的举报，(comment_id, user_id) 唯一
+type CommentReport struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CommentID primitive.ObjectID `bson:"comment_id" json:"comment_id"`
+	UserID    string             `bson:"user_id" json:"user_id"`
+	Reason    string             `bson:"reason" json:"reason"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ReportedComment 被举报的评论及举报次数
+type ReportedComment struct {
+	Comment     Comment `bson:"comment" json:"comment"`
+	ReportCount int64   `bson:"report_count" json:"report_count"`
 }
 
 // --- 5. User (OAuth2) ---
@@ -114,18 +357,42 @@ type SocialBind struct {
 	Provider       string `bson:"provider" json:"provider"`
 	ProviderUserID string `bson:"provider_user_id" json:"-"` // 隐藏敏感信息
 	Name           string `bson:"name" json:"name"`
-	Email          string `bson:"email" json:"-"` // 隐藏敏感信息
+	Email          string `bson:"email" json:"-"`          // 隐藏敏感信息
+	EmailVerified  bool   `bson:"email_verified" json:"-"` // 提供商是否验证过该邮箱，未验证时不按邮箱关联账号
 	Avatar         string `bson:"avatar" json:"avatar"`
 }
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Role      string             `bson:"role" json:"role"`
-	Nickname  string             `bson:"nickname" json:"nickname"`
-	Avatar    string             `bson:"avatar" json:"avatar"`
-	Email     string             `bson:"email" json:"email,omitempty"` // 仅管理员或本人可见
-	Socials   []SocialBind       `bson:"socials" json:"socials"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Role         string             `bson:"role" json:"role"`
+	Nickname     string             `bson:"nickname" json:"nickname"`
+	Avatar       string             `bson:"avatar" json:"avatar"`
+	Email        string             `bson:"email,omitempty" json:"email,omitempty"` // 仅管理员或本人可见；留空时不写入以配合稀疏唯一索引
+	Socials      []SocialBind       `bson:"socials" json:"socials"`
+	PasswordHash string             `bson:"password_hash,omitempty" json:"-"`
+	HasPassword  bool               `bson:"-" json:"has_password"`
+	Banned       bool               `bson:"banned,omitempty" json:"banned"`
+	BannedUntil  *time.Time         `bson:"banned_until,omitempty" json:"banned_until,omitempty"` // 为空表示永久封禁
+	BanReason    string             `bson:"ban_reason,omitempty" json:"ban_reason,omitempty"`
+	DeletedAt    *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // 已注销，文档保留以便已发布内容仍可追溯作者
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+
+	// EmailVerified 邮箱归属已确认：来自提供商验证过的邮箱，或绑定了邮箱相同且已验证的第三方账号；
+	// 注册时填写的邮箱未经验证
+	EmailVerified bool `bson:"email_verified,omitempty" json:"email_verified"`
+}
+
+// DeletedUserNickname 匿名化后用户昵称的占位文本
+const DeletedUserNickname = "[deleted]"
+
+// IsBanned 判断用户当前是否处于封禁期
+func (u *User) IsBanned() bool {
+	return u.Banned && (u.BannedUntil == nil || time.Now().Before(*u.BannedUntil))
+}
+
+// IsDeleted 判断用户是否已注销
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
 }
 
 // UserPublic 用于公开展示的用户信息
@@ -135,29 +402,84 @@ type UserPublic struct {
 	Avatar   string             `json:"avatar"`
 }
 
+// Public 转换为 UserPublic
+func (u *User) Public() *UserPublic {
+	return &UserPublic{ID: u.ID, Nickname: u.Nickname, Avatar: u.Avatar}
+}
+
 // --- 6. Session ---
 type Session struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Token     string             `bson:"token" json:"token"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Role      string             `bson:"role" json:"role"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Token      string             `bson:"token" json:"-"` // 凭证本身不对外暴露
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role       string             `bson:"role" json:"role"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	IP         string             `bson:"ip" json:"ip"`
+	UserAgent  string             `bson:"user_agent" json:"user_agent"`
+	LastSeenAt time.Time          `bson:"last_seen_at" json:"last_seen_at"`
+	Current    bool               `bson:"-" json:"current"` // 是否为发起请求的 session
+}
+
+// SessionClient 创建 session 时记录的客户端信息
+type SessionClient struct {
+	IP        string
+	UserAgent string
+}
+
+// APIKey 供脚本等非交互场景使用的凭证，仅保存哈希
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name       string             `bson:"name" json:"name"`
+	Role       string             `bson:"role" json:"role"`
+	Prefix     string             `bson:"prefix" json:"prefix"` // 便于用户辨认的前缀
+	KeyHash    string             `bson:"key_hash" json:"-"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
 }
 
 // --- 7. OAuth State (for CSRF protection) ---
 type OAuthState struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	State     string             `bson:"state" json:"state"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	State        string             `bson:"state" json:"state"`
+	CodeVerifier string             `bson:"code_verifier" json:"-"`          // PKCE verifier，回调换取 token 时使用
+	Redirect     string             `bson:"redirect,omitempty" json:"-"`     // 登录完成后返回的地址，已通过白名单校验
+	LinkUserID   string             `bson:"link_user_id,omitempty" json:"-"` // 非空表示为该用户绑定第三方账号，而不是登录
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt    time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// --- 8. Media ---
+type Media struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OwnerID     string             `bson:"owner_id" json:"owner_id"`
+	Filename    string             `bson:"filename" json:"filename"` // 上传时的原始文件名
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Size        int64              `bson:"size" json:"size"`
+	StorageKey  string             `bson:"storage_key" json:"-"` // 存储后端中的对象 key
+	URL         string             `bson:"url" json:"url"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// SearchSnippet 搜索结果的高亮摘要，匹配词以 <mark> 包裹，其余内容已做 HTML 转义
+type SearchSnippet struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
 }
 
 // --- Search Document for Meilisearch ---
 type SearchDocument struct {
-	ID        string `json:"id"`
-	Title     string `json:"title"`
-	Body      string `json:"body"`
-	SchemaKey string `json:"schema_key"`
-	AllText   string `json:"all_text"`
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Body      string   `json:"body"`
+	SchemaKey string   `json:"schema_key"`
+	AllText   string   `json:"all_text"`
+	TermIDs   []string `json:"term_ids"`   // entry 引用的所有 term，用于分面过滤
+	CreatedAt int64    `json:"created_at"` // Unix 时间戳，用于排序
+
+	// Attributes schema 中 Searchable 声明的属性文本，在 Meilisearch 中为 attributes.<key>；
+	// 这些属性同时保留在 AllText 中
+	Attributes map[string]string `json:"attributes,omitempty"`
 }
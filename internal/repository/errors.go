@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// 仓储层的错误，handler 和 service 据此判断而不依赖具体的数据库驱动
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict") // 违反唯一约束
+)
+
+// translateErr 将驱动错误转换为仓储层错误，其他错误原样返回
+func translateErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ErrNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return ErrConflict
+	}
+	return err
+}
@@ -1,48 +1,151 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"regexp"
+	"slices"
+	"strings"
+	"sync"
 
 	"matter-core/internal/model"
 
 	"github.com/meilisearch/meilisearch-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var schemaKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
-func isValidSchemaKey(key string) bool {
+// IsValidSchemaKey schema key 只能包含字母、数字、下划线和连字符，以免拼接到搜索过滤表达式中造成注入
+func IsValidSchemaKey(key string) bool {
 	return len(key) <= 50 && schemaKeyRegex.MatchString(key)
 }
 
 type MeiliRepo struct {
 	client meilisearch.ServiceManager
 	index  meilisearch.IndexManager
+
+	fieldsMu        sync.RWMutex
+	attributeFields []string // 当前单独建立索引的属性 key，见 SetAttributeFields
+}
+
+// ErrInvalidSearchField 按字段搜索时指定了未建立索引的字段
+var ErrInvalidSearchField = errors.New("invalid search field")
+
+// searchableAttributes 可搜索字段按权重从高到低排列：schema 声明的属性排在 title 之后、body 之前
+func searchableAttributes(attributeFields []string) []string {
+	searchable := []string{"title"}
+	for _, key := range attributeFields {
+		searchable = append(searchable, "attributes."+key)
+	}
+	return append(searchable, "body", "all_text", "schema_key")
 }
 
-func NewMeiliRepo(host, apiKey string) (*MeiliRepo, error) {
+// SearchSettings 可调整的相关度设置，nil 字段保持当前值不变
+type SearchSettings struct {
+	TypoTolerance  *bool    `json:"typo_tolerance,omitempty"`
+	MinWordSizeOne *int64   `json:"min_word_size_one_typo,omitempty"`
+	MinWordSizeTwo *int64   `json:"min_word_size_two_typos,omitempty"`
+	RankingRules   []string `json:"ranking_rules,omitempty"`
+	StopWords      []string `json:"stop_words,omitempty"`
+}
+
+func NewMeiliRepo(host, apiKey string, settings SearchSettings) (*MeiliRepo, error) {
 	client := meilisearch.New(host, meilisearch.WithAPIKey(apiKey))
 
 	index := client.Index("entries")
 
 	// Configure searchable and filterable attributes
-	searchable := []string{"title", "body", "all_text", "schema_key"}
+	searchable := searchableAttributes(nil)
 	_, err := index.UpdateSearchableAttributes(&searchable)
 	if err != nil {
 		return nil, err
 	}
 
-	filterable := []interface{}{"schema_key"}
+	filterable := []interface{}{"schema_key", "term_ids"}
 	_, err = index.UpdateFilterableAttributes(&filterable)
 	if err != nil {
 		return nil, err
 	}
 
-	return &MeiliRepo{
+	sortable := []string{"created_at"}
+	_, err = index.UpdateSortableAttributes(&sortable)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &MeiliRepo{
 		client: client,
 		index:  index,
-	}, nil
+	}
+	if err := repo.ApplySettings(settings); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// ApplySettings 更新拼写容错、排序规则和停用词；设置在 Meilisearch 中异步生效
+func (r *MeiliRepo) ApplySettings(settings SearchSettings) error {
+	if settings.TypoTolerance != nil || settings.MinWordSizeOne != nil || settings.MinWordSizeTwo != nil {
+		typo, err := r.index.GetTypoTolerance()
+		if err != nil {
+			return err
+		}
+		if settings.TypoTolerance != nil {
+			typo.Enabled = *settings.TypoTolerance
+		}
+		if settings.MinWordSizeOne != nil {
+			typo.MinWordSizeForTypos.OneTypo = *settings.MinWordSizeOne
+		}
+		if settings.MinWordSizeTwo != nil {
+			typo.MinWordSizeForTypos.TwoTypos = *settings.MinWordSizeTwo
+		}
+		if _, err := r.index.UpdateTypoTolerance(typo); err != nil {
+			return err
+		}
+	}
+	if settings.RankingRules != nil {
+		if _, err := r.index.UpdateRankingRules(&settings.RankingRules); err != nil {
+			return err
+		}
+	}
+	if settings.StopWords != nil {
+		if _, err := r.index.UpdateStopWords(&settings.StopWords); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetAttributeFields 更新单独建立索引的属性，未变化时不提交设置（修改可搜索字段会触发 Meilisearch 重建索引）
+func (r *MeiliRepo) SetAttributeFields(keys []string) error {
+	r.fieldsMu.Lock()
+	defer r.fieldsMu.Unlock()
+	if slices.Equal(keys, r.attributeFields) {
+		return nil
+	}
+	searchable := searchableAttributes(keys)
+	if _, err := r.index.UpdateSearchableAttributes(&searchable); err != nil {
+		return err
+	}
+	r.attributeFields = slices.Clone(keys)
+	return nil
+}
+
+// searchField 将对外的字段名映射为索引中的字段，title/body 之外只接受已建立索引的属性
+func (r *MeiliRepo) searchField(field string) (string, bool) {
+	if field == "title" || field == "body" {
+		return field, true
+	}
+	r.fieldsMu.RLock()
+	defer r.fieldsMu.RUnlock()
+	if slices.Contains(r.attributeFields, field) {
+		return "attributes." + field, true
+	}
+	return "", false
 }
 
 func (r *MeiliRepo) IndexDocument(doc model.SearchDocument) error {
@@ -53,39 +156,165 @@ func (r *MeiliRepo) IndexDocument(doc model.SearchDocument) error {
 	return err
 }
 
+// Health 检查 Meilisearch 服务是否可用
+func (r *MeiliRepo) Health(ctx context.Context) error {
+	health, err := r.client.HealthWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	if health.Status != "available" {
+		return fmt.Errorf("meilisearch status: %s", health.Status)
+	}
+	return nil
+}
+
+// IndexDocuments 批量写入文档，按主键覆盖已有文档
+func (r *MeiliRepo) IndexDocuments(docs []model.SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	pk := "id"
+	_, err := r.index.AddDocuments(docs, &meilisearch.DocumentOptions{
+		PrimaryKey: &pk,
+	})
+	return err
+}
+
 func (r *MeiliRepo) DeleteDocument(id string) error {
 	_, err := r.index.DeleteDocument(id, nil)
 	return err
 }
 
-func (r *MeiliRepo) Search(query string, schemaKey string, limit, offset int64) ([]string, int64, error) {
+func (r *MeiliRepo) DeleteDocuments(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.index.DeleteDocuments(ids, nil)
+	return err
+}
+
+// SearchOptions 搜索参数，TermIDs 之间为 AND 关系（结果须同时包含所有 term）
+type SearchOptions struct {
+	Query      string
+	SchemaKeys []string // 多个 schema 之间为 OR 关系
+	TermIDs    []string
+	Limit      int64
+	Offset     int64
+	Highlight  bool     // 返回带 <mark> 高亮的标题和正文摘要
+	Sort       string   // SearchSorts 中的取值，留空按相关度排序
+	Fields     []string // 只在这些字段中搜索（title、body 或 schema 声明的属性），留空搜索全部
+}
+
+// SearchSorts 对外开放的排序方式及对应的 Meilisearch sort 表达式
+var SearchSorts = map[string]string{
+	"newest": "created_at:desc",
+	"oldest": "created_at:asc",
+}
+
+// SearchResult 按相关度排序的 entry ID；Snippets 仅在 Highlight 时填充
+type SearchResult struct {
+	IDs      []string
+	Total    int64
+	Snippets map[string]model.SearchSnippet
+}
+
+// 高亮标记先用私有区字符占位，HTML 转义后再替换为 <mark>，避免文档内容中的 HTML 被原样输出
+const (
+	highlightPre  = "\uE000"
+	highlightPost = "\uE001"
+)
+
+var highlightReplacer = strings.NewReplacer(highlightPre, "<mark>", highlightPost, "</mark>")
+
+func (r *MeiliRepo) Search(opts SearchOptions) (*SearchResult, error) {
 	searchReq := &meilisearch.SearchRequest{
-		Limit:  limit,
-		Offset: offset,
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
 	}
 
-	if schemaKey != "" {
-		// Sanitize schemaKey to prevent filter injection
-		// Only allow alphanumeric, underscore, and hyphen
-		if !isValidSchemaKey(schemaKey) {
-			return nil, 0, fmt.Errorf("invalid schema_key format")
+	var filters []string
+	if len(opts.SchemaKeys) > 0 {
+		quoted := make([]string, 0, len(opts.SchemaKeys))
+		for _, key := range opts.SchemaKeys {
+			// Sanitize schemaKey to prevent filter injection
+			// Only allow alphanumeric, underscore, and hyphen
+			if !IsValidSchemaKey(key) {
+				return nil, fmt.Errorf("invalid schema_key format")
+			}
+			quoted = append(quoted, fmt.Sprintf("\"%s\"", key))
+		}
+		filters = append(filters, fmt.Sprintf("schema_key IN [%s]", strings.Join(quoted, ", ")))
+	}
+	for _, termID := range opts.TermIDs {
+		// Term IDs are ObjectID hex strings; anything else could inject filter syntax
+		if !primitive.IsValidObjectID(termID) {
+			return nil, fmt.Errorf("invalid term_id format")
+		}
+		filters = append(filters, fmt.Sprintf("term_ids = \"%s\"", termID))
+	}
+	if len(filters) > 0 {
+		searchReq.Filter = strings.Join(filters, " AND ")
+	}
+
+	if opts.Sort != "" {
+		sort, ok := SearchSorts[opts.Sort]
+		if !ok {
+			return nil, fmt.Errorf("invalid sort")
+		}
+		searchReq.Sort = []string{sort}
+	}
+
+	for _, field := range opts.Fields {
+		attr, ok := r.searchField(field)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSearchField, field)
 		}
-		searchReq.Filter = fmt.Sprintf("schema_key = \"%s\"", schemaKey)
+		searchReq.AttributesToSearchOn = append(searchReq.AttributesToSearchOn, attr)
 	}
 
-	result, err := r.index.Search(query, searchReq)
+	if opts.Highlight {
+		searchReq.AttributesToHighlight = []string{"title", "body"}
+		searchReq.AttributesToCrop = []string{"body"}
+		searchReq.CropLength = 30
+		searchReq.HighlightPreTag = highlightPre
+		searchReq.HighlightPostTag = highlightPost
+	}
+
+	result, err := r.index.Search(opts.Query, searchReq)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	ids := make([]string, 0, len(result.Hits))
+	res := &SearchResult{
+		IDs:   make([]string, 0, len(result.Hits)),
+		Total: result.EstimatedTotalHits,
+	}
+	if opts.Highlight {
+		res.Snippets = make(map[string]model.SearchSnippet, len(result.Hits))
+	}
 	for _, hit := range result.Hits {
-		if idRaw, ok := hit["id"]; ok {
-			var id string
-			if err := json.Unmarshal(idRaw, &id); err == nil {
-				ids = append(ids, id)
+		idRaw, ok := hit["id"]
+		if !ok {
+			continue
+		}
+		var id string
+		if err := json.Unmarshal(idRaw, &id); err != nil {
+			continue
+		}
+		res.IDs = append(res.IDs, id)
+
+		if formattedRaw, ok := hit["_formatted"]; ok && opts.Highlight {
+			var formatted struct {
+				Title string `json:"title"`
+				Body  string `json:"body"`
+			}
+			if err := json.Unmarshal(formattedRaw, &formatted); err == nil {
+				res.Snippets[id] = model.SearchSnippet{
+					Title: highlightReplacer.Replace(html.EscapeString(formatted.Title)),
+					Body:  highlightReplacer.Replace(html.EscapeString(formatted.Body)),
+				}
 			}
 		}
 	}
-	return ids, result.EstimatedTotalHits, nil
+	return res, nil
 }
@@ -0,0 +1,1854 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"matter-core/internal/model"
+	"matter-core/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryRepo 基于内存的 Repository 实现，用于测试 handler 和 service 而无需 MongoDB。
+// 文档以 BSON 编码保存，读写都会经过一次编解码，因此返回值与调用方互不共享，
+// 字段类型（如数组解码为 primitive.A）也与 MongoDB 返回的一致。
+// 查询语义尽量贴近 MongoRepo，但全文搜索只做不区分大小写的子串匹配
+type MemoryRepo struct {
+	mu sync.RWMutex
+
+	schemas     collection
+	entries     collection
+	users       collection
+	taxonomies  collection
+	terms       collection
+	comments    collection
+	votes       collection
+	reports     collection
+	sessions    collection
+	oauthStates collection
+	apiKeys     collection
+	media       collection
+
+	// uniqueFields 模拟 SyncUniqueFieldIndexes 建立的部分唯一索引：schema key -> 字段 key -> BSON 类型
+	uniqueFields map[string]map[string]string
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{
+		schemas:      collection{},
+		entries:      collection{},
+		users:        collection{},
+		taxonomies:   collection{},
+		terms:        collection{},
+		comments:     collection{},
+		votes:        collection{},
+		reports:      collection{},
+		sessions:     collection{},
+		oauthStates:  collection{},
+		apiKeys:      collection{},
+		media:        collection{},
+		uniqueFields: map[string]map[string]string{},
+	}
+}
+
+// collection 以 _id 为键保存 BSON 编码后的文档
+type collection map[primitive.ObjectID][]byte
+
+// putDoc 写入（或整体替换）文档
+func putDoc[T any](c collection, id primitive.ObjectID, doc *T) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	c[id] = data
+	return nil
+}
+
+func getDoc[T any](c collection, id primitive.ObjectID) (*T, error) {
+	data, ok := c[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var doc T
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// findDocs 按 _id 顺序返回满足 match 的文档，match 为 nil 时返回全部
+func findDocs[T any](c collection, match func(*T) bool) ([]T, error) {
+	ids := slices.SortedFunc(maps.Keys(c), func(a, b primitive.ObjectID) int {
+		return strings.Compare(a.Hex(), b.Hex())
+	})
+	var docs []T
+	for _, id := range ids {
+		var doc T
+		if err := bson.Unmarshal(c[id], &doc); err != nil {
+			return nil, err
+		}
+		if match == nil || match(&doc) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// findOne 返回第一个满足 match 的文档
+func findOne[T any](c collection, match func(*T) bool) (*T, error) {
+	docs, err := findDocs(c, match)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &docs[0], nil
+}
+
+// updateDocs 对满足 match 的文档调用 fn 并写回，fn 返回 false 表示未修改；返回修改的文档数
+func updateDocs[T any](c collection, match func(*T) bool, id func(*T) primitive.ObjectID, fn func(*T) bool) (int64, error) {
+	docs, err := findDocs(c, match)
+	if err != nil {
+		return 0, err
+	}
+	var modified int64
+	for i := range docs {
+		if !fn(&docs[i]) {
+			continue
+		}
+		if err := putDoc(c, id(&docs[i]), &docs[i]); err != nil {
+			return modified, err
+		}
+		modified++
+	}
+	return modified, nil
+}
+
+// deleteDocs 删除满足 match 的文档，返回删除数量
+func deleteDocs[T any](c collection, match func(*T) bool, id func(*T) primitive.ObjectID) (int64, error) {
+	docs, err := findDocs(c, match)
+	if err != nil {
+		return 0, err
+	}
+	for i := range docs {
+		delete(c, id(&docs[i]))
+	}
+	return int64(len(docs)), nil
+}
+
+// paginate 对应 Mongo 的 skip/limit，limit <= 0 表示不限
+func paginate[T any](docs []T, limit, offset int64) []T {
+	if offset > 0 {
+		if offset >= int64(len(docs)) {
+			return nil
+		}
+		docs = docs[offset:]
+	}
+	if limit > 0 && limit < int64(len(docs)) {
+		docs = docs[:limit]
+	}
+	return docs
+}
+
+func newIDIfZero(id primitive.ObjectID) primitive.ObjectID {
+	if id.IsZero() {
+		return primitive.NewObjectID()
+	}
+	return id
+}
+
+func entryDocID(e *model.Entry) primitive.ObjectID           { return e.ID }
+func termDocID(t *model.Term) primitive.ObjectID             { return t.ID }
+func commentDocID(c *model.Comment) primitive.ObjectID       { return c.ID }
+func voteDocID(v *model.CommentVote) primitive.ObjectID      { return v.ID }
+func sessionDocID(s *model.Session) primitive.ObjectID       { return s.ID }
+func oauthStateDocID(s *model.OAuthState) primitive.ObjectID { return s.ID }
+func apiKeyDocID(k *model.APIKey) primitive.ObjectID         { return k.ID }
+func schemaDocID(s *model.Schema) primitive.ObjectID         { return s.ID }
+
+// --- 值比较，模拟 Mongo 的相等匹配 ---
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func asArray(v any) ([]any, bool) {
+	switch arr := v.(type) {
+	case primitive.A:
+		return arr, true
+	case []any:
+		return arr, true
+	}
+	return nil, false
+}
+
+// equalValues 数字跨类型按数值比较，其他按深度相等比较
+func equalValues(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// matchValue 与 Mongo 一致：对数组字段的相等匹配也会命中包含该值的数组
+func matchValue(stored, v any) bool {
+	if equalValues(stored, v) {
+		return true
+	}
+	if arr, ok := asArray(stored); ok {
+		return slices.ContainsFunc(arr, func(item any) bool { return equalValues(item, v) })
+	}
+	return false
+}
+
+// matchPath 沿点号路径取值后用 matchValue 比较；与 Mongo 一致，途经数组时逐个元素继续匹配
+func matchPath(v any, parts []string, want any) bool {
+	if len(parts) == 0 {
+		return matchValue(v, want)
+	}
+	if arr, ok := asArray(v); ok {
+		return slices.ContainsFunc(arr, func(item any) bool { return matchPath(item, parts, want) })
+	}
+	child, ok := asObject(v)[parts[0]]
+	return ok && matchPath(child, parts[1:], want)
+}
+
+// pathValues 沿点号路径取出所有叶子值，途经的数组和数组形式的叶子都逐个元素展开，对应 $unwind
+func pathValues(v any, parts []string, fn func(any)) {
+	if arr, ok := asArray(v); ok {
+		for _, item := range arr {
+			pathValues(item, parts, fn)
+		}
+		return
+	}
+	if len(parts) == 0 {
+		fn(v)
+		return
+	}
+	if child, ok := asObject(v)[parts[0]]; ok {
+		pathValues(child, parts[1:], fn)
+	}
+}
+
+// rewritePath 沿点号路径找到每个叶子值交给 fn 改写，途经数组时逐个元素处理；fn 返回 false 时删除该键
+func rewritePath(v any, parts []string, fn func(leaf any) (any, bool)) {
+	if arr, ok := asArray(v); ok {
+		for _, item := range arr {
+			rewritePath(item, parts, fn)
+		}
+		return
+	}
+	obj := asObject(v)
+	child, ok := obj[parts[0]]
+	if !ok {
+		return
+	}
+	if len(parts) > 1 {
+		rewritePath(child, parts[1:], fn)
+		return
+	}
+	if value, keep := fn(child); keep {
+		obj[parts[0]] = value
+	} else {
+		delete(obj, parts[0])
+	}
+}
+
+func asObject(v any) map[string]any {
+	switch m := v.(type) {
+	case map[string]any:
+		return m
+	case primitive.M:
+		return m
+	}
+	return nil
+}
+
+// matchBSONType 对应部分唯一索引中的 $type 过滤
+func matchBSONType(v any, bsonType string) bool {
+	if bsonType == "number" {
+		_, ok := toFloat(v)
+		return ok
+	}
+	_, ok := v.(string)
+	return ok
+}
+
+func (r *MemoryRepo) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *MemoryRepo) Close(ctx context.Context) error {
+	return nil
+}
+
+// --- Schema Operations ---
+func (r *MemoryRepo) CreateSchema(ctx context.Context, schema *model.Schema) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	schema.CreatedAt = time.Now()
+	schema.ID = newIDIfZero(schema.ID)
+	return putDoc(r.schemas, schema.ID, schema)
+}
+
+func (r *MemoryRepo) GetLatestSchema(ctx context.Context, key string) (*model.Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	schemas, err := findDocs(r.schemas, func(s *model.Schema) bool { return s.Key == key })
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 {
+		return nil, ErrNotFound
+	}
+	latest := slices.MaxFunc(schemas, func(a, b model.Schema) int { return cmp.Compare(a.Version, b.Version) })
+	return &latest, nil
+}
+
+func (r *MemoryRepo) GetSchemaByID(ctx context.Context, id primitive.ObjectID) (*model.Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return getDoc[model.Schema](r.schemas, id)
+}
+
+func (r *MemoryRepo) DeleteSchemasByKey(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := deleteDocs(r.schemas, func(s *model.Schema) bool { return s.Key == key }, schemaDocID)
+	return err
+}
+
+// ListSchemas 返回每个 key 的最新版本，按 key 排序
+func (r *MemoryRepo) ListSchemas(ctx context.Context) ([]model.Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	schemas, err := findDocs[model.Schema](r.schemas, nil)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]model.Schema)
+	for _, s := range schemas {
+		if cur, ok := latest[s.Key]; !ok || s.Version > cur.Version {
+			latest[s.Key] = s
+		}
+	}
+	result := make([]model.Schema, 0, len(latest))
+	for _, key := range slices.Sorted(maps.Keys(latest)) {
+		result = append(result, latest[key])
+	}
+	return result, nil
+}
+
+// --- Entry Operations ---
+
+// checkUniqueLocked 检查 entry 是否与已存储的或同批次中更早的 entry 违反唯一字段约束
+func (r *MemoryRepo) checkUniqueLocked(entry *model.Entry, batch []*model.Entry) error {
+	fields := r.uniqueFields[entry.SchemaKey]
+	if len(fields) == 0 {
+		return nil
+	}
+	stored, err := findDocs(r.entries, func(e *model.Entry) bool {
+		return e.SchemaKey == entry.SchemaKey && e.ID != entry.ID
+	})
+	if err != nil {
+		return err
+	}
+	for key, bsonType := range fields {
+		v, ok := entry.Attributes[key]
+		if !ok || !matchBSONType(v, bsonType) {
+			continue
+		}
+		for _, other := range stored {
+			if ov, ok := other.Attributes[key]; ok && matchBSONType(ov, bsonType) && equalValues(ov, v) {
+				return ErrConflict
+			}
+		}
+		for _, other := range batch {
+			if other.SchemaKey != entry.SchemaKey {
+				continue
+			}
+			if ov, ok := other.Attributes[key]; ok && matchBSONType(ov, bsonType) && equalValues(ov, v) {
+				return ErrConflict
+			}
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepo) CreateEntry(ctx context.Context, entry *model.Entry) error {
+	return r.CreateEntries(ctx, []*model.Entry{entry})
+}
+
+// CreateEntries 违反唯一约束时整批都不写入
+func (r *MemoryRepo) CreateEntries(ctx context.Context, entries []*model.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for i, entry := range entries {
+		entry.Base.SyncStatus()
+		entry.Base.SlugNormalized = utils.NormalizeSlug(entry.Base.Slug)
+		entry.Base.CreatedAt = now
+		entry.Base.UpdatedAt = now
+		if _, exists := r.entries[entry.ID]; exists {
+			return ErrConflict
+		}
+		if err := r.checkUniqueLocked(entry, entries[:i]); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		entry.ID = newIDIfZero(entry.ID)
+		if err := putDoc(r.entries, entry.ID, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepo) UpdateEntry(ctx context.Context, entry *model.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.Base.SyncStatus()
+	entry.Base.SlugNormalized = utils.NormalizeSlug(entry.Base.Slug)
+	entry.Base.UpdatedAt = time.Now()
+	if _, exists := r.entries[entry.ID]; !exists {
+		return nil
+	}
+	if err := r.checkUniqueLocked(entry, nil); err != nil {
+		return err
+	}
+	return putDoc(r.entries, entry.ID, entry)
+}
+
+func (r *MemoryRepo) DeleteEntry(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := deleteDocs(r.comments, func(c *model.Comment) bool { return c.EntryID == id }, commentDocID); err != nil {
+		return err
+	}
+	delete(r.entries, id)
+	return nil
+}
+
+func (r *MemoryRepo) GetEntryByID(ctx context.Context, id primitive.ObjectID) (*model.Entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return getDoc[model.Entry](r.entries, id)
+}
+
+func (r *MemoryRepo) GetEntryBySlug(ctx context.Context, schemaKey, slug string) (*model.Entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	normalized := utils.NormalizeSlug(slug)
+	entries, err := findDocs(r.entries, func(e *model.Entry) bool {
+		return e.SchemaKey == schemaKey && e.Base.SlugNormalized == normalized
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrNotFound
+	}
+	sortEntriesByCreated(entries, false)
+	return &entries[0], nil
+}
+
+// entryListMatch 与 entryListFilter 对应
+func entryListMatch(schemaKeys []string, draft *bool, status model.EntryStatus) func(*model.Entry) bool {
+	return func(e *model.Entry) bool {
+		if len(schemaKeys) > 0 && !slices.Contains(schemaKeys, e.SchemaKey) {
+			return false
+		}
+		if draft != nil && e.Base.Draft != *draft {
+			return false
+		}
+		return status == "" || e.Base.Status == status
+	}
+}
+
+func sortEntriesByCreated(entries []model.Entry, oldestFirst bool) {
+	slices.SortStableFunc(entries, func(a, b model.Entry) int {
+		if oldestFirst {
+			return a.Base.CreatedAt.Compare(b.Base.CreatedAt)
+		}
+		return b.Base.CreatedAt.Compare(a.Base.CreatedAt)
+	})
+}
+
+// entryMatchLocked 对应 entryFilter：在 entryListMatch 的基础上要求 entry 引用了所有 termIDs，
+// term 不存在时不匹配任何 entry；调用方需持有锁
+func (r *MemoryRepo) entryMatchLocked(schemaKeys, termIDs []string, draft *bool, status model.EntryStatus) (func(*model.Entry) bool, error) {
+	match := entryListMatch(schemaKeys, draft, status)
+	if len(termIDs) == 0 {
+		return match, nil
+	}
+	none := func(*model.Entry) bool { return false }
+	refs := make([]func(*model.Entry) bool, 0, len(termIDs))
+	for _, id := range termIDs {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return none, nil
+		}
+		terms, err := findDocs(r.terms, func(t *model.Term) bool { return t.ID == oid })
+		if err != nil {
+			return nil, err
+		}
+		if len(terms) == 0 {
+			return none, nil
+		}
+		paths, err := r.taxonomyFieldPathsLocked(terms[0].TaxonomyKey)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, termRefMatch(paths, id))
+	}
+	return func(e *model.Entry) bool {
+		if !match(e) {
+			return false
+		}
+		for _, ref := range refs {
+			if !ref(e) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (r *MemoryRepo) ListEntries(ctx context.Context, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus, limit, offset int64) ([]model.Entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	match, err := r.entryMatchLocked(schemaKeys, termIDs, draft, status)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := findDocs(r.entries, match)
+	if err != nil {
+		return nil, err
+	}
+	sortEntriesByCreated(entries, false)
+	return paginate(entries, limit, offset), nil
+}
+
+func (r *MemoryRepo) ListEntriesByAuthor(ctx context.Context, authorID string, schemaKeys []string, draft *bool, status model.EntryStatus, oldestFirst bool, limit, offset int64) ([]model.Entry, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	match := entryListMatch(schemaKeys, draft, status)
+	entries, err := findDocs(r.entries, func(e *model.Entry) bool { return e.AuthorID == authorID && match(e) })
+	if err != nil {
+		return nil, 0, err
+	}
+	sortEntriesByCreated(entries, oldestFirst)
+	return paginate(entries, limit, offset), int64(len(entries)), nil
+}
+
+func (r *MemoryRepo) TransferEntries(ctx context.Context, fromAuthorID, toAuthorID, operatorID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	transfer := model.OwnershipTransfer{FromAuthorID: fromAuthorID, ToAuthorID: toAuthorID, OperatorID: operatorID, At: now}
+	return updateDocs(r.entries, func(e *model.Entry) bool { return e.AuthorID == fromAuthorID }, entryDocID, func(e *model.Entry) bool {
+		e.AuthorID = toAuthorID
+		e.Transfers = append(e.Transfers, transfer)
+		e.Base.UpdatedAt = now
+		return true
+	})
+}
+
+// SyncUniqueFieldIndexes 以该版本的 Unique 字段替换登记的唯一字段，已有重复数据时返回 ErrConflict 且保持原样
+func (r *MemoryRepo) SyncUniqueFieldIndexes(ctx context.Context, schema *model.Schema) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added := make(map[string]string)
+	for _, field := range schema.Fields {
+		if field.Unique {
+			added[field.Key] = uniqueFieldBSONType(field.Type)
+		}
+	}
+	entries, err := findDocs(r.entries, func(e *model.Entry) bool { return e.SchemaKey == schema.Key })
+	if err != nil {
+		return err
+	}
+	for key, bsonType := range added {
+		var seen []any
+		for _, e := range entries {
+			v, ok := e.Attributes[key]
+			if !ok || !matchBSONType(v, bsonType) {
+				continue
+			}
+			if slices.ContainsFunc(seen, func(s any) bool { return equalValues(s, v) }) {
+				return ErrConflict
+			}
+			seen = append(seen, v)
+		}
+	}
+	if len(added) == 0 {
+		delete(r.uniqueFields, schema.Key)
+		return nil
+	}
+	r.uniqueFields[schema.Key] = added
+	return nil
+}
+
+func (r *MemoryRepo) FindUniqueConflicts(ctx context.Context, schemaKey string, values map[string]any, excludeID primitive.ObjectID) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entries, err := findDocs(r.entries, func(e *model.Entry) bool {
+		return e.SchemaKey == schemaKey && (excludeID.IsZero() || e.ID != excludeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []string
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if slices.ContainsFunc(entries, func(e model.Entry) bool {
+			stored, ok := e.Attributes[key]
+			return ok && matchValue(stored, values[key])
+		}) {
+			conflicts = append(conflicts, key)
+		}
+	}
+	return conflicts, nil
+}
+
+func (r *MemoryRepo) ForEachEntryBatch(ctx context.Context, draft *bool, batchSize int, fn func([]model.Entry) error) error {
+	return r.forEachEntryBatch(entryListMatch(nil, draft, ""), batchSize, fn)
+}
+
+func (r *MemoryRepo) ForEachTermEntryBatch(ctx context.Context, taxonomyKey string, termID primitive.ObjectID, batchSize int, fn func([]model.Entry) error) error {
+	r.mu.RLock()
+	paths, err := r.taxonomyFieldPathsLocked(taxonomyKey)
+	r.mu.RUnlock()
+	if err != nil || len(paths) == 0 {
+		return err
+	}
+	return r.forEachEntryBatch(termRefMatch(paths, termID.Hex()), batchSize, fn)
+}
+
+// forEachEntryBatch 先在锁内取快照再逐批回调，fn 可以再调用仓储方法
+func (r *MemoryRepo) forEachEntryBatch(match func(*model.Entry) bool, batchSize int, fn func([]model.Entry) error) error {
+	r.mu.RLock()
+	entries, err := findDocs(r.entries, match)
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	for batch := range slices.Chunk(entries, max(batchSize, 1)) {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchEntries 不区分大小写地匹配 base.title 和 body 中的查询词（任一命中即可），
+// 标题命中的权重高于正文，与文本索引的权重设置一致
+func (r *MemoryRepo) SearchEntries(ctx context.Context, query string, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus, limit, offset int64) ([]model.Entry, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	terms := strings.Fields(strings.ToLower(query))
+	score := func(e *model.Entry) int {
+		title, body := strings.ToLower(e.Base.Title), strings.ToLower(e.Body)
+		s := 0
+		for _, t := range terms {
+			if strings.Contains(title, t) {
+				s += 10
+			}
+			if strings.Contains(body, t) {
+				s++
+			}
+		}
+		return s
+	}
+	match, err := r.entryMatchLocked(schemaKeys, termIDs, draft, status)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries, err := findDocs(r.entries, func(e *model.Entry) bool { return match(e) && score(e) > 0 })
+	if err != nil {
+		return nil, 0, err
+	}
+	sortEntriesByCreated(entries, false)
+	slices.SortStableFunc(entries, func(a, b model.Entry) int { return cmp.Compare(score(&b), score(&a)) })
+	return paginate(entries, limit, offset), int64(len(entries)), nil
+}
+
+func (r *MemoryRepo) CountEntries(ctx context.Context, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	match, err := r.entryMatchLocked(schemaKeys, termIDs, draft, status)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := findDocs(r.entries, match)
+	return int64(len(entries)), err
+}
+
+func (r *MemoryRepo) GetEntriesByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Entry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entries := make([]model.Entry, 0, len(ids))
+	for _, id := range ids {
+		entry, err := getDoc[model.Entry](r.entries, id)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+// --- User Operations ---
+
+// checkEmailLocked 模拟 email 上的稀疏唯一索引
+func (r *MemoryRepo) checkEmailLocked(user *model.User) error {
+	if user.Email == "" {
+		return nil
+	}
+	_, err := findOne(r.users, func(u *model.User) bool { return u.Email == user.Email && u.ID != user.ID })
+	if err == nil {
+		return ErrConflict
+	}
+	if err != ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func (r *MemoryRepo) CreateUser(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.CreatedAt = time.Now()
+	if err := r.checkEmailLocked(user); err != nil {
+		return err
+	}
+	user.ID = newIDIfZero(user.ID)
+	return putDoc(r.users, user.ID, user)
+}
+
+func (r *MemoryRepo) GetUserByID(ctx context.Context, id primitive.ObjectID) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return getDoc[model.User](r.users, id)
+}
+
+func (r *MemoryRepo) GetUsersByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return findDocs(r.users, func(u *model.User) bool { return slices.Contains(ids, u.ID) })
+}
+
+func (r *MemoryRepo) GetUserBySocial(ctx context.Context, provider, providerUserID string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return findOne(r.users, func(u *model.User) bool {
+		return slices.ContainsFunc(u.Socials, func(s model.SocialBind) bool {
+			return s.Provider == provider && s.ProviderUserID == providerUserID
+		})
+	})
+}
+
+func (r *MemoryRepo) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	email = utils.NormalizeEmail(email)
+	return findOne(r.users, func(u *model.User) bool { return email != "" && u.Email == email })
+}
+
+// updateUserLocked 修改单个用户，用户不存在时不做任何事
+func (r *MemoryRepo) updateUserLocked(id primitive.ObjectID, fn func(*model.User)) (bool, error) {
+	user, err := getDoc[model.User](r.users, id)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	fn(user)
+	return true, putDoc(r.users, id, user)
+}
+
+func (r *MemoryRepo) AddUserSocial(ctx context.Context, userID primitive.ObjectID, social model.SocialBind) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.updateUserLocked(userID, func(u *model.User) { u.Socials = append(u.Socials, social) })
+	return err
+}
+
+func (r *MemoryRepo) RemoveUserSocial(ctx context.Context, userID primitive.ObjectID, provider string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, err := getDoc[model.User](r.users, userID)
+	if err != nil {
+		return err
+	}
+	bound := slices.ContainsFunc(user.Socials, func(s model.SocialBind) bool { return s.Provider == provider })
+	if !bound || (len(user.Socials) < 2 && user.PasswordHash == "") {
+		return ErrNotFound
+	}
+	user.Socials = slices.DeleteFunc(user.Socials, func(s model.SocialBind) bool { return s.Provider == provider })
+	return putDoc(r.users, userID, user)
+}
+
+func (r *MemoryRepo) SetUserBan(ctx context.Context, userID primitive.ObjectID, banned bool, until *time.Time, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found, err := r.updateUserLocked(userID, func(u *model.User) {
+		u.Banned, u.BannedUntil, u.BanReason = false, nil, ""
+		if banned {
+			u.Banned, u.BannedUntil, u.BanReason = true, until, reason
+		}
+	})
+	if err == nil && !found {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *MemoryRepo) DeleteUser(ctx context.Context, userID primitive.ObjectID, anonymize bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, err := getDoc[model.User](r.users, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsDeleted() {
+		return ErrNotFound
+	}
+	now := time.Now()
+	user.DeletedAt = &now
+	if anonymize {
+		user.Nickname = model.DeletedUserNickname
+		user.Avatar = ""
+		user.Socials = []model.SocialBind{}
+		user.Email = ""
+		user.EmailVerified = false
+		user.PasswordHash = ""
+	}
+	return putDoc(r.users, userID, user)
+}
+
+func (r *MemoryRepo) MarkUserEmailVerified(ctx context.Context, userID primitive.ObjectID, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, err := getDoc[model.User](r.users, userID)
+	if err != nil {
+		return err
+	}
+	if user.Email != email {
+		return ErrNotFound
+	}
+	user.EmailVerified = true
+	return putDoc(r.users, userID, user)
+}
+
+func (r *MemoryRepo) SetUserPassword(ctx context.Context, userID primitive.ObjectID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.updateUserLocked(userID, func(u *model.User) { u.PasswordHash = passwordHash })
+	return err
+}
+
+func (r *MemoryRepo) UpdateUser(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.users[user.ID]; !exists {
+		return nil
+	}
+	if err := r.checkEmailLocked(user); err != nil {
+		return err
+	}
+	return putDoc(r.users, user.ID, user)
+}
+
+func (r *MemoryRepo) UpdateUserProfile(ctx context.Context, userID primitive.ObjectID, nickname, avatar string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.updateUserLocked(userID, func(u *model.User) {
+		if nickname != "" {
+			u.Nickname = nickname
+		}
+		if avatar != "" {
+			u.Avatar = avatar
+		}
+	})
+	return err
+}
+
+// --- Taxonomy Operations ---
+func (r *MemoryRepo) checkTaxonomyKeyLocked(tax *model.Taxonomy) error {
+	_, err := findOne(r.taxonomies, func(t *model.Taxonomy) bool { return t.Key == tax.Key && t.ID != tax.ID })
+	if err == nil {
+		return ErrConflict
+	}
+	if err != ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func (r *MemoryRepo) CreateTaxonomy(ctx context.Context, tax *model.Taxonomy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tax.CreatedAt = time.Now()
+	tax.UpdatedAt = tax.CreatedAt
+	if err := r.checkTaxonomyKeyLocked(tax); err != nil {
+		return err
+	}
+	tax.ID = newIDIfZero(tax.ID)
+	return putDoc(r.taxonomies, tax.ID, tax)
+}
+
+func (r *MemoryRepo) GetTaxonomyByKey(ctx context.Context, key string) (*model.Taxonomy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return findOne(r.taxonomies, func(t *model.Taxonomy) bool { return t.Key == key })
+}
+
+func (r *MemoryRepo) ListTaxonomies(ctx context.Context) ([]model.Taxonomy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return findDocs[model.Taxonomy](r.taxonomies, nil)
+}
+
+func (r *MemoryRepo) UpdateTaxonomy(ctx context.Context, tax *model.Taxonomy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tax.UpdatedAt = time.Now()
+	if _, exists := r.taxonomies[tax.ID]; !exists {
+		return nil
+	}
+	if err := r.checkTaxonomyKeyLocked(tax); err != nil {
+		return err
+	}
+	return putDoc(r.taxonomies, tax.ID, tax)
+}
+
+func (r *MemoryRepo) DeleteTaxonomy(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tax, err := findOne(r.taxonomies, func(t *model.Taxonomy) bool { return t.Key == key })
+	if err == nil {
+		delete(r.taxonomies, tax.ID)
+	}
+	if err == ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// --- Term Operations ---
+// checkTermSlugLocked 模拟 (taxonomy_key, slug_normalized) 上的唯一索引
+func (r *MemoryRepo) checkTermSlugLocked(term *model.Term) error {
+	_, err := findOne(r.terms, func(t *model.Term) bool {
+		return t.TaxonomyKey == term.TaxonomyKey && t.SlugNormalized == term.SlugNormalized && t.ID != term.ID
+	})
+	if err == nil {
+		return ErrConflict
+	}
+	if err != ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func (r *MemoryRepo) CreateTerm(ctx context.Context, term *model.Term) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	term.SlugNormalized = utils.NormalizeSlug(term.Slug)
+	if err := r.checkTermSlugLocked(term); err != nil {
+		return err
+	}
+	term.CreatedAt = time.Now()
+	term.UpdatedAt = term.CreatedAt
+	term.ID = newIDIfZero(term.ID)
+	return putDoc(r.terms, term.ID, term)
+}
+
+func (r *MemoryRepo) GetTermByID(ctx context.Context, id primitive.ObjectID) (*model.Term, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return getDoc[model.Term](r.terms, id)
+}
+
+func (r *MemoryRepo) GetTermsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Term, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return findDocs(r.terms, func(t *model.Term) bool { return slices.Contains(ids, t.ID) })
+}
+
+func (r *MemoryRepo) GetTermsByTaxonomy(ctx context.Context, taxonomyKey string) ([]model.Term, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	terms, err := findDocs(r.terms, func(t *model.Term) bool { return t.TaxonomyKey == taxonomyKey })
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(terms, func(a, b model.Term) int {
+		return cmp.Or(cmp.Compare(a.Order, b.Order), strings.Compare(a.Name, b.Name))
+	})
+	return terms, nil
+}
+
+func (r *MemoryRepo) GetTermBySlug(ctx context.Context, taxonomyKey, slug string) (*model.Term, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	normalized := utils.NormalizeSlug(slug)
+	return findOne(r.terms, func(t *model.Term) bool {
+		return t.TaxonomyKey == taxonomyKey && t.SlugNormalized == normalized
+	})
+}
+
+func (r *MemoryRepo) IsTermSlugExists(ctx context.Context, taxonomyKey, slug string, excludeID primitive.ObjectID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	normalized := utils.NormalizeSlug(slug)
+	_, err := findOne(r.terms, func(t *model.Term) bool {
+		return t.TaxonomyKey == taxonomyKey && t.SlugNormalized == normalized && (excludeID.IsZero() || t.ID != excludeID)
+	})
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *MemoryRepo) ReorderTerms(ctx context.Context, taxonomyKey string, termIDs []primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := updateDocs(r.terms, func(t *model.Term) bool {
+		return t.TaxonomyKey == taxonomyKey && slices.Contains(termIDs, t.ID)
+	}, termDocID, func(t *model.Term) bool {
+		t.Order = slices.Index(termIDs, t.ID)
+		return true
+	})
+	return err
+}
+
+func (r *MemoryRepo) CountTermsInTaxonomy(ctx context.Context, taxonomyKey string, termIDs []primitive.ObjectID) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	terms, err := findDocs(r.terms, func(t *model.Term) bool {
+		return t.TaxonomyKey == taxonomyKey && slices.Contains(termIDs, t.ID)
+	})
+	return int64(len(terms)), err
+}
+
+func (r *MemoryRepo) UpdateTerm(ctx context.Context, term *model.Term) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	term.SlugNormalized = utils.NormalizeSlug(term.Slug)
+	term.UpdatedAt = time.Now()
+	if _, exists := r.terms[term.ID]; !exists {
+		return nil
+	}
+	if err := r.checkTermSlugLocked(term); err != nil {
+		return err
+	}
+	return putDoc(r.terms, term.ID, term)
+}
+
+func (r *MemoryRepo) DeleteTerm(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.terms, id)
+	return nil
+}
+
+// GetTermAncestors 沿 parent_id 向上查找，按由近到远排序，遇到环时停止
+func (r *MemoryRepo) GetTermAncestors(ctx context.Context, id primitive.ObjectID) ([]model.Term, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	term, err := getDoc[model.Term](r.terms, id)
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ancestors []model.Term
+	seen := map[primitive.ObjectID]bool{}
+	for parentID := term.ParentID; !parentID.IsZero() && !seen[parentID]; {
+		seen[parentID] = true
+		parent, err := getDoc[model.Term](r.terms, parentID)
+		if err == ErrNotFound {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, *parent)
+		parentID = parent.ParentID
+	}
+	return ancestors, nil
+}
+
+func (r *MemoryRepo) HasChildTerms(ctx context.Context, parentID primitive.ObjectID) (bool, error) {
+	count, err := r.CountChildTerms(ctx, parentID)
+	return count > 0, err
+}
+
+func (r *MemoryRepo) CountChildTerms(ctx context.Context, parentID primitive.ObjectID) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	terms, err := findDocs(r.terms, func(t *model.Term) bool { return t.ParentID == parentID })
+	return int64(len(terms)), err
+}
+
+func (r *MemoryRepo) HasTermReferences(ctx context.Context, taxonomyKey string, termID primitive.ObjectID) (bool, error) {
+	count, err := r.CountTermReferences(ctx, taxonomyKey, termID)
+	return count > 0, err
+}
+
+// taxonomyFieldPathsLocked 对应 MongoRepo.taxonomyFieldPaths
+func (r *MemoryRepo) taxonomyFieldPathsLocked(taxonomyKey string) ([]string, error) {
+	schemas, err := findDocs[model.Schema](r.schemas, nil)
+	if err != nil {
+		return nil, err
+	}
+	return termFieldPaths(schemas, taxonomyKey), nil
+}
+
+// termRefMatch 对应 termRefFilter：在任一字段路径上（单值或多值）引用了 id 的 entry
+func termRefMatch(paths []string, id string) func(*model.Entry) bool {
+	return func(e *model.Entry) bool {
+		return slices.ContainsFunc(paths, func(path string) bool {
+			return matchPath(e.Attributes, strings.Split(termQueryPath(path), "."), id)
+		})
+	}
+}
+
+// rewriteTermRefs 对应 updateTermRefs：在每个字段路径上用 fn 改写叶子值
+func rewriteTermRefs(e *model.Entry, paths []string, fn func(leaf any) (any, bool)) {
+	for _, path := range paths {
+		rewritePath(e.Attributes, strings.Split(termQueryPath(path), "."), fn)
+	}
+}
+
+func (r *MemoryRepo) CountTermReferences(ctx context.Context, taxonomyKey string, termID primitive.ObjectID) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	paths, err := r.taxonomyFieldPathsLocked(taxonomyKey)
+	if err != nil || len(paths) == 0 {
+		return 0, err
+	}
+	entries, err := findDocs(r.entries, termRefMatch(paths, termID.Hex()))
+	return int64(len(entries)), err
+}
+
+func (r *MemoryRepo) RemoveTermReferences(ctx context.Context, taxonomyKey string, termID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	paths, err := r.taxonomyFieldPathsLocked(taxonomyKey)
+	if err != nil || len(paths) == 0 {
+		return 0, err
+	}
+	id := termID.Hex()
+	return updateDocs(r.entries, termRefMatch(paths, id), entryDocID, func(e *model.Entry) bool {
+		rewriteTermRefs(e, paths, func(leaf any) (any, bool) {
+			if arr, ok := asArray(leaf); ok {
+				return slices.DeleteFunc(slices.Clone(arr), func(item any) bool { return item == id }), true
+			}
+			return leaf, leaf != id
+		})
+		return true
+	})
+}
+
+func (r *MemoryRepo) CountTermUsage(ctx context.Context, taxonomyKey string) (map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entries, err := findDocs[model.Entry](r.entries, nil)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := r.taxonomyFieldPathsLocked(taxonomyKey)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	for _, e := range entries {
+		// 同一 entry 在多个字段中引用同一 term 只计一次
+		seen := make(map[string]bool)
+		for _, path := range paths {
+			pathValues(e.Attributes, strings.Split(termQueryPath(path), "."), func(v any) {
+				if id, ok := v.(string); ok && !seen[id] {
+					seen[id] = true
+					counts[id]++
+				}
+			})
+		}
+	}
+	return counts, nil
+}
+
+// MergeTerms 在锁内完成，效果与 MongoRepo 的事务一致
+func (r *MemoryRepo) MergeTerms(ctx context.Context, source, target *model.Term) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	paths, err := r.taxonomyFieldPathsLocked(source.TaxonomyKey)
+	if err != nil {
+		return 0, err
+	}
+	sourceID, targetID := source.ID.Hex(), target.ID.Hex()
+
+	rewritten, err := updateDocs(r.entries, termRefMatch(paths, sourceID), entryDocID, func(e *model.Entry) bool {
+		rewriteTermRefs(e, paths, func(leaf any) (any, bool) {
+			arr, ok := asArray(leaf)
+			if !ok {
+				if leaf == sourceID {
+					return targetID, true
+				}
+				return leaf, true
+			}
+			if !slices.Contains(arr, any(sourceID)) {
+				return leaf, true
+			}
+			merged := slices.DeleteFunc(slices.Clone(arr), func(item any) bool { return item == sourceID })
+			if !slices.Contains(merged, any(targetID)) {
+				merged = append(merged, targetID)
+			}
+			return merged, true
+		})
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if _, err := updateDocs(r.terms, func(t *model.Term) bool {
+		return t.ParentID == source.ID && t.ID != target.ID
+	}, termDocID, func(t *model.Term) bool {
+		t.ParentID = target.ID
+		t.UpdatedAt = now
+		return true
+	}); err != nil {
+		return 0, err
+	}
+	if target.ParentID == source.ID {
+		if _, err := updateDocs(r.terms, func(t *model.Term) bool { return t.ID == target.ID }, termDocID, func(t *model.Term) bool {
+			t.ParentID = source.ParentID
+			t.UpdatedAt = now
+			return true
+		}); err != nil {
+			return 0, err
+		}
+	}
+	delete(r.terms, source.ID)
+	return rewritten, nil
+}
+
+func (r *MemoryRepo) DeleteTermsByTaxonomy(ctx context.Context, taxonomyKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := deleteDocs(r.terms, func(t *model.Term) bool { return t.TaxonomyKey == taxonomyKey }, termDocID)
+	return err
+}
+
+// --- Comment Operations ---
+func (r *MemoryRepo) CreateComment(ctx context.Context, comment *model.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	comment.CreatedAt = time.Now()
+	comment.ID = newIDIfZero(comment.ID)
+	return putDoc(r.comments, comment.ID, comment)
+}
+
+func (r *MemoryRepo) GetCommentByID(ctx context.Context, id primitive.ObjectID) (*model.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return getDoc[model.Comment](r.comments, id)
+}
+
+// isApproved 与 approvedStatusFilter 对应
+func isApproved(c *model.Comment) bool {
+	return c.Status != model.CommentPending && c.Status != model.CommentRejected
+}
+
+// findCommentsLocked 按创建时间升序返回满足 match 的评论
+func (r *MemoryRepo) findCommentsLocked(match func(*model.Comment) bool) ([]model.Comment, error) {
+	comments, err := findDocs(r.comments, match)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(comments, func(a, b model.Comment) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return comments, nil
+}
+
+// withAuthorsLocked 填充作者和被回复用户的公开信息，对应 commentAuthorLookup
+func (r *MemoryRepo) withAuthorsLocked(comments []model.Comment) ([]model.CommentWithAuthor, error) {
+	lookup := func(id string) (*model.UserPublic, error) {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, nil
+		}
+		user, err := getDoc[model.User](r.users, oid)
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return user.Public(), nil
+	}
+	result := make([]model.CommentWithAuthor, 0, len(comments))
+	for _, c := range comments {
+		item := model.CommentWithAuthor{Comment: c}
+		var err error
+		if item.Author, err = lookup(c.AuthorID); err != nil {
+			return nil, err
+		}
+		if item.ReplyTo, err = lookup(c.ReplyToUID); err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func (r *MemoryRepo) GetCommentsByEntry(ctx context.Context, entryID primitive.ObjectID) ([]model.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.findCommentsLocked(func(c *model.Comment) bool { return c.EntryID == entryID })
+}
+
+func (r *MemoryRepo) GetCommentsByEntryPaginated(ctx context.Context, entryID primitive.ObjectID, approvedOnly bool, limit, offset int64) ([]model.CommentWithAuthor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	roots, err := r.findCommentsLocked(func(c *model.Comment) bool {
+		return c.EntryID == entryID && c.RootID.IsZero() && (!approvedOnly || isApproved(c))
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.withAuthorsLocked(paginate(roots, limit, offset))
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		rootID := result[i].ID
+		replies, err := findDocs(r.comments, func(c *model.Comment) bool {
+			return c.RootID == rootID && (!approvedOnly || isApproved(c))
+		})
+		if err != nil {
+			return nil, err
+		}
+		result[i].ReplyCount = int64(len(replies))
+	}
+	return result, nil
+}
+
+func (r *MemoryRepo) GetRepliesByRoot(ctx context.Context, rootID primitive.ObjectID, approvedOnly bool, limit, offset int64) ([]model.CommentWithAuthor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	replies, err := r.findCommentsLocked(func(c *model.Comment) bool {
+		return c.RootID == rootID && (!approvedOnly || isApproved(c))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.withAuthorsLocked(paginate(replies, limit, offset))
+}
+
+func (r *MemoryRepo) HasThreadCommentByAuthor(ctx context.Context, rootID primitive.ObjectID, authorID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, err := findOne(r.comments, func(c *model.Comment) bool {
+		return c.AuthorID == authorID && (c.ID == rootID || c.RootID == rootID)
+	})
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *MemoryRepo) countCommentsLocked(match func(*model.Comment) bool) (int64, error) {
+	comments, err := findDocs(r.comments, match)
+	return int64(len(comments)), err
+}
+
+func (r *MemoryRepo) CountRepliesByRoot(ctx context.Context, rootID primitive.ObjectID, approvedOnly bool) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.countCommentsLocked(func(c *model.Comment) bool {
+		return c.RootID == rootID && (!approvedOnly || isApproved(c))
+	})
+}
+
+func (r *MemoryRepo) GetPendingComments(ctx context.Context, limit, offset int64) ([]model.CommentWithAuthor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pending, err := r.findCommentsLocked(func(c *model.Comment) bool { return c.Status == model.CommentPending })
+	if err != nil {
+		return nil, err
+	}
+	return r.withAuthorsLocked(paginate(pending, limit, offset))
+}
+
+func (r *MemoryRepo) CountPendingComments(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.countCommentsLocked(func(c *model.Comment) bool { return c.Status == model.CommentPending })
+}
+
+func (r *MemoryRepo) UpdateCommentStatus(ctx context.Context, id primitive.ObjectID, status model.CommentStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := updateDocs(r.comments, func(c *model.Comment) bool { return c.ID == id }, commentDocID, func(c *model.Comment) bool {
+		c.Status = status
+		return true
+	})
+	return err
+}
+
+func (r *MemoryRepo) CountCommentsByEntry(ctx context.Context, entryID primitive.ObjectID) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.countCommentsLocked(func(c *model.Comment) bool { return c.EntryID == entryID })
+}
+
+func (r *MemoryRepo) CountCommentsByEntries(ctx context.Context, entryIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[primitive.ObjectID]int64, len(entryIDs))
+	comments, err := findDocs(r.comments, func(c *model.Comment) bool {
+		return slices.Contains(entryIDs, c.EntryID) && isApproved(c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		counts[c.EntryID]++
+	}
+	return counts, nil
+}
+
+func (r *MemoryRepo) CountRootCommentsByEntry(ctx context.Context, entryID primitive.ObjectID, approvedOnly bool) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.countCommentsLocked(func(c *model.Comment) bool {
+		return c.EntryID == entryID && c.RootID.IsZero() && (!approvedOnly || isApproved(c))
+	})
+}
+
+func (r *MemoryRepo) DeleteComment(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.comments, id)
+	return nil
+}
+
+func (r *MemoryRepo) UpdateComment(ctx context.Context, comment *model.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	comment.UpdatedAt = time.Now()
+	if _, exists := r.comments[comment.ID]; !exists {
+		return nil
+	}
+	return putDoc(r.comments, comment.ID, comment)
+}
+
+func (r *MemoryRepo) SoftDeleteComment(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := updateDocs(r.comments, func(c *model.Comment) bool { return c.ID == id }, commentDocID, func(c *model.Comment) bool {
+		c.Content = model.DeletedCommentContent
+		c.Deleted = true
+		c.UpdatedAt = time.Now()
+		return true
+	})
+	return err
+}
+
+func (r *MemoryRepo) DeleteCommentsByRootID(ctx context.Context, rootID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := deleteDocs(r.comments, func(c *model.Comment) bool { return c.RootID == rootID }, commentDocID)
+	return err
+}
+
+// --- Comment Vote Operations ---
+func (r *MemoryRepo) LikeComment(ctx context.Context, commentID primitive.ObjectID, userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := findOne(r.votes, func(v *model.CommentVote) bool { return v.CommentID == commentID && v.UserID == userID })
+	if err == nil {
+		return false, nil
+	}
+	if err != ErrNotFound {
+		return false, err
+	}
+	vote := &model.CommentVote{ID: primitive.NewObjectID(), CommentID: commentID, UserID: userID, CreatedAt: time.Now()}
+	if err := putDoc(r.votes, vote.ID, vote); err != nil {
+		return false, err
+	}
+	_, err = updateDocs(r.comments, func(c *model.Comment) bool { return c.ID == commentID }, commentDocID, func(c *model.Comment) bool {
+		c.LikeCount++
+		return true
+	})
+	return true, err
+}
+
+func (r *MemoryRepo) UnlikeComment(ctx context.Context, commentID primitive.ObjectID, userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deleted, err := deleteDocs(r.votes, func(v *model.CommentVote) bool { return v.CommentID == commentID && v.UserID == userID }, voteDocID)
+	if err != nil || deleted == 0 {
+		return false, err
+	}
+	_, err = updateDocs(r.comments, func(c *model.Comment) bool { return c.ID == commentID && c.LikeCount > 0 }, commentDocID, func(c *model.Comment) bool {
+		c.LikeCount--
+		return true
+	})
+	return true, err
+}
+
+func (r *MemoryRepo) GetLikedCommentIDs(ctx context.Context, userID string, commentIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	liked := make(map[primitive.ObjectID]bool)
+	votes, err := findDocs(r.votes, func(v *model.CommentVote) bool {
+		return v.UserID == userID && slices.Contains(commentIDs, v.CommentID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range votes {
+		liked[v.CommentID] = true
+	}
+	return liked, nil
+}
+
+// --- Comment Report Operations ---
+func (r *MemoryRepo) CreateCommentReport(ctx context.Context, report *model.CommentReport) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report.CreatedAt = time.Now()
+	_, err := findOne(r.reports, func(rp *model.CommentReport) bool {
+		return rp.CommentID == report.CommentID && rp.UserID == report.UserID
+	})
+	if err == nil {
+		return false, nil
+	}
+	if err != ErrNotFound {
+		return false, err
+	}
+	report.ID = newIDIfZero(report.ID)
+	return true, putDoc(r.reports, report.ID, report)
+}
+
+// GetReportedComments 与 MongoRepo 一样在分页之后才丢弃已删除评论的举报
+func (r *MemoryRepo) GetReportedComments(ctx context.Context, minReports, limit, offset int64) ([]model.ReportedComment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	reports, err := findDocs[model.CommentReport](r.reports, nil)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[primitive.ObjectID]int64)
+	for _, rp := range reports {
+		counts[rp.CommentID]++
+	}
+	var ids []primitive.ObjectID
+	for id, n := range counts {
+		if n >= minReports {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b primitive.ObjectID) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a.Hex(), b.Hex()))
+	})
+	var reported []model.ReportedComment
+	for _, id := range paginate(ids, limit, offset) {
+		comment, err := getDoc[model.Comment](r.comments, id)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		reported = append(reported, model.ReportedComment{Comment: *comment, ReportCount: counts[id]})
+	}
+	return reported, nil
+}
+
+// --- Statistics ---
+func (r *MemoryRepo) GetAdminStats(ctx context.Context) (*model.AdminStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stats := &model.AdminStats{
+		TermsByTaxonomy: make(map[string]int64),
+		GeneratedAt:     time.Now(),
+	}
+
+	entries, err := findDocs[model.Entry](r.entries, nil)
+	if err != nil {
+		return nil, err
+	}
+	bySchema := make(map[string]*model.SchemaEntryStats)
+	for _, e := range entries {
+		s, ok := bySchema[e.SchemaKey]
+		if !ok {
+			s = &model.SchemaEntryStats{SchemaKey: e.SchemaKey}
+			bySchema[e.SchemaKey] = s
+		}
+		s.Total++
+		if e.Base.Draft {
+			s.Drafts++
+		}
+	}
+	stats.Entries.BySchema = []model.SchemaEntryStats{}
+	for _, key := range slices.Sorted(maps.Keys(bySchema)) {
+		s := bySchema[key]
+		stats.Entries.BySchema = append(stats.Entries.BySchema, *s)
+		stats.Entries.Total += s.Total
+		stats.Entries.Drafts += s.Drafts
+	}
+	stats.Entries.Published = stats.Entries.Total - stats.Entries.Drafts
+
+	users, err := findDocs(r.users, func(u *model.User) bool { return !u.IsDeleted() })
+	if err != nil {
+		return nil, err
+	}
+	stats.Users = int64(len(users))
+	stats.Comments = int64(len(r.comments))
+
+	terms, err := findDocs[model.Term](r.terms, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range terms {
+		stats.TermsByTaxonomy[t.TaxonomyKey]++
+	}
+	return stats, nil
+}
+
+// --- Session Operations ---
+func (r *MemoryRepo) CreateSession(ctx context.Context, session *model.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	if _, err := findOne(r.sessions, func(s *model.Session) bool { return s.Token == session.Token }); err != ErrNotFound {
+		if err == nil {
+			return ErrConflict
+		}
+		return err
+	}
+	session.ID = newIDIfZero(session.ID)
+	return putDoc(r.sessions, session.ID, session)
+}
+
+func activeSession(token string) func(*model.Session) bool {
+	now := time.Now()
+	return func(s *model.Session) bool { return s.Token == token && s.ExpiresAt.After(now) }
+}
+
+func (r *MemoryRepo) GetSessionByToken(ctx context.Context, token string) (*model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return findOne(r.sessions, activeSession(token))
+}
+
+func (r *MemoryRepo) TakeSession(ctx context.Context, token string) (*model.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, err := findOne(r.sessions, activeSession(token))
+	if err != nil {
+		return nil, err
+	}
+	delete(r.sessions, session.ID)
+	return session, nil
+}
+
+func (r *MemoryRepo) TouchSession(ctx context.Context, id primitive.ObjectID, seenAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := updateDocs(r.sessions, func(s *model.Session) bool { return s.ID == id }, sessionDocID, func(s *model.Session) bool {
+		s.LastSeenAt = seenAt
+		return true
+	})
+	return err
+}
+
+func (r *MemoryRepo) DeleteSession(ctx context.Context, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := deleteDocs(r.sessions, func(s *model.Session) bool { return s.Token == token }, sessionDocID)
+	return err
+}
+
+func (r *MemoryRepo) GetSessionsByUser(ctx context.Context, userID primitive.ObjectID) ([]model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	now := time.Now()
+	sessions, err := findDocs(r.sessions, func(s *model.Session) bool { return s.UserID == userID && s.ExpiresAt.After(now) })
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(sessions, func(a, b model.Session) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return sessions, nil
+}
+
+func (r *MemoryRepo) DeleteUserSession(ctx context.Context, userID, sessionID primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deleted, err := deleteDocs(r.sessions, func(s *model.Session) bool { return s.ID == sessionID && s.UserID == userID }, sessionDocID)
+	return deleted > 0, err
+}
+
+func (r *MemoryRepo) DeleteUserSessionsExcept(ctx context.Context, userID primitive.ObjectID, keepToken string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return deleteDocs(r.sessions, func(s *model.Session) bool { return s.UserID == userID && s.Token != keepToken }, sessionDocID)
+}
+
+func (r *MemoryRepo) DeleteUserSessions(ctx context.Context, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := deleteDocs(r.sessions, func(s *model.Session) bool { return s.UserID == userID }, sessionDocID)
+	return err
+}
+
+func (r *MemoryRepo) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	return deleteDocs(r.sessions, func(s *model.Session) bool { return s.ExpiresAt.Before(now) }, sessionDocID)
+}
+
+// --- OAuth State Operations ---
+func (r *MemoryRepo) CreateOAuthState(ctx context.Context, state *model.OAuthState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state.CreatedAt = time.Now()
+	if _, err := findOne(r.oauthStates, func(s *model.OAuthState) bool { return s.State == state.State }); err != ErrNotFound {
+		if err == nil {
+			return ErrConflict
+		}
+		return err
+	}
+	state.ID = newIDIfZero(state.ID)
+	return putDoc(r.oauthStates, state.ID, state)
+}
+
+func (r *MemoryRepo) GetAndDeleteOAuthState(ctx context.Context, state string) (*model.OAuthState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	oauthState, err := findOne(r.oauthStates, func(s *model.OAuthState) bool { return s.State == state })
+	if err != nil {
+		return nil, err
+	}
+	delete(r.oauthStates, oauthState.ID)
+	return oauthState, nil
+}
+
+func (r *MemoryRepo) DeleteExpiredOAuthStates(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	return deleteDocs(r.oauthStates, func(s *model.OAuthState) bool { return s.ExpiresAt.Before(now) }, oauthStateDocID)
+}
+
+// --- API Key Operations ---
+func (r *MemoryRepo) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key.CreatedAt = time.Now()
+	if _, err := findOne(r.apiKeys, func(k *model.APIKey) bool { return k.KeyHash == key.KeyHash }); err != ErrNotFound {
+		if err == nil {
+			return ErrConflict
+		}
+		return err
+	}
+	key.ID = newIDIfZero(key.ID)
+	return putDoc(r.apiKeys, key.ID, key)
+}
+
+func (r *MemoryRepo) GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return findOne(r.apiKeys, func(k *model.APIKey) bool { return k.KeyHash == keyHash })
+}
+
+func (r *MemoryRepo) GetAPIKeysByUser(ctx context.Context, userID primitive.ObjectID) ([]model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys, err := findDocs(r.apiKeys, func(k *model.APIKey) bool { return k.UserID == userID })
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(keys, func(a, b model.APIKey) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return keys, nil
+}
+
+func (r *MemoryRepo) TouchAPIKey(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := updateDocs(r.apiKeys, func(k *model.APIKey) bool { return k.ID == id }, apiKeyDocID, func(k *model.APIKey) bool {
+		k.LastUsedAt = &usedAt
+		return true
+	})
+	return err
+}
+
+func (r *MemoryRepo) DeleteUserAPIKeys(ctx context.Context, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := deleteDocs(r.apiKeys, func(k *model.APIKey) bool { return k.UserID == userID }, apiKeyDocID)
+	return err
+}
+
+func (r *MemoryRepo) DeleteAPIKey(ctx context.Context, userID, keyID primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deleted, err := deleteDocs(r.apiKeys, func(k *model.APIKey) bool { return k.ID == keyID && k.UserID == userID }, apiKeyDocID)
+	return deleted > 0, err
+}
+
+// --- Media Operations ---
+func (r *MemoryRepo) CreateMedia(ctx context.Context, media *model.Media) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	media.CreatedAt = time.Now()
+	media.ID = newIDIfZero(media.ID)
+	return putDoc(r.media, media.ID, media)
+}
+
+func (r *MemoryRepo) GetMediaByID(ctx context.Context, id primitive.ObjectID) (*model.Media, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return getDoc[model.Media](r.media, id)
+}
+
+func (r *MemoryRepo) DeleteMedia(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.media, id)
+	return nil
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"matter-core/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// termRefFixture 一个在顶层、嵌套对象和数组元素中引用 brand taxonomy 的 schema，以及若干 entry
+type termRefFixture struct {
+	repo    *MemoryRepo
+	brand   string                        // 被引用的 term ID
+	entries map[string]primitive.ObjectID // 按名称索引的 entry ID
+}
+
+func newTermRefFixture(t *testing.T) *termRefFixture {
+	t.Helper()
+	ctx := context.Background()
+	repo := NewMemoryRepo()
+	schema := &model.Schema{Key: "product", Version: 1, Fields: []model.FieldSchema{
+		{Key: "category", Type: model.TypeTaxonomy, TaxonomyKey: "category"},
+		{Key: "maker", Type: model.TypeTaxonomy, TaxonomyKey: "brand"},
+		{Key: "specs", Type: model.TypeObject, Children: []model.FieldSchema{
+			{Key: "brand", Type: model.TypeTaxonomy, TaxonomyKey: "brand"},
+		}},
+		{Key: "variants", Type: model.TypeArray, ItemType: &model.FieldSchema{Type: model.TypeObject, Children: []model.FieldSchema{
+			{Key: "brands", Type: model.TypeTaxonomy, TaxonomyKey: "brand", AllowMultiple: true},
+		}}},
+	}}
+	if err := repo.CreateSchema(ctx, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &termRefFixture{repo: repo, brand: primitive.NewObjectID().Hex(), entries: map[string]primitive.ObjectID{}}
+	for name, attrs := range map[string]map[string]any{
+		"other key":     {"maker": f.brand},
+		"nested object": {"specs": map[string]any{"brand": f.brand}},
+		"array item":    {"variants": []any{map[string]any{"brands": []any{"x"}}, map[string]any{"brands": []any{"y", f.brand}}}},
+		"other field":   {"category": f.brand}, // 同一 ID 出现在其他 taxonomy 的字段中不算引用
+		"unrelated":     {"specs": map[string]any{"brand": "z"}},
+		"top-level key": {"brand": f.brand}, // schema 中没有名为 brand 的顶层字段
+	} {
+		entry := &model.Entry{SchemaKey: "product", SchemaID: schema.ID, Attributes: attrs}
+		if err := repo.CreateEntry(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+		f.entries[name] = entry.ID
+	}
+	return f
+}
+
+// referencing 引用了 brand 的 entry 名称
+var referencing = []string{"other key", "nested object", "array item"}
+
+func (f *termRefFixture) attributes(t *testing.T, name string) map[string]any {
+	t.Helper()
+	entry, err := f.repo.GetEntryByID(context.Background(), f.entries[name])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry.Attributes
+}
+
+func TestForEachTermEntryBatchNestedFields(t *testing.T) {
+	f := newTermRefFixture(t)
+	brand, _ := primitive.ObjectIDFromHex(f.brand)
+
+	got := map[primitive.ObjectID]bool{}
+	err := f.repo.ForEachTermEntryBatch(context.Background(), "brand", brand, 1, func(batch []model.Entry) error {
+		for _, e := range batch {
+			got[e.ID] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(referencing) {
+		t.Errorf("matched %d entries, want %d", len(got), len(referencing))
+	}
+	for _, name := range referencing {
+		if !got[f.entries[name]] {
+			t.Errorf("entry %q was not matched", name)
+		}
+	}
+}
+
+func TestRemoveTermReferencesNestedFields(t *testing.T) {
+	ctx := context.Background()
+	f := newTermRefFixture(t)
+	brand, _ := primitive.ObjectIDFromHex(f.brand)
+
+	count, err := f.repo.CountTermReferences(ctx, "brand", brand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != int64(len(referencing)) {
+		t.Errorf("CountTermReferences() = %d, want %d", count, len(referencing))
+	}
+
+	removed, err := f.repo.RemoveTermReferences(ctx, "brand", brand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != int64(len(referencing)) {
+		t.Errorf("RemoveTermReferences() = %d, want %d", removed, len(referencing))
+	}
+	if has, err := f.repo.HasTermReferences(ctx, "brand", brand); err != nil || has {
+		t.Errorf("HasTermReferences() after remove = %v, %v", has, err)
+	}
+
+	for name, want := range map[string]map[string]any{
+		"other key":     {},
+		"nested object": {"specs": map[string]any{}},
+		"array item":    {"variants": []any{map[string]any{"brands": []any{"x"}}, map[string]any{"brands": []any{"y"}}}},
+		"other field":   {"category": f.brand},
+		"top-level key": {"brand": f.brand},
+	} {
+		if got := normalize(f.attributes(t, name)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: attributes = %#v, want %#v", name, got, want)
+		}
+	}
+}
+
+// normalize 把解码得到的 primitive.A / primitive.M 转成 []any / map[string]any 以便比较
+func normalize(v any) any {
+	if arr, ok := asArray(v); ok {
+		out := make([]any, len(arr))
+		for i, item := range arr {
+			out[i] = normalize(item)
+		}
+		return out
+	}
+	if obj := asObject(v); obj != nil {
+		out := make(map[string]any, len(obj))
+		for k, item := range obj {
+			out[k] = normalize(item)
+		}
+		return out
+	}
+	return v
+}
+
+func TestMergeTermsNestedFields(t *testing.T) {
+	ctx := context.Background()
+	f := newTermRefFixture(t)
+	brand, _ := primitive.ObjectIDFromHex(f.brand)
+	source := &model.Term{ID: brand, TaxonomyKey: "brand", Name: "Old", Slug: "old"}
+	target := &model.Term{TaxonomyKey: "brand", Name: "New", Slug: "new"}
+	for _, term := range []*model.Term{source, target} {
+		if err := f.repo.CreateTerm(ctx, term); err != nil {
+			t.Fatal(err)
+		}
+	}
+	to := target.ID.Hex()
+
+	rewritten, err := f.repo.MergeTerms(ctx, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewritten != int64(len(referencing)) {
+		t.Errorf("MergeTerms() = %d, want %d", rewritten, len(referencing))
+	}
+	if _, err := f.repo.GetTermByID(ctx, source.ID); err != ErrNotFound {
+		t.Errorf("source term still exists: %v", err)
+	}
+
+	for name, want := range map[string]map[string]any{
+		"other key":     {"maker": to},
+		"nested object": {"specs": map[string]any{"brand": to}},
+		"array item":    {"variants": []any{map[string]any{"brands": []any{"x"}}, map[string]any{"brands": []any{"y", to}}}},
+		"other field":   {"category": f.brand},
+		"top-level key": {"brand": f.brand},
+	} {
+		if got := normalize(f.attributes(t, name)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: attributes = %#v, want %#v", name, got, want)
+		}
+	}
+}
+
+func TestCountTermUsageNestedFields(t *testing.T) {
+	ctx := context.Background()
+	f := newTermRefFixture(t)
+	// 同一 entry 在多个字段中引用同一 term 只计一次
+	both := &model.Entry{SchemaKey: "product", Attributes: map[string]any{
+		"maker":    f.brand,
+		"variants": []any{map[string]any{"brands": []any{f.brand, "x"}}},
+	}}
+	if err := f.repo.CreateEntry(ctx, both); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := f.repo.CountTermUsage(ctx, "brand")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{f.brand: int64(len(referencing)) + 1, "x": 2, "y": 1, "z": 1}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("CountTermUsage() = %v, want %v", counts, want)
+	}
+}
+
+func TestListEntriesByTermNestedFields(t *testing.T) {
+	ctx := context.Background()
+	f := newTermRefFixture(t)
+	brand, _ := primitive.ObjectIDFromHex(f.brand)
+	if err := f.repo.CreateTerm(ctx, &model.Term{ID: brand, TaxonomyKey: "brand", Name: "Acme", Slug: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := f.repo.ListEntries(ctx, nil, []string{f.brand}, nil, "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[primitive.ObjectID]bool{}
+	for _, e := range entries {
+		got[e.ID] = true
+	}
+	if len(got) != len(referencing) {
+		t.Errorf("matched %d entries, want %d", len(got), len(referencing))
+	}
+	for _, name := range referencing {
+		if !got[f.entries[name]] {
+			t.Errorf("entry %q was not matched", name)
+		}
+	}
+
+	// 不存在的 term 不匹配任何 entry，而不是退化为不过滤
+	total, err := f.repo.CountEntries(ctx, nil, []string{primitive.NewObjectID().Hex()}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 0 {
+		t.Errorf("CountEntries() with unknown term = %d, want 0", total)
+	}
+}
+
+func TestTransferEntriesRecordsTransfer(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo()
+	entry := &model.Entry{SchemaKey: "post", AuthorID: "alice"}
+	if err := repo.CreateEntry(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := repo.TransferEntries(ctx, "alice", "bob", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("transferred %d entries, want 1", n)
+	}
+	got, err := repo.GetEntryByID(ctx, entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AuthorID != "bob" || len(got.Transfers) != 1 {
+		t.Fatalf("entry after transfer = author %q, transfers %+v", got.AuthorID, got.Transfers)
+	}
+	if tr := got.Transfers[0]; tr.FromAuthorID != "alice" || tr.ToAuthorID != "bob" || tr.OperatorID != "admin" || tr.At.IsZero() {
+		t.Errorf("transfer record = %+v", tr)
+	}
+}
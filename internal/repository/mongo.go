@@ -2,7 +2,13 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"maps"
 	"matter-core/internal/model"
+	"matter-core/pkg/utils"
+	"slices"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -20,8 +26,12 @@ type MongoRepo struct {
 	taxonomy    *mongo.Collection
 	terms       *mongo.Collection
 	comments    *mongo.Collection
+	votes       *mongo.Collection
+	reports     *mongo.Collection
 	sessions    *mongo.Collection
 	oauthStates *mongo.Collection
+	apiKeys     *mongo.Collection
+	media       *mongo.Collection
 }
 
 func NewMongoRepo(uri, dbName string) (*MongoRepo, error) {
@@ -47,17 +57,189 @@ func NewMongoRepo(uri, dbName string) (*MongoRepo, error) {
 		taxonomy:    db.Collection("taxonomies"),
 		terms:       db.Collection("terms"),
 		comments:    db.Collection("comments"),
+		votes:       db.Collection("comment_votes"),
+		reports:     db.Collection("comment_reports"),
 		sessions:    db.Collection("sessions"),
 		oauthStates: db.Collection("oauth_states"),
+		apiKeys:     db.Collection("api_keys"),
+		media:       db.Collection("media"),
 	}
 
 	if err := repo.ensureIndexes(ctx); err != nil {
 		return nil, err
 	}
 
+	if err := repo.backfillNormalizedSlugs(); err != nil {
+		return nil, err
+	}
+
+	if err := repo.ensureTermSlugIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := repo.backfillEntryStatus(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := repo.backfillNormalizedEmails(ctx); err != nil {
+		return nil, err
+	}
+
 	return repo, nil
 }
 
+// backfillEntryStatus 为只有 draft 标记的旧 entry 补齐 status
+func (r *MongoRepo) backfillEntryStatus(ctx context.Context) error {
+	for draft, status := range map[bool]model.EntryStatus{true: model.EntryDraft, false: model.EntryPublished} {
+		_, err := r.entries.UpdateMany(ctx,
+			bson.M{"base.status": bson.M{"$exists": false}, "base.draft": draft},
+			bson.M{"$set": bson.M{"base.status": status}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// termSlugIndex (taxonomy_key, slug_normalized) 唯一索引的名字，与旧版本建立的非唯一索引同名
+const termSlugIndex = "taxonomy_key_1_slug_normalized_1"
+
+// ensureTermSlugIndex 在补齐 slug_normalized 之后建立唯一索引，保证同一 taxonomy 内 slug 唯一；
+// 旧版本的同名非唯一索引先删除。已有重复 slug 时返回错误，需先人工改名
+func (r *MongoRepo) ensureTermSlugIndex(ctx context.Context) error {
+	cursor, err := r.terms.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	var indexes []struct {
+		Name   string `bson:"name"`
+		Unique bool   `bson:"unique"`
+	}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		if idx.Name != termSlugIndex {
+			continue
+		}
+		if idx.Unique {
+			return nil
+		}
+		if _, err := r.terms.Indexes().DropOne(ctx, termSlugIndex); err != nil {
+			return err
+		}
+	}
+	_, err = r.terms.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "taxonomy_key", Value: 1}, {Key: "slug_normalized", Value: 1}},
+		Options: options.Index().SetName(termSlugIndex).SetUnique(true),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("terms with duplicate slugs in the same taxonomy must be renamed before startup: %w", err)
+	}
+	return err
+}
+
+// backfillNormalizedEmails 把旧用户的邮箱改写为规范形式（去空白、小写），之后按邮箱只做精确匹配。
+// 规范化后与其他用户冲突的记录保持原样并记录告警，需人工合并
+func (r *MongoRepo) backfillNormalizedEmails(ctx context.Context) error {
+	cursor, err := r.users.Find(ctx,
+		bson.M{"email": primitive.Regex{Pattern: `[A-Z]|^\s|\s$`}},
+		options.Find().SetProjection(bson.M{"email": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Email string             `bson:"email"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		update := bson.M{"$set": bson.M{"email": utils.NormalizeEmail(doc.Email)}}
+		if utils.NormalizeEmail(doc.Email) == "" {
+			update = bson.M{"$unset": bson.M{"email": ""}}
+		}
+		_, err := r.users.UpdateOne(ctx, bson.M{"_id": doc.ID}, update)
+		if mongo.IsDuplicateKeyError(err) {
+			slog.Warn("user email collides with another account after normalization",
+				"user_id", doc.ID.Hex(), "email", doc.Email)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// backfillNormalizedSlugs 为旧数据补齐 slug_normalized，已补齐时只是一次空查询
+func (r *MongoRepo) backfillNormalizedSlugs() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	for _, target := range []struct {
+		coll  *mongo.Collection
+		field string // slug 字段路径，规范化字段与其同级
+	}{
+		{r.terms, "slug"},
+		{r.entries, "base.slug"},
+	} {
+		normalized := strings.TrimSuffix(target.field, "slug") + "slug_normalized"
+		cursor, err := target.coll.Find(ctx,
+			bson.M{normalized: bson.M{"$exists": false}},
+			options.Find().SetProjection(bson.M{target.field: 1}))
+		if err != nil {
+			return err
+		}
+		var models []mongo.WriteModel
+		flush := func() error {
+			if len(models) == 0 {
+				return nil
+			}
+			_, err := target.coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+			models = models[:0]
+			return err
+		}
+		for cursor.Next(ctx) {
+			var doc struct {
+				ID   primitive.ObjectID `bson:"_id"`
+				Slug string             `bson:"slug"`
+				Base struct {
+					Slug string `bson:"slug"`
+				} `bson:"base"`
+			}
+			if err := cursor.Decode(&doc); err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+			slug := doc.Slug
+			if target.field == "base.slug" {
+				slug = doc.Base.Slug
+			}
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": doc.ID}).
+				SetUpdate(bson.M{"$set": bson.M{normalized: utils.NormalizeSlug(slug)}}))
+			if len(models) == 500 {
+				if err := flush(); err != nil {
+					cursor.Close(ctx)
+					return err
+				}
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *MongoRepo) ensureIndexes(ctx context.Context) error {
 	// Schema indexes
 	_, err := r.schemas.Indexes().CreateMany(ctx, []mongo.IndexModel{
@@ -72,6 +254,13 @@ func (r *MongoRepo) ensureIndexes(ctx context.Context) error {
 		{Keys: bson.D{{Key: "attributes.$**", Value: 1}}},
 		{Keys: bson.D{{Key: "schema_key", Value: 1}}},
 		{Keys: bson.D{{Key: "author_id", Value: 1}}},
+		{Keys: bson.D{{Key: "schema_key", Value: 1}, {Key: "base.slug_normalized", Value: 1}}},
+		{Keys: bson.D{{Key: "base.status", Value: 1}, {Key: "base.created_at", Value: -1}}},
+		// Text index for search without Meilisearch; a collection can only have one
+		{
+			Keys:    bson.D{{Key: "base.title", Value: "text"}, {Key: "body", Value: "text"}},
+			Options: options.Index().SetName("entries_text").SetWeights(bson.D{{Key: "base.title", Value: 10}, {Key: "body", Value: 1}}),
+		},
 	})
 	if err != nil {
 		return err
@@ -106,7 +295,26 @@ func (r *MongoRepo) ensureIndexes(ctx context.Context) error {
 	// Comment indexes
 	_, err = r.comments.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.D{{Key: "entry_id", Value: 1}}},
+		{Keys: bson.D{{Key: "entry_id", Value: 1}, {Key: "root_id", Value: 1}, {Key: "created_at", Value: 1}}},
 		{Keys: bson.D{{Key: "root_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Comment vote indexes
+	_, err = r.votes.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "comment_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Comment report indexes
+	_, err = r.reports.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "comment_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
 	})
 	if err != nil {
 		return err
@@ -116,6 +324,7 @@ func (r *MongoRepo) ensureIndexes(ctx context.Context) error {
 	_, err = r.sessions.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.D{{Key: "token", Value: 1}}, Options: options.Index().SetUnique(true)},
 		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
 	})
 	if err != nil {
 		return err
@@ -126,9 +335,30 @@ func (r *MongoRepo) ensureIndexes(ctx context.Context) error {
 		{Keys: bson.D{{Key: "state", Value: 1}}, Options: options.Index().SetUnique(true)},
 		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
 	})
+	if err != nil {
+		return err
+	}
+
+	// API key indexes
+	_, err = r.apiKeys.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Media indexes
+	_, err = r.media.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
 	return err
 }
 
+func (r *MongoRepo) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx, nil)
+}
+
 func (r *MongoRepo) Close(ctx context.Context) error {
 	return r.client.Disconnect(ctx)
 }
@@ -138,7 +368,7 @@ func (r *MongoRepo) CreateSchema(ctx context.Context, schema *model.Schema) erro
 	schema.CreatedAt = time.Now()
 	result, err := r.schemas.InsertOne(ctx, schema)
 	if err != nil {
-		return err
+		return translateErr(err)
 	}
 	schema.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
@@ -149,7 +379,7 @@ func (r *MongoRepo) GetLatestSchema(ctx context.Context, key string) (*model.Sch
 	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
 	err := r.schemas.FindOne(ctx, bson.M{"key": key}, opts).Decode(&schema)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &schema, nil
 }
@@ -158,14 +388,14 @@ func (r *MongoRepo) GetSchemaByID(ctx context.Context, id primitive.ObjectID) (*
 	var schema model.Schema
 	err := r.schemas.FindOne(ctx, bson.M{"_id": id}).Decode(&schema)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &schema, nil
 }
 
 func (r *MongoRepo) DeleteSchemasByKey(ctx context.Context, key string) error {
 	_, err := r.schemas.DeleteMany(ctx, bson.M{"key": key})
-	return err
+	return translateErr(err)
 }
 
 func (r *MongoRepo) ListSchemas(ctx context.Context) ([]model.Schema, error) {
@@ -179,90 +409,505 @@ func (r *MongoRepo) ListSchemas(ctx context.Context) ([]model.Schema, error) {
 	}
 	cursor, err := r.schemas.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	var schemas []model.Schema
 	if err := cursor.All(ctx, &schemas); err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return schemas, nil
 }
 
 // --- Entry Operations ---
 func (r *MongoRepo) CreateEntry(ctx context.Context, entry *model.Entry) error {
+	entry.Base.SyncStatus()
+	entry.Base.SlugNormalized = utils.NormalizeSlug(entry.Base.Slug)
 	entry.Base.CreatedAt = time.Now()
 	entry.Base.UpdatedAt = time.Now()
 	result, err := r.entries.InsertOne(ctx, entry)
 	if err != nil {
-		return err
+		return translateErr(err)
 	}
 	entry.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
 }
 
+// CreateEntries 批量插入 entry，并回填各自的 ID
+func (r *MongoRepo) CreateEntries(ctx context.Context, entries []*model.Entry) error {
+	now := time.Now()
+	docs := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		entry.Base.SyncStatus()
+		entry.Base.SlugNormalized = utils.NormalizeSlug(entry.Base.Slug)
+		entry.Base.CreatedAt = now
+		entry.Base.UpdatedAt = now
+		docs[i] = entry
+	}
+	result, err := r.entries.InsertMany(ctx, docs)
+	if err != nil {
+		return translateErr(err)
+	}
+	for i, id := range result.InsertedIDs {
+		entries[i].ID = id.(primitive.ObjectID)
+	}
+	return nil
+}
+
 func (r *MongoRepo) UpdateEntry(ctx context.Context, entry *model.Entry) error {
+	entry.Base.SyncStatus()
+	entry.Base.SlugNormalized = utils.NormalizeSlug(entry.Base.Slug)
 	entry.Base.UpdatedAt = time.Now()
 	_, err := r.entries.ReplaceOne(ctx, bson.M{"_id": entry.ID}, entry)
-	return err
+	return translateErr(err)
 }
 
 func (r *MongoRepo) DeleteEntry(ctx context.Context, id primitive.ObjectID) error {
 	// 先删除关联的评论
 	if _, err := r.comments.DeleteMany(ctx, bson.M{"entry_id": id}); err != nil {
-		return err
+		return translateErr(err)
 	}
 	_, err := r.entries.DeleteOne(ctx, bson.M{"_id": id})
-	return err
+	return translateErr(err)
 }
 
 func (r *MongoRepo) GetEntryByID(ctx context.Context, id primitive.ObjectID) (*model.Entry, error) {
 	var entry model.Entry
 	err := r.entries.FindOne(ctx, bson.M{"_id": id}).Decode(&entry)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &entry, nil
 }
 
-func (r *MongoRepo) ListEntries(ctx context.Context, schemaKey string, draft *bool, limit, offset int64) ([]model.Entry, error) {
+// GetEntryBySlug 按 schema 内的 slug 查找（不区分大小写和重音），有重复时返回最新的一条
+func (r *MongoRepo) GetEntryBySlug(ctx context.Context, schemaKey, slug string) (*model.Entry, error) {
+	var entry model.Entry
+	opts := options.FindOne().SetSort(bson.D{{Key: "base.created_at", Value: -1}})
+	filter := bson.M{"schema_key": schemaKey, "base.slug_normalized": utils.NormalizeSlug(slug)}
+	if err := r.entries.FindOne(ctx, filter, opts).Decode(&entry); err != nil {
+		return nil, translateErr(err)
+	}
+	return &entry, nil
+}
+
+// entryListFilter 构建列表过滤条件，schemaKeys 为空表示不限 schema，status 为空表示不限状态
+func entryListFilter(schemaKeys []string, draft *bool, status model.EntryStatus) bson.M {
 	filter := bson.M{}
-	if schemaKey != "" {
-		filter["schema_key"] = schemaKey
+	switch len(schemaKeys) {
+	case 0:
+	case 1:
+		filter["schema_key"] = schemaKeys[0]
+	default:
+		filter["schema_key"] = bson.M{"$in": schemaKeys}
 	}
 	if draft != nil {
 		filter["base.draft"] = *draft
 	}
+	if status != "" {
+		filter["base.status"] = status
+	}
+	return filter
+}
+
+// matchNothing 返回不匹配任何文档的过滤条件
+func matchNothing() bson.M { return bson.M{"_id": bson.M{"$in": bson.A{}}} }
+
+// entryFilter 在 entryListFilter 的基础上要求 entry 引用了所有 termIDs（与搜索的 AND 语义一致），
+// 字段路径由各 term 所属 taxonomy 在 schema 中的字段决定；term 不存在时不匹配任何 entry
+func (r *MongoRepo) entryFilter(ctx context.Context, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus) (bson.M, error) {
+	filter := entryListFilter(schemaKeys, draft, status)
+	if len(termIDs) == 0 {
+		return filter, nil
+	}
+	var oids []primitive.ObjectID
+	for _, id := range termIDs {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return matchNothing(), nil
+		}
+		if !slices.Contains(oids, oid) {
+			oids = append(oids, oid)
+		}
+	}
+	terms, err := r.GetTermsByIDs(ctx, oids)
+	if err != nil {
+		return nil, err
+	}
+	if len(terms) < len(oids) {
+		return matchNothing(), nil
+	}
+	pathsByTaxonomy := make(map[string][]string)
+	and := make([]bson.M, 0, len(terms))
+	for _, term := range terms {
+		paths, ok := pathsByTaxonomy[term.TaxonomyKey]
+		if !ok {
+			if paths, err = r.taxonomyFieldPaths(ctx, term.TaxonomyKey); err != nil {
+				return nil, err
+			}
+			pathsByTaxonomy[term.TaxonomyKey] = paths
+		}
+		if len(paths) == 0 {
+			return matchNothing(), nil
+		}
+		and = append(and, termRefFilter(paths, term.ID.Hex()))
+	}
+	filter["$and"] = and
+	return filter, nil
+}
+
+func (r *MongoRepo) ListEntries(ctx context.Context, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus, limit, offset int64) ([]model.Entry, error) {
+	filter, err := r.entryFilter(ctx, schemaKeys, termIDs, draft, status)
+	if err != nil {
+		return nil, err
+	}
 	opts := options.Find().SetLimit(limit).SetSkip(offset).SetSort(bson.D{{Key: "base.created_at", Value: -1}})
 	cursor, err := r.entries.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	var entries []model.Entry
 	if err := cursor.All(ctx, &entries); err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return entries, nil
 }
 
-func (r *MongoRepo) CountEntries(ctx context.Context, schemaKey string, draft *bool) (int64, error) {
-	filter := bson.M{}
-	if schemaKey != "" {
-		filter["schema_key"] = schemaKey
+// ListEntriesByAuthor 列出某个作者的 entry（含草稿，draft 为 nil 时不过滤），按创建时间排序，走 author_id 索引
+func (r *MongoRepo) ListEntriesByAuthor(ctx context.Context, authorID string, schemaKeys []string, draft *bool, status model.EntryStatus, oldestFirst bool, limit, offset int64) ([]model.Entry, int64, error) {
+	filter := entryListFilter(schemaKeys, draft, status)
+	filter["author_id"] = authorID
+	order := -1
+	if oldestFirst {
+		order = 1
+	}
+	opts := options.Find().SetLimit(limit).SetSkip(offset).SetSort(bson.D{{Key: "base.created_at", Value: order}})
+	cursor, err := r.entries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, translateErr(err)
+	}
+	var entries []model.Entry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, translateErr(err)
+	}
+	total, err := r.entries.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, translateErr(err)
+	}
+	return entries, total, nil
+}
+
+// TransferEntries 将 fromAuthorID 的所有 entry 转给 toAuthorID 并追加转移记录，返回转移的数量
+func (r *MongoRepo) TransferEntries(ctx context.Context, fromAuthorID, toAuthorID, operatorID string) (int64, error) {
+	now := time.Now()
+	transfer := model.OwnershipTransfer{FromAuthorID: fromAuthorID, ToAuthorID: toAuthorID, OperatorID: operatorID, At: now}
+	result, err := r.entries.UpdateMany(ctx, bson.M{"author_id": fromAuthorID}, bson.M{
+		"$set": bson.M{
+			"author_id":       toAuthorID,
+			"base.updated_at": now,
+		},
+		"$push": bson.M{"transfers": transfer},
+	})
+	if err != nil {
+		return 0, translateErr(err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// uniqueFieldBSONType 唯一字段在部分索引中匹配的 BSON 类型，null 不参与唯一约束
+func uniqueFieldBSONType(t model.FieldType) string {
+	if t == model.TypeNumber {
+		return "number"
+	}
+	return "string"
+}
+
+// uniqueIndexPrefix 唯一字段索引名的前缀，完整名称为 unique_<schema>_<field>
+const uniqueIndexPrefix = "unique_"
+
+// uniqueIndexModel 字段 key 在 schema 内的部分唯一索引，只约束指定 BSON 类型的值
+func uniqueIndexModel(schemaKey, fieldKey, bsonType string) mongo.IndexModel {
+	path := "attributes." + fieldKey
+	return mongo.IndexModel{
+		Keys: bson.D{{Key: "schema_key", Value: 1}, {Key: path, Value: 1}},
+		Options: options.Index().
+			SetName(uniqueIndexPrefix + schemaKey + "_" + fieldKey).
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{
+				"schema_key": schemaKey,
+				path:         bson.M{"$type": bsonType},
+			}),
+	}
+}
+
+// SyncUniqueFieldIndexes 使 schema key 的部分唯一索引与该版本声明的 Unique 字段一致，索引是唯一性的最终保证：
+// 建立新增的索引，字段类型变化时重建，删除不再声明的索引。已有重复数据时返回 ErrConflict，
+// 此时保留原有索引。传入没有 Unique 字段的 schema 即删除该 key 的全部唯一索引
+func (r *MongoRepo) SyncUniqueFieldIndexes(ctx context.Context, schema *model.Schema) error {
+	// 现有索引按 partialFilterExpression 中的 schema_key 识别，避免 "blog" 误删 "blog_post" 的索引
+	cursor, err := r.entries.Indexes().List(ctx)
+	if err != nil {
+		return translateErr(err)
+	}
+	var indexes []struct {
+		Name    string `bson:"name"`
+		Partial bson.M `bson:"partialFilterExpression"`
+	}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return translateErr(err)
+	}
+	existing := make(map[string]string) // 索引名 -> 约束的 BSON 类型
+	for _, index := range indexes {
+		if !strings.HasPrefix(index.Name, uniqueIndexPrefix) || index.Partial["schema_key"] != schema.Key {
+			continue
+		}
+		existing[index.Name] = ""
+		for key, cond := range index.Partial {
+			if m, ok := cond.(bson.M); ok && strings.HasPrefix(key, "attributes.") {
+				existing[index.Name], _ = m["$type"].(string)
+			}
+		}
+	}
+
+	type change struct{ field, oldType, newType string }
+	var created []mongo.IndexModel
+	var retyped []change
+	wanted := make(map[string]bool)
+	for _, field := range schema.Fields {
+		if !field.Unique {
+			continue
+		}
+		name := uniqueIndexPrefix + schema.Key + "_" + field.Key
+		bsonType := uniqueFieldBSONType(field.Type)
+		wanted[name] = true
+		oldType, ok := existing[name]
+		switch {
+		case !ok:
+			created = append(created, uniqueIndexModel(schema.Key, field.Key, bsonType))
+		case oldType != bsonType:
+			retyped = append(retyped, change{field.Key, oldType, bsonType})
+		}
+	}
+
+	if len(created) > 0 {
+		if _, err := r.entries.Indexes().CreateMany(ctx, created); err != nil {
+			return translateErr(err)
+		}
 	}
+	// 同名索引只能先删后建；新索引建立失败时恢复旧索引
+	for _, c := range retyped {
+		name := uniqueIndexPrefix + schema.Key + "_" + c.field
+		if _, err := r.entries.Indexes().DropOne(ctx, name); err != nil {
+			return translateErr(err)
+		}
+		if _, err := r.entries.Indexes().CreateOne(ctx, uniqueIndexModel(schema.Key, c.field, c.newType)); err != nil {
+			if c.oldType != "" {
+				_, _ = r.entries.Indexes().CreateOne(ctx, uniqueIndexModel(schema.Key, c.field, c.oldType))
+			}
+			return translateErr(err)
+		}
+	}
+	for name := range existing {
+		if wanted[name] {
+			continue
+		}
+		if _, err := r.entries.Indexes().DropOne(ctx, name); err != nil {
+			return translateErr(err)
+		}
+	}
+	return nil
+}
+
+// FindUniqueConflicts 返回 values 中已被同 schema 其他 entry 使用的字段 key。
+// 这只是为了给出友好的 409，检查与写入之间仍有竞争窗口，由唯一索引兜底
+func (r *MongoRepo) FindUniqueConflicts(ctx context.Context, schemaKey string, values map[string]any, excludeID primitive.ObjectID) ([]string, error) {
+	var conflicts []string
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		filter := bson.M{"schema_key": schemaKey, "attributes." + key: values[key]}
+		if !excludeID.IsZero() {
+			filter["_id"] = bson.M{"$ne": excludeID}
+		}
+		count, err := r.entries.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, translateErr(err)
+		}
+		if count > 0 {
+			conflicts = append(conflicts, key)
+		}
+	}
+	return conflicts, nil
+}
+
+// ForEachEntryBatch 按 _id 顺序遍历 entry，每凑满 batchSize 条调用一次 fn，fn 返回错误时中止
+func (r *MongoRepo) ForEachEntryBatch(ctx context.Context, draft *bool, batchSize int, fn func([]model.Entry) error) error {
+	filter := bson.M{}
 	if draft != nil {
 		filter["base.draft"] = *draft
 	}
+	return r.forEachEntryBatch(ctx, filter, batchSize, fn)
+}
+
+// ForEachTermEntryBatch 分批遍历引用了该 term 的 entry（单值或多值字段）。
+// 字段路径取自所有 schema 版本中引用该 taxonomy 的字段，包括嵌套对象和数组元素中的字段
+func (r *MongoRepo) ForEachTermEntryBatch(ctx context.Context, taxonomyKey string, termID primitive.ObjectID, batchSize int, fn func([]model.Entry) error) error {
+	paths, err := r.taxonomyFieldPaths(ctx, taxonomyKey)
+	if err != nil || len(paths) == 0 {
+		return err
+	}
+	return r.forEachEntryBatch(ctx, termRefFilter(paths, termID.Hex()), batchSize, fn)
+}
+
+// termFieldPaths 汇总各 schema 中引用该 taxonomy 的属性路径并去重，路径格式见 model.TaxonomyFieldPaths
+func termFieldPaths(schemas []model.Schema, taxonomyKey string) []string {
+	var paths []string
+	for _, schema := range schemas {
+		for _, path := range model.TaxonomyFieldPaths(schema.Fields, taxonomyKey) {
+			if !slices.Contains(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// termQueryPath 去掉路径中的数组标记，得到查询用的点号路径；Mongo 和 matchPath 都会自动展开途经的数组
+func termQueryPath(path string) string {
+	return strings.ReplaceAll(path, "[]", "")
+}
+
+// taxonomyFieldPaths 从所有 schema 版本中汇总引用该 taxonomy 的属性路径
+func (r *MongoRepo) taxonomyFieldPaths(ctx context.Context, taxonomyKey string) ([]string, error) {
+	cursor, err := r.schemas.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"fields": 1}))
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var schemas []model.Schema
+	if err := cursor.All(ctx, &schemas); err != nil {
+		return nil, translateErr(err)
+	}
+	return termFieldPaths(schemas, taxonomyKey), nil
+}
+
+// termRefFilter 匹配在任一字段路径上（单值或多值）引用了该 term 的 entry；
+// 对途经数组的路径做相等匹配时，包含该值的数组同样命中
+func termRefFilter(paths []string, termID string) bson.M {
+	or := make([]bson.M, len(paths))
+	for i, path := range paths {
+		or[i] = bson.M{"attributes." + termQueryPath(path): termID}
+	}
+	return bson.M{"$or": or}
+}
+
+// updateTermRefs 在每个字段路径上改写对 termID 的引用：multi 为 true 时只处理存为数组的多值字段，
+// 否则处理单值字段（调用方需先处理多值字段）。途经的每层数组用一个 arrayFilters 标识符（$[a0]、$[a1]…），
+// 只选中其下确实引用了该 term 的元素。update 接收改写后的完整更新路径
+func (r *MongoRepo) updateTermRefs(ctx context.Context, paths []string, termID string, multi bool, update func(path string) bson.M) error {
+	leaf := any(termID)
+	if multi {
+		leaf = bson.M{"$elemMatch": bson.M{"$eq": termID}}
+	}
+	for _, path := range paths {
+		segments := strings.Split(path, "[]")
+		target := "attributes." + segments[0]
+		var filters []any
+		for i := 1; i < len(segments); i++ {
+			id := fmt.Sprintf("a%d", i-1)
+			target += ".$[" + id + "]" + segments[i]
+			cond := any(termID)
+			if i == len(segments)-1 {
+				cond = leaf
+			}
+			filters = append(filters, bson.M{id + strings.Join(segments[i:], ""): cond})
+		}
+
+		filter := bson.M{"attributes." + termQueryPath(path): termID}
+		opts := options.Update()
+		if len(filters) == 0 {
+			filter = bson.M{target: leaf}
+		} else {
+			opts.SetArrayFilters(options.ArrayFilters{Filters: filters})
+		}
+		if _, err := r.entries.UpdateMany(ctx, filter, update(target), opts); err != nil {
+			return translateErr(err)
+		}
+	}
+	return nil
+}
+
+func (r *MongoRepo) forEachEntryBatch(ctx context.Context, filter bson.M, batchSize int, fn func([]model.Entry) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(int32(batchSize))
+	cursor, err := r.entries.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	batch := make([]model.Entry, 0, batchSize)
+	for cursor.Next(ctx) {
+		var entry model.Entry
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		batch = append(batch, entry)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = make([]model.Entry, 0, batchSize)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// SearchEntries 基于 MongoDB 文本索引（base.title、body）的全文搜索，按相关度排序；
+// 用于未配置 Meilisearch 或其不可用时
+func (r *MongoRepo) SearchEntries(ctx context.Context, query string, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus, limit, offset int64) ([]model.Entry, int64, error) {
+	filter, err := r.entryFilter(ctx, schemaKeys, termIDs, draft, status)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter["$text"] = bson.M{"$search": query}
+
+	total, err := r.entries.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, translateErr(err)
+	}
+	opts := options.Find().SetLimit(limit).SetSkip(offset).SetSort(bson.D{
+		{Key: "score", Value: bson.M{"$meta": "textScore"}},
+		{Key: "base.created_at", Value: -1},
+	})
+	cursor, err := r.entries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, translateErr(err)
+	}
+	var entries []model.Entry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, translateErr(err)
+	}
+	return entries, total, nil
+}
+
+func (r *MongoRepo) CountEntries(ctx context.Context, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus) (int64, error) {
+	filter, err := r.entryFilter(ctx, schemaKeys, termIDs, draft, status)
+	if err != nil {
+		return 0, err
+	}
 	return r.entries.CountDocuments(ctx, filter)
 }
 
 func (r *MongoRepo) GetEntriesByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Entry, error) {
 	cursor, err := r.entries.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	var entries []model.Entry
 	if err := cursor.All(ctx, &entries); err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 
 	// Preserve order from input IDs (important for search relevance)
@@ -284,7 +929,7 @@ func (r *MongoRepo) CreateUser(ctx context.Context, user *model.User) error {
 	user.CreatedAt = time.Now()
 	result, err := r.users.InsertOne(ctx, user)
 	if err != nil {
-		return err
+		return translateErr(err)
 	}
 	user.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
@@ -294,11 +939,23 @@ func (r *MongoRepo) GetUserByID(ctx context.Context, id primitive.ObjectID) (*mo
 	var user model.User
 	err := r.users.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &user, nil
 }
 
+func (r *MongoRepo) GetUsersByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.User, error) {
+	cursor, err := r.users.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var users []model.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, translateErr(err)
+	}
+	return users, nil
+}
+
 func (r *MongoRepo) GetUserBySocial(ctx context.Context, provider, providerUserID string) (*model.User, error) {
 	var user model.User
 	filter := bson.M{
@@ -311,16 +968,18 @@ func (r *MongoRepo) GetUserBySocial(ctx context.Context, provider, providerUserI
 	}
 	err := r.users.FindOne(ctx, filter).Decode(&user)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &user, nil
 }
 
+// GetUserByEmail 按规范化后的邮箱精确匹配用户；已存储的邮箱在启动时由 backfillNormalizedEmails 统一规范化一次
 func (r *MongoRepo) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	email = utils.NormalizeEmail(email)
 	var user model.User
 	err := r.users.FindOne(ctx, bson.M{"email": email}).Decode(&user)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &user, nil
 }
@@ -329,19 +988,108 @@ func (r *MongoRepo) AddUserSocial(ctx context.Context, userID primitive.ObjectID
 	_, err := r.users.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
 		"$push": bson.M{"socials": social},
 	})
-	return err
-}
-
-func (r *MongoRepo) UpdateUser(ctx context.Context, user *model.User) error {
-	_, err := r.users.ReplaceOne(ctx, bson.M{"_id": user.ID}, user)
-	return err
+	return translateErr(err)
 }
 
-// --- Taxonomy Operations ---
-func (r *MongoRepo) CreateTaxonomy(ctx context.Context, tax *model.Taxonomy) error {
+// RemoveUserSocial 解绑第三方账号；仅在用户仍保留其他登录方式时生效，否则返回 ErrNotFound
+func (r *MongoRepo) RemoveUserSocial(ctx context.Context, userID primitive.ObjectID, provider string) error {
+	filter := bson.M{
+		"_id":              userID,
+		"socials.provider": provider,
+		"$or": []bson.M{
+			{"socials.1": bson.M{"$exists": true}},
+			{"password_hash": bson.M{"$exists": true, "$ne": ""}},
+		},
+	}
+	result, err := r.users.UpdateOne(ctx, filter, bson.M{
+		"$pull": bson.M{"socials": bson.M{"provider": provider}},
+	})
+	if err != nil {
+		return translateErr(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetUserBan 设置或解除封禁；解除时一并清空期限和原因
+func (r *MongoRepo) SetUserBan(ctx context.Context, userID primitive.ObjectID, banned bool, until *time.Time, reason string) error {
+	update := bson.M{"$unset": bson.M{"banned": "", "banned_until": "", "ban_reason": ""}}
+	if banned {
+		set := bson.M{"banned": true, "ban_reason": reason}
+		unset := bson.M{}
+		if until != nil {
+			set["banned_until"] = *until
+		} else {
+			unset["banned_until"] = ""
+		}
+		update = bson.M{"$set": set}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+	}
+	result, err := r.users.UpdateOne(ctx, bson.M{"_id": userID}, update)
+	if err != nil {
+		return translateErr(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteUser 软删除用户：打上 deleted_at 标记，保留文档使其创作的内容仍指向该用户。
+// anonymize 为 true 时同时清除邮箱、昵称、头像、第三方绑定和密码，仅留下墓碑记录。
+// 用户不存在或已注销时返回 ErrNotFound
+func (r *MongoRepo) DeleteUser(ctx context.Context, userID primitive.ObjectID, anonymize bool) error {
+	set := bson.M{"deleted_at": time.Now()}
+	update := bson.M{"$set": set}
+	if anonymize {
+		set["nickname"] = model.DeletedUserNickname
+		set["avatar"] = ""
+		set["socials"] = []model.SocialBind{}
+		update["$unset"] = bson.M{"email": "", "email_verified": "", "password_hash": ""}
+	}
+	result, err := r.users.UpdateOne(ctx, bson.M{"_id": userID, "deleted_at": bson.M{"$exists": false}}, update)
+	if err != nil {
+		return translateErr(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkUserEmailVerified 在用户邮箱仍为 email 时标记为已验证，邮箱已变化时返回 ErrNotFound
+func (r *MongoRepo) MarkUserEmailVerified(ctx context.Context, userID primitive.ObjectID, email string) error {
+	result, err := r.users.UpdateOne(ctx, bson.M{"_id": userID, "email": email}, bson.M{"$set": bson.M{"email_verified": true}})
+	if err != nil {
+		return translateErr(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoRepo) SetUserPassword(ctx context.Context, userID primitive.ObjectID, passwordHash string) error {
+	_, err := r.users.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"password_hash": passwordHash}})
+	return translateErr(err)
+}
+
+func (r *MongoRepo) UpdateUser(ctx context.Context, user *model.User) error {
+	_, err := r.users.ReplaceOne(ctx, bson.M{"_id": user.ID}, user)
+	return translateErr(err)
+}
+
+// --- Taxonomy Operations ---
+func (r *MongoRepo) CreateTaxonomy(ctx context.Context, tax *model.Taxonomy) error {
+	tax.CreatedAt = time.Now()
+	tax.UpdatedAt = tax.CreatedAt
 	result, err := r.taxonomy.InsertOne(ctx, tax)
 	if err != nil {
-		return err
+		return translateErr(err)
 	}
 	tax.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
@@ -351,7 +1099,7 @@ func (r *MongoRepo) GetTaxonomyByKey(ctx context.Context, key string) (*model.Ta
 	var tax model.Taxonomy
 	err := r.taxonomy.FindOne(ctx, bson.M{"key": key}).Decode(&tax)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &tax, nil
 }
@@ -359,30 +1107,34 @@ func (r *MongoRepo) GetTaxonomyByKey(ctx context.Context, key string) (*model.Ta
 func (r *MongoRepo) ListTaxonomies(ctx context.Context) ([]model.Taxonomy, error) {
 	cursor, err := r.taxonomy.Find(ctx, bson.M{})
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	var taxonomies []model.Taxonomy
 	if err := cursor.All(ctx, &taxonomies); err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return taxonomies, nil
 }
 
 func (r *MongoRepo) UpdateTaxonomy(ctx context.Context, tax *model.Taxonomy) error {
+	tax.UpdatedAt = time.Now()
 	_, err := r.taxonomy.ReplaceOne(ctx, bson.M{"_id": tax.ID}, tax)
-	return err
+	return translateErr(err)
 }
 
 func (r *MongoRepo) DeleteTaxonomy(ctx context.Context, key string) error {
 	_, err := r.taxonomy.DeleteOne(ctx, bson.M{"key": key})
-	return err
+	return translateErr(err)
 }
 
 // --- Term Operations ---
 func (r *MongoRepo) CreateTerm(ctx context.Context, term *model.Term) error {
+	term.SlugNormalized = utils.NormalizeSlug(term.Slug)
+	term.CreatedAt = time.Now()
+	term.UpdatedAt = term.CreatedAt
 	result, err := r.terms.InsertOne(ctx, term)
 	if err != nil {
-		return err
+		return translateErr(err)
 	}
 	term.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
@@ -392,70 +1144,292 @@ func (r *MongoRepo) GetTermByID(ctx context.Context, id primitive.ObjectID) (*mo
 	var term model.Term
 	err := r.terms.FindOne(ctx, bson.M{"_id": id}).Decode(&term)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &term, nil
 }
 
+func (r *MongoRepo) GetTermsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Term, error) {
+	cursor, err := r.terms.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var terms []model.Term
+	if err := cursor.All(ctx, &terms); err != nil {
+		return nil, translateErr(err)
+	}
+	return terms, nil
+}
+
 func (r *MongoRepo) GetTermsByTaxonomy(ctx context.Context, taxonomyKey string) ([]model.Term, error) {
-	cursor, err := r.terms.Find(ctx, bson.M{"taxonomy_key": taxonomyKey})
+	opts := options.Find().SetSort(bson.D{{Key: "order", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := r.terms.Find(ctx, bson.M{"taxonomy_key": taxonomyKey}, opts)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	var terms []model.Term
 	if err := cursor.All(ctx, &terms); err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return terms, nil
 }
 
 func (r *MongoRepo) GetTermBySlug(ctx context.Context, taxonomyKey, slug string) (*model.Term, error) {
 	var term model.Term
-	err := r.terms.FindOne(ctx, bson.M{"taxonomy_key": taxonomyKey, "slug": slug}).Decode(&term)
+	err := r.terms.FindOne(ctx, bson.M{"taxonomy_key": taxonomyKey, "slug_normalized": utils.NormalizeSlug(slug)}).Decode(&term)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &term, nil
 }
 
+// ReorderTerms 按 termIDs 的顺序一次性批量写入 order
+func (r *MongoRepo) ReorderTerms(ctx context.Context, taxonomyKey string, termIDs []primitive.ObjectID) error {
+	if len(termIDs) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(termIDs))
+	for i, id := range termIDs {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id, "taxonomy_key": taxonomyKey}).
+			SetUpdate(bson.M{"$set": bson.M{"order": i}}))
+	}
+	_, err := r.terms.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return translateErr(err)
+}
+
+func (r *MongoRepo) CountTermsInTaxonomy(ctx context.Context, taxonomyKey string, termIDs []primitive.ObjectID) (int64, error) {
+	return r.terms.CountDocuments(ctx, bson.M{"taxonomy_key": taxonomyKey, "_id": bson.M{"$in": termIDs}})
+}
+
 func (r *MongoRepo) UpdateTerm(ctx context.Context, term *model.Term) error {
+	term.SlugNormalized = utils.NormalizeSlug(term.Slug)
+	term.UpdatedAt = time.Now()
 	_, err := r.terms.ReplaceOne(ctx, bson.M{"_id": term.ID}, term)
-	return err
+	return translateErr(err)
 }
 
 func (r *MongoRepo) DeleteTerm(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.terms.DeleteOne(ctx, bson.M{"_id": id})
-	return err
+	return translateErr(err)
+}
+
+// GetTermAncestors 沿 parent_id 向上查找所有祖先，按由近到远排序
+// $graphLookup 会自动处理已存在的环，不会无限递归
+func (r *MongoRepo) GetTermAncestors(ctx context.Context, id primitive.ObjectID) ([]model.Term, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": id}}},
+		{{Key: "$graphLookup", Value: bson.D{
+			{Key: "from", Value: r.terms.Name()},
+			{Key: "startWith", Value: "$parent_id"},
+			{Key: "connectFromField", Value: "parent_id"},
+			{Key: "connectToField", Value: "_id"},
+			{Key: "as", Value: "ancestors"},
+			{Key: "depthField", Value: "depth"},
+		}}},
+		{{Key: "$unwind", Value: "$ancestors"}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$ancestors"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "depth", Value: 1}}}},
+	}
+	cursor, err := r.terms.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var ancestors []model.Term
+	if err := cursor.All(ctx, &ancestors); err != nil {
+		return nil, translateErr(err)
+	}
+	return ancestors, nil
 }
 
 func (r *MongoRepo) HasChildTerms(ctx context.Context, parentID primitive.ObjectID) (bool, error) {
-	count, err := r.terms.CountDocuments(ctx, bson.M{"parent_id": parentID})
+	count, err := r.CountChildTerms(ctx, parentID)
 	if err != nil {
-		return false, err
+		return false, translateErr(err)
 	}
 	return count > 0, nil
 }
 
+func (r *MongoRepo) CountChildTerms(ctx context.Context, parentID primitive.ObjectID) (int64, error) {
+	return r.terms.CountDocuments(ctx, bson.M{"parent_id": parentID})
+}
+
 func (r *MongoRepo) HasTermReferences(ctx context.Context, taxonomyKey string, termID primitive.ObjectID) (bool, error) {
-	// Check if any entry's attributes contain this term ID
-	// This searches in attributes where taxonomy fields store term IDs
-	termIDStr := termID.Hex()
-	filter := bson.M{
-		"$or": []bson.M{
-			{"attributes." + taxonomyKey: termIDStr},
-			{"attributes." + taxonomyKey: bson.M{"$in": []string{termIDStr}}},
-		},
-	}
-	count, err := r.entries.CountDocuments(ctx, filter)
+	count, err := r.CountTermReferences(ctx, taxonomyKey, termID)
 	if err != nil {
-		return false, err
+		return false, translateErr(err)
 	}
 	return count > 0, nil
 }
 
+// CountTermReferences 统计在任一引用该 taxonomy 的字段（含嵌套对象和数组元素）中使用了该 term 的 entry 数
+func (r *MongoRepo) CountTermReferences(ctx context.Context, taxonomyKey string, termID primitive.ObjectID) (int64, error) {
+	paths, err := r.taxonomyFieldPaths(ctx, taxonomyKey)
+	if err != nil || len(paths) == 0 {
+		return 0, err
+	}
+	return r.entries.CountDocuments(ctx, termRefFilter(paths, termID.Hex()))
+}
+
+// RemoveTermReferences 从 entry attributes 中移除对该 term 的引用（含嵌套对象和数组元素中的字段），
+// 返回受影响的 entry 数
+func (r *MongoRepo) RemoveTermReferences(ctx context.Context, taxonomyKey string, termID primitive.ObjectID) (int64, error) {
+	paths, err := r.taxonomyFieldPaths(ctx, taxonomyKey)
+	if err != nil || len(paths) == 0 {
+		return 0, err
+	}
+	id := termID.Hex()
+	affected, err := r.entries.CountDocuments(ctx, termRefFilter(paths, id))
+	if err != nil || affected == 0 {
+		return 0, translateErr(err)
+	}
+
+	// Multi-value fields: pull the ID out of the array
+	if err := r.updateTermRefs(ctx, paths, id, true, func(path string) bson.M {
+		return bson.M{"$pull": bson.M{path: id}}
+	}); err != nil {
+		return 0, err
+	}
+	// Single-value fields: unset the attribute
+	if err := r.updateTermRefs(ctx, paths, id, false, func(path string) bson.M {
+		return bson.M{"$unset": bson.M{path: ""}}
+	}); err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// CountTermUsage 通过一次聚合统计分类下每个 term 被多少个 entry 引用，
+// 覆盖各 schema 中引用该 taxonomy 的所有字段路径，包括嵌套对象和数组元素中的字段
+func (r *MongoRepo) CountTermUsage(ctx context.Context, taxonomyKey string) (map[string]int64, error) {
+	paths, err := r.taxonomyFieldPaths(ctx, taxonomyKey)
+	if err != nil || len(paths) == 0 {
+		return map[string]int64{}, err
+	}
+	exists := make([]bson.M, len(paths))
+	values := make(bson.A, len(paths))
+	depth := 0
+	for i, path := range paths {
+		field := "attributes." + termQueryPath(path)
+		exists[i] = bson.M{field: bson.M{"$exists": true}}
+		values[i] = "$" + field
+		depth = max(depth, strings.Count(path, "[]"))
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$or": exists}}},
+		{{Key: "$project", Value: bson.D{{Key: "term", Value: values}}}},
+	}
+	// 一层展开 paths，再按途经数组的层数和多值字段逐层展开；$unwind 把标量视为单元素数组，缺失的值被丢弃
+	for range depth + 2 {
+		pipeline = append(pipeline, bson.D{{Key: "$unwind", Value: "$term"}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$match", Value: bson.M{"term": bson.M{"$type": "string"}}}},
+		// 同一 entry 在多个字段中引用同一 term 只计一次
+		bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: bson.D{{Key: "entry", Value: "$_id"}, {Key: "term", Value: "$term"}}}}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$_id.term"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	)
+	cursor, err := r.entries.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var rows []struct {
+		ID    any   `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, translateErr(err)
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		if id, ok := row.ID.(string); ok {
+			counts[id] = row.Count
+		}
+	}
+	return counts, nil
+}
+
+// MergeTerms 在事务中将 source 的所有引用（含嵌套对象和数组元素中的字段）改写为 target，
+// 转移子节点后删除 source。返回被改写的 entry 数量。需要 MongoDB 副本集以支持事务
+func (r *MongoRepo) MergeTerms(ctx context.Context, source, target *model.Term) (int64, error) {
+	paths, err := r.taxonomyFieldPaths(ctx, source.TaxonomyKey)
+	if err != nil {
+		return 0, err
+	}
+
+	session, err := r.client.StartSession()
+	if err != nil {
+		return 0, translateErr(err)
+	}
+	defer session.EndSession(ctx)
+
+	sourceID := source.ID.Hex()
+	targetID := target.ID.Hex()
+
+	now := time.Now()
+	result, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		var rewritten int64
+		if len(paths) > 0 {
+			count, err := r.entries.CountDocuments(sc, termRefFilter(paths, sourceID))
+			if err != nil {
+				return nil, translateErr(err)
+			}
+			rewritten = count
+
+			// Multi-value fields: add target (deduplicated) then drop source
+			if err := r.updateTermRefs(sc, paths, sourceID, true, func(path string) bson.M {
+				return bson.M{"$addToSet": bson.M{path: targetID}}
+			}); err != nil {
+				return nil, err
+			}
+			if err := r.updateTermRefs(sc, paths, sourceID, true, func(path string) bson.M {
+				return bson.M{"$pull": bson.M{path: sourceID}}
+			}); err != nil {
+				return nil, err
+			}
+			// Single-value fields
+			if err := r.updateTermRefs(sc, paths, sourceID, false, func(path string) bson.M {
+				return bson.M{"$set": bson.M{path: targetID}}
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		// Re-parent children of the source onto the target
+		if _, err := r.terms.UpdateMany(sc,
+			bson.M{"parent_id": source.ID, "_id": bson.M{"$ne": target.ID}},
+			bson.M{"$set": bson.M{"parent_id": target.ID, "updated_at": now}},
+		); err != nil {
+			return nil, translateErr(err)
+		}
+		// Target directly under source takes over the source's position
+		if target.ParentID == source.ID {
+			update := bson.M{"$unset": bson.M{"parent_id": ""}, "$set": bson.M{"updated_at": now}}
+			if !source.ParentID.IsZero() {
+				update = bson.M{"$set": bson.M{"parent_id": source.ParentID, "updated_at": now}}
+			}
+			if _, err := r.terms.UpdateOne(sc, bson.M{"_id": target.ID}, update); err != nil {
+				return nil, translateErr(err)
+			}
+		}
+
+		if _, err := r.terms.DeleteOne(sc, bson.M{"_id": source.ID}); err != nil {
+			return nil, translateErr(err)
+		}
+		return rewritten, nil
+	})
+	if err != nil {
+		return 0, translateErr(err)
+	}
+	return result.(int64), nil
+}
+
 func (r *MongoRepo) DeleteTermsByTaxonomy(ctx context.Context, taxonomyKey string) error {
 	_, err := r.terms.DeleteMany(ctx, bson.M{"taxonomy_key": taxonomyKey})
-	return err
+	return translateErr(err)
 }
 
 // --- Comment Operations ---
@@ -463,7 +1437,7 @@ func (r *MongoRepo) CreateComment(ctx context.Context, comment *model.Comment) e
 	comment.CreatedAt = time.Now()
 	result, err := r.comments.InsertOne(ctx, comment)
 	if err != nil {
-		return err
+		return translateErr(err)
 	}
 	comment.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
@@ -473,7 +1447,7 @@ func (r *MongoRepo) GetCommentByID(ctx context.Context, id primitive.ObjectID) (
 	var comment model.Comment
 	err := r.comments.FindOne(ctx, bson.M{"_id": id}).Decode(&comment)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &comment, nil
 }
@@ -481,68 +1455,328 @@ func (r *MongoRepo) GetCommentByID(ctx context.Context, id primitive.ObjectID) (
 func (r *MongoRepo) GetCommentsByEntry(ctx context.Context, entryID primitive.ObjectID) ([]model.Comment, error) {
 	cursor, err := r.comments.Find(ctx, bson.M{"entry_id": entryID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	var comments []model.Comment
 	if err := cursor.All(ctx, &comments); err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return comments, nil
 }
 
-func (r *MongoRepo) GetCommentsByEntryPaginated(ctx context.Context, entryID primitive.ObjectID, limit, offset int64) ([]model.CommentWithAuthor, error) {
+// GetCommentsByEntryPaginated 分页返回 entry 的顶层评论，附带作者信息和回复数
+// approvedOnly 为 true 时仅返回（并统计）已审核通过的评论。reply_count 在查询时统计，
+// 回复是物理删除的，因此删除回复后计数始终准确
+func (r *MongoRepo) GetCommentsByEntryPaginated(ctx context.Context, entryID primitive.ObjectID, approvedOnly bool, limit, offset int64) ([]model.CommentWithAuthor, error) {
+	filter := bson.M{"entry_id": entryID, "root_id": rootCommentFilter()}
+	replyFilter := bson.M{}
+	if approvedOnly {
+		filter["status"] = approvedStatusFilter()
+		replyFilter["status"] = approvedStatusFilter()
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}}}},
+		{{Key: "$skip", Value: offset}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "comments"},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "root_id"},
+			{Key: "pipeline", Value: mongo.Pipeline{
+				{{Key: "$match", Value: replyFilter}},
+				{{Key: "$count", Value: "n"}},
+			}},
+			{Key: "as", Value: "reply_stats"},
+		}}},
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "reply_count", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+				bson.D{{Key: "$first", Value: "$reply_stats.n"}}, 0,
+			}}}},
+		}}},
+		{{Key: "$project", Value: bson.D{{Key: "reply_stats", Value: 0}}}},
+	}
+	pipeline = append(pipeline, commentAuthorLookup()...)
+	return r.aggregateComments(ctx, pipeline)
+}
+
+// GetRepliesByRoot 分页返回某条顶层评论下的回复，附带作者信息
+func (r *MongoRepo) GetRepliesByRoot(ctx context.Context, rootID primitive.ObjectID, approvedOnly bool, limit, offset int64) ([]model.CommentWithAuthor, error) {
+	filter := bson.M{"root_id": rootID}
+	if approvedOnly {
+		filter["status"] = approvedStatusFilter()
+	}
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"entry_id": entryID}}},
+		{{Key: "$match", Value: filter}},
 		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}}}},
 		{{Key: "$skip", Value: offset}},
 		{{Key: "$limit", Value: limit}},
+	}
+	pipeline = append(pipeline, commentAuthorLookup()...)
+	return r.aggregateComments(ctx, pipeline)
+}
+
+// HasThreadCommentByAuthor 检查线程（根评论及其回复）中是否有该用户发表的评论
+func (r *MongoRepo) HasThreadCommentByAuthor(ctx context.Context, rootID primitive.ObjectID, authorID string) (bool, error) {
+	count, err := r.comments.CountDocuments(ctx, bson.M{
+		"author_id": authorID,
+		"$or":       []bson.M{{"_id": rootID}, {"root_id": rootID}},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, translateErr(err)
+	}
+	return count > 0, nil
+}
+
+func (r *MongoRepo) CountRepliesByRoot(ctx context.Context, rootID primitive.ObjectID, approvedOnly bool) (int64, error) {
+	filter := bson.M{"root_id": rootID}
+	if approvedOnly {
+		filter["status"] = approvedStatusFilter()
+	}
+	return r.comments.CountDocuments(ctx, filter)
+}
+
+// GetPendingComments 分页返回待审核的评论，按创建时间排序
+func (r *MongoRepo) GetPendingComments(ctx context.Context, limit, offset int64) ([]model.CommentWithAuthor, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": model.CommentPending}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}}}},
+		{{Key: "$skip", Value: offset}},
+		{{Key: "$limit", Value: limit}},
+	}
+	pipeline = append(pipeline, commentAuthorLookup()...)
+	return r.aggregateComments(ctx, pipeline)
+}
+
+func (r *MongoRepo) CountPendingComments(ctx context.Context) (int64, error) {
+	return r.comments.CountDocuments(ctx, bson.M{"status": model.CommentPending})
+}
+
+func (r *MongoRepo) UpdateCommentStatus(ctx context.Context, id primitive.ObjectID, status model.CommentStatus) error {
+	_, err := r.comments.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"status": status}})
+	return translateErr(err)
+}
+
+// rootCommentFilter 匹配顶层评论：root_id 不存在、为 null 或为零值 ObjectID
+func rootCommentFilter() bson.M {
+	return bson.M{"$in": bson.A{nil, primitive.NilObjectID}}
+}
+
+// approvedStatusFilter 匹配已通过的评论；启用审核前的旧评论没有 status 字段，视为已通过
+func approvedStatusFilter() bson.M {
+	return bson.M{"$nin": []model.CommentStatus{model.CommentPending, model.CommentRejected}}
+}
+
+func (r *MongoRepo) aggregateComments(ctx context.Context, pipeline mongo.Pipeline) ([]model.CommentWithAuthor, error) {
+	cursor, err := r.comments.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var comments []model.CommentWithAuthor
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// commentAuthorLookup 关联 users 集合，填充评论作者和被回复用户（reply_to_uid）的公开信息
+func commentAuthorLookup() mongo.Pipeline {
+	return append(
+		userPublicLookup("$author_id", "author"),
+		userPublicLookup("$reply_to_uid", "reply_to")...,
+	)
+}
+
+// userPublicLookup 按字符串形式的用户 ID 关联 users，结果写入 as 字段；
+// ID 为空、非法或用户不存在时该字段缺省
+func userPublicLookup(idField, as string) mongo.Pipeline {
+	return mongo.Pipeline{
 		{{Key: "$lookup", Value: bson.D{
 			{Key: "from", Value: "users"},
-			{Key: "let", Value: bson.D{{Key: "authorId", Value: bson.D{{Key: "$toObjectId", Value: "$author_id"}}}}},
+			{Key: "let", Value: bson.D{{Key: "userId", Value: bson.D{{Key: "$convert", Value: bson.D{
+				{Key: "input", Value: idField},
+				{Key: "to", Value: "objectId"},
+				{Key: "onError", Value: nil},
+				{Key: "onNull", Value: nil},
+			}}}}}},
 			{Key: "pipeline", Value: mongo.Pipeline{
-				{{Key: "$match", Value: bson.D{{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$_id", "$$authorId"}}}}}}},
+				{{Key: "$match", Value: bson.D{{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$_id", "$$userId"}}}}}}},
 				{{Key: "$project", Value: bson.D{
 					{Key: "_id", Value: 1},
 					{Key: "nickname", Value: 1},
 					{Key: "avatar", Value: 1},
 				}}},
 			}},
-			{Key: "as", Value: "author"},
+			{Key: "as", Value: as},
 		}}},
 		{{Key: "$unwind", Value: bson.D{
-			{Key: "path", Value: "$author"},
+			{Key: "path", Value: "$" + as},
 			{Key: "preserveNullAndEmptyArrays", Value: true},
 		}}},
 	}
+}
+
+func (r *MongoRepo) CountCommentsByEntry(ctx context.Context, entryID primitive.ObjectID) (int64, error) {
+	return r.comments.CountDocuments(ctx, bson.M{"entry_id": entryID})
+}
 
+// CountCommentsByEntries 通过一次聚合统计多篇 entry 的公开评论数（不含待审核/已拒绝）
+func (r *MongoRepo) CountCommentsByEntries(ctx context.Context, entryIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	counts := make(map[primitive.ObjectID]int64, len(entryIDs))
+	if len(entryIDs) == 0 {
+		return counts, nil
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"entry_id": bson.M{"$in": entryIDs},
+			"status":   approvedStatusFilter(),
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$entry_id"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
 	cursor, err := r.comments.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
-	var comments []model.CommentWithAuthor
-	if err := cursor.All(ctx, &comments); err != nil {
-		return nil, err
+	var rows []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
 	}
-	return comments, nil
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, translateErr(err)
+	}
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	return counts, nil
 }
 
-func (r *MongoRepo) CountCommentsByEntry(ctx context.Context, entryID primitive.ObjectID) (int64, error) {
-	return r.comments.CountDocuments(ctx, bson.M{"entry_id": entryID})
+func (r *MongoRepo) CountRootCommentsByEntry(ctx context.Context, entryID primitive.ObjectID, approvedOnly bool) (int64, error) {
+	filter := bson.M{"entry_id": entryID, "root_id": rootCommentFilter()}
+	if approvedOnly {
+		filter["status"] = approvedStatusFilter()
+	}
+	return r.comments.CountDocuments(ctx, filter)
 }
 
 func (r *MongoRepo) DeleteComment(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.comments.DeleteOne(ctx, bson.M{"_id": id})
-	return err
+	return translateErr(err)
+}
+
+// --- Comment Vote Operations ---
+
+// LikeComment 记录点赞并递增计数，重复点赞由唯一索引拦截，返回是否新增
+func (r *MongoRepo) LikeComment(ctx context.Context, commentID primitive.ObjectID, userID string) (bool, error) {
+	_, err := r.votes.InsertOne(ctx, model.CommentVote{
+		CommentID: commentID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, translateErr(err)
+	}
+	_, err = r.comments.UpdateOne(ctx, bson.M{"_id": commentID}, bson.M{"$inc": bson.M{"like_count": 1}})
+	return true, translateErr(err)
+}
+
+// UnlikeComment 取消点赞并递减计数，返回是否实际删除了记录
+func (r *MongoRepo) UnlikeComment(ctx context.Context, commentID primitive.ObjectID, userID string) (bool, error) {
+	result, err := r.votes.DeleteOne(ctx, bson.M{"comment_id": commentID, "user_id": userID})
+	if err != nil {
+		return false, translateErr(err)
+	}
+	if result.DeletedCount == 0 {
+		return false, nil
+	}
+	_, err = r.comments.UpdateOne(ctx,
+		bson.M{"_id": commentID, "like_count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"like_count": -1}},
+	)
+	return true, translateErr(err)
 }
 
+// GetLikedCommentIDs 返回 commentIDs 中被该用户点赞过的集合
+func (r *MongoRepo) GetLikedCommentIDs(ctx context.Context, userID string, commentIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	liked := make(map[primitive.ObjectID]bool)
+	if len(commentIDs) == 0 {
+		return liked, nil
+	}
+	cursor, err := r.votes.Find(ctx, bson.M{"user_id": userID, "comment_id": bson.M{"$in": commentIDs}})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var votes []model.CommentVote
+	if err := cursor.All(ctx, &votes); err != nil {
+		return nil, translateErr(err)
+	}
+	for _, v := range votes {
+		liked[v.CommentID] = true
+	}
+	return liked, nil
+}
+
+// --- Comment Report Operations ---
+
+// CreateCommentReport 记录举报，同一用户重复举报同一评论时返回 false
+func (r *MongoRepo) CreateCommentReport(ctx context.Context, report *model.CommentReport) (bool, error) {
+	report.CreatedAt = time.Now()
+	result, err := r.reports.InsertOne(ctx, report)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, translateErr(err)
+	}
+	report.ID = result.InsertedID.(primitive.ObjectID)
+	return true, nil
+}
+
+// GetReportedComments 返回举报数不少于 minReports 的评论，按举报数降序
+func (r *MongoRepo) GetReportedComments(ctx context.Context, minReports, limit, offset int64) ([]model.ReportedComment, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$comment_id"},
+			{Key: "report_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"report_count": bson.M{"$gte": minReports}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "report_count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$skip", Value: offset}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "comments"},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "comment"},
+		}}},
+		// Reports of since-deleted comments are dropped here
+		{{Key: "$unwind", Value: "$comment"}},
+	}
+	cursor, err := r.reports.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var reported []model.ReportedComment
+	if err := cursor.All(ctx, &reported); err != nil {
+		return nil, translateErr(err)
+	}
+	return reported, nil
+}
+
+// IsTermSlugExists 按规范化后的 slug 比较，"JavaScript" 与 "javascript" 视为重复
 func (r *MongoRepo) IsTermSlugExists(ctx context.Context, taxonomyKey, slug string, excludeID primitive.ObjectID) (bool, error) {
-	filter := bson.M{"taxonomy_key": taxonomyKey, "slug": slug}
+	filter := bson.M{"taxonomy_key": taxonomyKey, "slug_normalized": utils.NormalizeSlug(slug)}
 	if !excludeID.IsZero() {
 		filter["_id"] = bson.M{"$ne": excludeID}
 	}
 	count, err := r.terms.CountDocuments(ctx, filter)
 	if err != nil {
-		return false, err
+		return false, translateErr(err)
 	}
 	return count > 0, nil
 }
@@ -550,12 +1784,22 @@ func (r *MongoRepo) IsTermSlugExists(ctx context.Context, taxonomyKey, slug stri
 func (r *MongoRepo) UpdateComment(ctx context.Context, comment *model.Comment) error {
 	comment.UpdatedAt = time.Now()
 	_, err := r.comments.ReplaceOne(ctx, bson.M{"_id": comment.ID}, comment)
-	return err
+	return translateErr(err)
+}
+
+// SoftDeleteComment 清空评论内容并打上删除标记，保留其回复
+func (r *MongoRepo) SoftDeleteComment(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.comments.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"content":    model.DeletedCommentContent,
+		"deleted":    true,
+		"updated_at": time.Now(),
+	}})
+	return translateErr(err)
 }
 
 func (r *MongoRepo) DeleteCommentsByRootID(ctx context.Context, rootID primitive.ObjectID) error {
 	_, err := r.comments.DeleteMany(ctx, bson.M{"root_id": rootID})
-	return err
+	return translateErr(err)
 }
 
 // --- User Update ---
@@ -571,15 +1815,80 @@ func (r *MongoRepo) UpdateUserProfile(ctx context.Context, userID primitive.Obje
 		return nil
 	}
 	_, err := r.users.UpdateOne(ctx, bson.M{"_id": userID}, update)
-	return err
+	return translateErr(err)
+}
+
+// --- Statistics ---
+
+// GetAdminStats 汇总各集合的数量，供管理后台概览使用
+func (r *MongoRepo) GetAdminStats(ctx context.Context) (*model.AdminStats, error) {
+	stats := &model.AdminStats{
+		TermsByTaxonomy: make(map[string]int64),
+		GeneratedAt:     time.Now(),
+	}
+
+	cursor, err := r.entries.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$schema_key"},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "drafts", Value: bson.D{{Key: "$sum", Value: bson.D{
+				{Key: "$cond", Value: bson.A{"$base.draft", 1, 0}},
+			}}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	if err := cursor.All(ctx, &stats.Entries.BySchema); err != nil {
+		return nil, translateErr(err)
+	}
+	if stats.Entries.BySchema == nil {
+		stats.Entries.BySchema = []model.SchemaEntryStats{}
+	}
+	for _, s := range stats.Entries.BySchema {
+		stats.Entries.Total += s.Total
+		stats.Entries.Drafts += s.Drafts
+	}
+	stats.Entries.Published = stats.Entries.Total - stats.Entries.Drafts
+
+	if stats.Users, err = r.users.CountDocuments(ctx, bson.M{"deleted_at": bson.M{"$exists": false}}); err != nil {
+		return nil, translateErr(err)
+	}
+	if stats.Comments, err = r.comments.CountDocuments(ctx, bson.M{}); err != nil {
+		return nil, translateErr(err)
+	}
+
+	cursor, err = r.terms.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$taxonomy_key"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var termCounts []struct {
+		TaxonomyKey string `bson:"_id"`
+		Count       int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &termCounts); err != nil {
+		return nil, translateErr(err)
+	}
+	for _, tc := range termCounts {
+		stats.TermsByTaxonomy[tc.TaxonomyKey] = tc.Count
+	}
+
+	return stats, nil
 }
 
 // --- Session Operations ---
 func (r *MongoRepo) CreateSession(ctx context.Context, session *model.Session) error {
 	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
 	result, err := r.sessions.InsertOne(ctx, session)
 	if err != nil {
-		return err
+		return translateErr(err)
 	}
 	session.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
@@ -592,19 +1901,81 @@ func (r *MongoRepo) GetSessionByToken(ctx context.Context, token string) (*model
 		"expires_at": bson.M{"$gt": time.Now()},
 	}).Decode(&session)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &session, nil
 }
 
+// TakeSession 原子地取出并删除一个未过期的 session，保证同一 token 只能被轮换一次
+func (r *MongoRepo) TakeSession(ctx context.Context, token string) (*model.Session, error) {
+	var session model.Session
+	err := r.sessions.FindOneAndDelete(ctx, bson.M{
+		"token":      token,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&session)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	return &session, nil
+}
+
+func (r *MongoRepo) TouchSession(ctx context.Context, id primitive.ObjectID, seenAt time.Time) error {
+	_, err := r.sessions.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_seen_at": seenAt}})
+	return translateErr(err)
+}
+
 func (r *MongoRepo) DeleteSession(ctx context.Context, token string) error {
 	_, err := r.sessions.DeleteOne(ctx, bson.M{"token": token})
-	return err
+	return translateErr(err)
 }
 
-func (r *MongoRepo) DeleteExpiredSessions(ctx context.Context) error {
-	_, err := r.sessions.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": time.Now()}})
-	return err
+func (r *MongoRepo) GetSessionsByUser(ctx context.Context, userID primitive.ObjectID) ([]model.Session, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.sessions.Find(ctx, bson.M{
+		"user_id":    userID,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, opts)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var sessions []model.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, translateErr(err)
+	}
+	return sessions, nil
+}
+
+// DeleteUserSession 删除属于该用户的指定 session，返回是否删除成功
+func (r *MongoRepo) DeleteUserSession(ctx context.Context, userID, sessionID primitive.ObjectID) (bool, error) {
+	result, err := r.sessions.DeleteOne(ctx, bson.M{"_id": sessionID, "user_id": userID})
+	if err != nil {
+		return false, translateErr(err)
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// DeleteUserSessionsExcept 删除该用户除 keepToken 外的所有 session，返回删除数量
+func (r *MongoRepo) DeleteUserSessionsExcept(ctx context.Context, userID primitive.ObjectID, keepToken string) (int64, error) {
+	result, err := r.sessions.DeleteMany(ctx, bson.M{"user_id": userID, "token": bson.M{"$ne": keepToken}})
+	if err != nil {
+		return 0, translateErr(err)
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteUserSessions 删除该用户的所有 session
+func (r *MongoRepo) DeleteUserSessions(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.sessions.DeleteMany(ctx, bson.M{"user_id": userID})
+	return translateErr(err)
+}
+
+// DeleteExpiredSessions 清理已过期的 session，返回删除数量
+func (r *MongoRepo) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := r.sessions.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": time.Now()}})
+	if err != nil {
+		return 0, translateErr(err)
+	}
+	return result.DeletedCount, nil
 }
 
 // --- OAuth State Operations ---
@@ -612,7 +1983,7 @@ func (r *MongoRepo) CreateOAuthState(ctx context.Context, state *model.OAuthStat
 	state.CreatedAt = time.Now()
 	result, err := r.oauthStates.InsertOne(ctx, state)
 	if err != nil {
-		return err
+		return translateErr(err)
 	}
 	state.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
@@ -622,7 +1993,93 @@ func (r *MongoRepo) GetAndDeleteOAuthState(ctx context.Context, state string) (*
 	var oauthState model.OAuthState
 	err := r.oauthStates.FindOneAndDelete(ctx, bson.M{"state": state}).Decode(&oauthState)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return &oauthState, nil
 }
+
+// DeleteExpiredOAuthStates 清理已过期的 state，返回删除数量
+func (r *MongoRepo) DeleteExpiredOAuthStates(ctx context.Context) (int64, error) {
+	result, err := r.oauthStates.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": time.Now()}})
+	if err != nil {
+		return 0, translateErr(err)
+	}
+	return result.DeletedCount, nil
+}
+
+// --- API Key Operations ---
+func (r *MongoRepo) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	key.CreatedAt = time.Now()
+	result, err := r.apiKeys.InsertOne(ctx, key)
+	if err != nil {
+		return translateErr(err)
+	}
+	key.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *MongoRepo) GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.apiKeys.FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&key)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	return &key, nil
+}
+
+func (r *MongoRepo) GetAPIKeysByUser(ctx context.Context, userID primitive.ObjectID) ([]model.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.apiKeys.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	var keys []model.APIKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, translateErr(err)
+	}
+	return keys, nil
+}
+
+func (r *MongoRepo) TouchAPIKey(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	_, err := r.apiKeys.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	return translateErr(err)
+}
+
+// DeleteUserAPIKeys 删除该用户的所有 API Key
+func (r *MongoRepo) DeleteUserAPIKeys(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.apiKeys.DeleteMany(ctx, bson.M{"user_id": userID})
+	return translateErr(err)
+}
+
+func (r *MongoRepo) DeleteAPIKey(ctx context.Context, userID, keyID primitive.ObjectID) (bool, error) {
+	result, err := r.apiKeys.DeleteOne(ctx, bson.M{"_id": keyID, "user_id": userID})
+	if err != nil {
+		return false, translateErr(err)
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// --- Media Operations ---
+func (r *MongoRepo) CreateMedia(ctx context.Context, media *model.Media) error {
+	media.CreatedAt = time.Now()
+	result, err := r.media.InsertOne(ctx, media)
+	if err != nil {
+		return translateErr(err)
+	}
+	media.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *MongoRepo) GetMediaByID(ctx context.Context, id primitive.ObjectID) (*model.Media, error) {
+	var media model.Media
+	err := r.media.FindOne(ctx, bson.M{"_id": id}).Decode(&media)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	return &media, nil
+}
+
+func (r *MongoRepo) DeleteMedia(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.media.DeleteOne(ctx, bson.M{"_id": id})
+	return translateErr(err)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"matter-core/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Repository 是 handler 和 service 依赖的存储接口，生产环境使用 MongoRepo，
+// 测试可使用 MemoryRepo 而无需启动 MongoDB。未找到时返回 ErrNotFound，违反唯一约束时返回 ErrConflict
+type Repository interface {
+	Ping(ctx context.Context) error
+	Close(ctx context.Context) error
+
+	// Schema
+	CreateSchema(ctx context.Context, schema *model.Schema) error
+	GetLatestSchema(ctx context.Context, key string) (*model.Schema, error)
+	GetSchemaByID(ctx context.Context, id primitive.ObjectID) (*model.Schema, error)
+	DeleteSchemasByKey(ctx context.Context, key string) error
+	ListSchemas(ctx context.Context) ([]model.Schema, error)
+
+	// Entry
+	CreateEntry(ctx context.Context, entry *model.Entry) error
+	CreateEntries(ctx context.Context, entries []*model.Entry) error
+	UpdateEntry(ctx context.Context, entry *model.Entry) error
+	DeleteEntry(ctx context.Context, id primitive.ObjectID) error
+	GetEntryByID(ctx context.Context, id primitive.ObjectID) (*model.Entry, error)
+	GetEntryBySlug(ctx context.Context, schemaKey, slug string) (*model.Entry, error)
+	ListEntries(ctx context.Context, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus, limit, offset int64) ([]model.Entry, error)
+	ListEntriesByAuthor(ctx context.Context, authorID string, schemaKeys []string, draft *bool, status model.EntryStatus, oldestFirst bool, limit, offset int64) ([]model.Entry, int64, error)
+	TransferEntries(ctx context.Context, fromAuthorID, toAuthorID, operatorID string) (int64, error)
+	SyncUniqueFieldIndexes(ctx context.Context, schema *model.Schema) error
+	FindUniqueConflicts(ctx context.Context, schemaKey string, values map[string]any, excludeID primitive.ObjectID) ([]string, error)
+	ForEachEntryBatch(ctx context.Context, draft *bool, batchSize int, fn func([]model.Entry) error) error
+	ForEachTermEntryBatch(ctx context.Context, taxonomyKey string, termID primitive.ObjectID, batchSize int, fn func([]model.Entry) error) error
+	SearchEntries(ctx context.Context, query string, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus, limit, offset int64) ([]model.Entry, int64, error)
+	CountEntries(ctx context.Context, schemaKeys, termIDs []string, draft *bool, status model.EntryStatus) (int64, error)
+	GetEntriesByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Entry, error)
+
+	// User
+	CreateUser(ctx context.Context, user *model.User) error
+	GetUserByID(ctx context.Context, id primitive.ObjectID) (*model.User, error)
+	GetUsersByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.User, error)
+	GetUserBySocial(ctx context.Context, provider, providerUserID string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	AddUserSocial(ctx context.Context, userID primitive.ObjectID, social model.SocialBind) error
+	RemoveUserSocial(ctx context.Context, userID primitive.ObjectID, provider string) error
+	SetUserBan(ctx context.Context, userID primitive.ObjectID, banned bool, until *time.Time, reason string) error
+	DeleteUser(ctx context.Context, userID primitive.ObjectID, anonymize bool) error
+	MarkUserEmailVerified(ctx context.Context, userID primitive.ObjectID, email string) error
+	SetUserPassword(ctx context.Context, userID primitive.ObjectID, passwordHash string) error
+	UpdateUser(ctx context.Context, user *model.User) error
+	UpdateUserProfile(ctx context.Context, userID primitive.ObjectID, nickname, avatar string) error
+
+	// Taxonomy
+	CreateTaxonomy(ctx context.Context, tax *model.Taxonomy) error
+	GetTaxonomyByKey(ctx context.Context, key string) (*model.Taxonomy, error)
+	ListTaxonomies(ctx context.Context) ([]model.Taxonomy, error)
+	UpdateTaxonomy(ctx context.Context, tax *model.Taxonomy) error
+	DeleteTaxonomy(ctx context.Context, key string) error
+
+	// Term
+	CreateTerm(ctx context.Context, term *model.Term) error
+	GetTermByID(ctx context.Context, id primitive.ObjectID) (*model.Term, error)
+	GetTermsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]model.Term, error)
+	GetTermsByTaxonomy(ctx context.Context, taxonomyKey string) ([]model.Term, error)
+	GetTermBySlug(ctx context.Context, taxonomyKey, slug string) (*model.Term, error)
+	IsTermSlugExists(ctx context.Context, taxonomyKey, slug string, excludeID primitive.ObjectID) (bool, error)
+	ReorderTerms(ctx context.Context, taxonomyKey string, termIDs []primitive.ObjectID) error
+	CountTermsInTaxonomy(ctx context.Context, taxonomyKey string, termIDs []primitive.ObjectID) (int64, error)
+	UpdateTerm(ctx context.Context, term *model.Term) error
+	DeleteTerm(ctx context.Context, id primitive.ObjectID) error
+	GetTermAncestors(ctx context.Context, id primitive.ObjectID) ([]model.Term, error)
+	HasChildTerms(ctx context.Context, parentID primitive.ObjectID) (bool, error)
+	CountChildTerms(ctx context.Context, parentID primitive.ObjectID) (int64, error)
+	HasTermReferences(ctx context.Context, taxonomyKey string, termID primitive.ObjectID) (bool, error)
+	CountTermReferences(ctx context.Context, taxonomyKey string, termID primitive.ObjectID) (int64, error)
+	RemoveTermReferences(ctx context.Context, taxonomyKey string, termID primitive.ObjectID) (int64, error)
+	CountTermUsage(ctx context.Context, taxonomyKey string) (map[string]int64, error)
+	MergeTerms(ctx context.Context, source, target *model.Term) (int64, error)
+	DeleteTermsByTaxonomy(ctx context.Context, taxonomyKey string) error
+
+	// Comment
+	CreateComment(ctx context.Context, comment *model.Comment) error
+	GetCommentByID(ctx context.Context, id primitive.ObjectID) (*model.Comment, error)
+	GetCommentsByEntry(ctx context.Context, entryID primitive.ObjectID) ([]model.Comment, error)
+	GetCommentsByEntryPaginated(ctx context.Context, entryID primitive.ObjectID, approvedOnly bool, limit, offset int64) ([]model.CommentWithAuthor, error)
+	GetRepliesByRoot(ctx context.Context, rootID primitive.ObjectID, approvedOnly bool, limit, offset int64) ([]model.CommentWithAuthor, error)
+	HasThreadCommentByAuthor(ctx context.Context, rootID primitive.ObjectID, authorID string) (bool, error)
+	CountRepliesByRoot(ctx context.Context, rootID primitive.ObjectID, approvedOnly bool) (int64, error)
+	GetPendingComments(ctx context.Context, limit, offset int64) ([]model.CommentWithAuthor, error)
+	CountPendingComments(ctx context.Context) (int64, error)
+	UpdateCommentStatus(ctx context.Context, id primitive.ObjectID, status model.CommentStatus) error
+	CountCommentsByEntry(ctx context.Context, entryID primitive.ObjectID) (int64, error)
+	CountCommentsByEntries(ctx context.Context, entryIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	CountRootCommentsByEntry(ctx context.Context, entryID primitive.ObjectID, approvedOnly bool) (int64, error)
+	DeleteComment(ctx context.Context, id primitive.ObjectID) error
+	UpdateComment(ctx context.Context, comment *model.Comment) error
+	SoftDeleteComment(ctx context.Context, id primitive.ObjectID) error
+	DeleteCommentsByRootID(ctx context.Context, rootID primitive.ObjectID) error
+	LikeComment(ctx context.Context, commentID primitive.ObjectID, userID string) (bool, error)
+	UnlikeComment(ctx context.Context, commentID primitive.ObjectID, userID string) (bool, error)
+	GetLikedCommentIDs(ctx context.Context, userID string, commentIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error)
+	CreateCommentReport(ctx context.Context, report *model.CommentReport) (bool, error)
+	GetReportedComments(ctx context.Context, minReports, limit, offset int64) ([]model.ReportedComment, error)
+
+	// Statistics
+	GetAdminStats(ctx context.Context) (*model.AdminStats, error)
+
+	// Session
+	CreateSession(ctx context.Context, session *model.Session) error
+	GetSessionByToken(ctx context.Context, token string) (*model.Session, error)
+	TakeSession(ctx context.Context, token string) (*model.Session, error)
+	TouchSession(ctx context.Context, id primitive.ObjectID, seenAt time.Time) error
+	DeleteSession(ctx context.Context, token string) error
+	GetSessionsByUser(ctx context.Context, userID primitive.ObjectID) ([]model.Session, error)
+	DeleteUserSession(ctx context.Context, userID, sessionID primitive.ObjectID) (bool, error)
+	DeleteUserSessionsExcept(ctx context.Context, userID primitive.ObjectID, keepToken string) (int64, error)
+	DeleteUserSessions(ctx context.Context, userID primitive.ObjectID) error
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+
+	// OAuth State
+	CreateOAuthState(ctx context.Context, state *model.OAuthState) error
+	GetAndDeleteOAuthState(ctx context.Context, state string) (*model.OAuthState, error)
+	DeleteExpiredOAuthStates(ctx context.Context) (int64, error)
+
+	// API Key
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+	GetAPIKeysByUser(ctx context.Context, userID primitive.ObjectID) ([]model.APIKey, error)
+	TouchAPIKey(ctx context.Context, id primitive.ObjectID, usedAt time.Time) error
+	DeleteUserAPIKeys(ctx context.Context, userID primitive.ObjectID) error
+	DeleteAPIKey(ctx context.Context, userID, keyID primitive.ObjectID) (bool, error)
+
+	// Media
+	CreateMedia(ctx context.Context, media *model.Media) error
+	GetMediaByID(ctx context.Context, id primitive.ObjectID) (*model.Media, error)
+	DeleteMedia(ctx context.Context, id primitive.ObjectID) error
+}
+
+var (
+	_ Repository = (*MongoRepo)(nil)
+	_ Repository = (*MemoryRepo)(nil)
+)
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const apiKeyPrefix = "mk_"
+
+type APIKeyStore struct {
+	mongoRepo repository.Repository
+}
+
+func NewAPIKeyStore(mongoRepo repository.Repository) *APIKeyStore {
+	return &APIKeyStore{mongoRepo: mongoRepo}
+}
+
+// Create 生成新的 API key，返回的明文只在此时可见，库中仅保存其哈希
+func (s *APIKeyStore) Create(ctx context.Context, userID primitive.ObjectID, name, role string, expiresAt *time.Time) (*model.APIKey, string, error) {
+	token, err := generateToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plain := apiKeyPrefix + token
+
+	key := &model.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Role:      role,
+		Prefix:    plain[:len(apiKeyPrefix)+8],
+		KeyHash:   hashAPIKey(plain),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.mongoRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+func (s *APIKeyStore) List(ctx context.Context, userID primitive.ObjectID) ([]model.APIKey, error) {
+	return s.mongoRepo.GetAPIKeysByUser(ctx, userID)
+}
+
+func (s *APIKeyStore) Revoke(ctx context.Context, userID, keyID primitive.ObjectID) (bool, error) {
+	return s.mongoRepo.DeleteAPIKey(ctx, userID, keyID)
+}
+
+// Validate 根据明文 key 查找未过期的记录，并顺带更新最近使用时间
+func (s *APIKeyStore) Validate(ctx context.Context, plain string) (*model.APIKey, bool) {
+	key, err := s.mongoRepo.GetAPIKeyByHash(ctx, hashAPIKey(plain))
+	if err != nil {
+		return nil, false
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, false
+	}
+
+	// 权限不超过所有者当前角色，用户被降级后 key 随之降级
+	user, err := s.mongoRepo.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		return nil, false
+	}
+	if model.UserRole(key.Role).Rank() > model.UserRole(user.Role).Rank() {
+		key.Role = user.Role
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > lastSeenThrottle {
+		_ = s.mongoRepo.TouchAPIKey(ctx, key.ID, time.Now())
+	}
+	return key, true
+}
+
+// API key 本身是高熵随机串，使用 SHA-256 即可安全存储并支持按哈希索引查找
+func hashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
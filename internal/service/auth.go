@@ -7,27 +7,54 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"matter-core/internal/config"
 	"matter-core/internal/model"
 	"matter-core/internal/repository"
+	"matter-core/pkg/utils"
 
+	"github.com/golang-jwt/jwt/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
 	"golang.org/x/oauth2/github"
 	"golang.org/x/oauth2/google"
 )
 
+var (
+	ErrJWTDisabled         = errors.New("jwt is not configured")
+	ErrEmailTaken          = errors.New("email already registered")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrPasswordMismatch    = errors.New("current password is incorrect")
+	ErrSocialNotLinked     = errors.New("provider is not linked")
+	ErrLastLoginMethod     = errors.New("cannot remove the only remaining login method")
+	ErrSocialLinkedToOther = errors.New("this account is already linked to another user")
+	ErrProviderLinked      = errors.New("provider is already linked")
+
+	ErrUnsupportedProvider   = errors.New("unsupported provider")
+	ErrProviderNotConfigured = errors.New("oauth provider not configured")
+)
+
+// Claims JWT 中携带的用户身份
+type Claims struct {
+	UserID string `json:"uid"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
 type AuthService struct {
-	mongoRepo    *repository.MongoRepo
-	cfg          *config.Config
-	githubConfig *oauth2.Config
-	googleConfig *oauth2.Config
+	mongoRepo     repository.Repository
+	cfg           *config.Config
+	githubConfig  *oauth2.Config
+	googleConfig  *oauth2.Config
+	gitlabConfig  *oauth2.Config
+	discordConfig *oauth2.Config
 }
 
-func NewAuthService(mongoRepo *repository.MongoRepo, cfg *config.Config) *AuthService {
+func NewAuthService(mongoRepo repository.Repository, cfg *config.Config) *AuthService {
 	svc := &AuthService{
 		mongoRepo: mongoRepo,
 		cfg:       cfg,
@@ -53,80 +80,142 @@ func NewAuthService(mongoRepo *repository.MongoRepo, cfg *config.Config) *AuthSe
 		}
 	}
 
+	if cfg.GitLabClientID != "" {
+		svc.gitlabConfig = &oauth2.Config{
+			ClientID:     cfg.GitLabClientID,
+			ClientSecret: cfg.GitLabClientSecret,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  cfg.GitLabURL + "/oauth/authorize",
+				TokenURL: cfg.GitLabURL + "/oauth/token",
+			},
+			RedirectURL: cfg.OAuthRedirectURL + "/gitlab",
+			Scopes:      []string{"read_user"},
+		}
+	}
+
+	if cfg.DiscordClientID != "" {
+		svc.discordConfig = &oauth2.Config{
+			ClientID:     cfg.DiscordClientID,
+			ClientSecret: cfg.DiscordSecret,
+			Endpoint:     endpoints.Discord,
+			RedirectURL:  cfg.OAuthRedirectURL + "/discord",
+			Scopes:       []string{"identify", "email"},
+		}
+	}
+
 	return svc
 }
 
-// generateState creates a cryptographically secure random state for CSRF protection
+// generateState creates a cryptographically secure random state for CSRF protection,
+// together with a PKCE code verifier bound to it.
 // State is stored in MongoDB for distributed deployment support
-func (s *AuthService) generateState(ctx context.Context) (string, error) {
+func (s *AuthService) generateState(ctx context.Context, redirect, linkUserID string) (string, string, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
-		return "", err
+		return "", "", err
 	}
 	state := base64.URLEncoding.EncodeToString(b)
+	verifier := oauth2.GenerateVerifier()
 
 	oauthState := &model.OAuthState{
-		State:     state,
-		ExpiresAt: time.Now().Add(10 * time.Minute),
+		State:        state,
+		CodeVerifier: verifier,
+		Redirect:     redirect,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(s.cfg.OAuthStateTTL),
 	}
 	if err := s.mongoRepo.CreateOAuthState(ctx, oauthState); err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return state, nil
+	return state, verifier, nil
 }
 
-// ValidateState checks if the state is valid and removes it from store
-func (s *AuthService) ValidateState(ctx context.Context, state string) bool {
+// ValidateState checks if the state is valid and removes it from store.
+// The returned state carries the PKCE code verifier to be sent with the token exchange,
+// along with the redirect target and link user recorded when the flow started.
+func (s *AuthService) ValidateState(ctx context.Context, state string) (*model.OAuthState, bool) {
 	oauthState, err := s.mongoRepo.GetAndDeleteOAuthState(ctx, state)
 	if err != nil {
-		return false
+		return nil, false
+	}
+	// 同时按当前配置的 TTL 判断，调小 OAUTH_STATE_TTL 后对已签发的 state 立即生效
+	now := time.Now()
+	if !now.Before(oauthState.ExpiresAt) || !now.Before(oauthState.CreatedAt.Add(s.cfg.OAuthStateTTL)) {
+		return nil, false
 	}
-	return time.Now().Before(oauthState.ExpiresAt)
+	return oauthState, true
 }
 
-func (s *AuthService) GetAuthURL(ctx context.Context, provider string) (string, error) {
-	state, err := s.generateState(ctx)
-	if err != nil {
-		return "", errors.New("failed to generate state")
-	}
+// supportedProviders 所有支持的 OAuth 提供商，顺序即前端展示顺序
+var supportedProviders = []string{"github", "google", "gitlab", "discord"}
 
+// oauthConfig 返回提供商的配置，未知提供商返回 ErrUnsupportedProvider，
+// 未配置凭证时返回 ErrProviderNotConfigured
+func (s *AuthService) oauthConfig(provider string) (*oauth2.Config, error) {
+	var conf *oauth2.Config
 	switch provider {
 	case "github":
-		if s.githubConfig == nil {
-			return "", errors.New("github oauth not configured")
-		}
-		return s.githubConfig.AuthCodeURL(state), nil
+		conf = s.githubConfig
 	case "google":
-		if s.googleConfig == nil {
-			return "", errors.New("google oauth not configured")
-		}
-		return s.googleConfig.AuthCodeURL(state), nil
+		conf = s.googleConfig
+	case "gitlab":
+		conf = s.gitlabConfig
+	case "discord":
+		conf = s.discordConfig
 	default:
-		return "", errors.New("unsupported provider")
+		return nil, ErrUnsupportedProvider
+	}
+	if conf == nil {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotConfigured, provider)
 	}
+	return conf, nil
 }
 
-func (s *AuthService) HandleCallback(ctx context.Context, provider, code string) (*model.User, error) {
-	var socialBind model.SocialBind
-	var err error
+// EnabledProviders 返回已配置凭证、可用于登录的提供商
+func (s *AuthService) EnabledProviders() []string {
+	providers := []string{}
+	for _, p := range supportedProviders {
+		if _, err := s.oauthConfig(p); err == nil {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
 
-	switch provider {
-	case "github":
-		socialBind, err = s.handleGitHubCallback(ctx, code)
-	case "google":
-		socialBind, err = s.handleGoogleCallback(ctx, code)
-	default:
-		return nil, errors.New("unsupported provider")
+// GetAuthURL 生成跳转到提供商的授权地址，redirect 随 state 保存，回调成功后使用
+func (s *AuthService) GetAuthURL(ctx context.Context, provider, redirect string) (string, error) {
+	return s.authCodeURL(ctx, provider, redirect, "")
+}
+
+// GetLinkURL 与 GetAuthURL 相同，但回调时将第三方账号绑定到 userID 而不是登录
+func (s *AuthService) GetLinkURL(ctx context.Context, provider string, userID primitive.ObjectID, redirect string) (string, error) {
+	return s.authCodeURL(ctx, provider, redirect, userID.Hex())
+}
+
+func (s *AuthService) authCodeURL(ctx context.Context, provider, redirect, linkUserID string) (string, error) {
+	conf, err := s.oauthConfig(provider)
+	if err != nil {
+		return "", err
+	}
+
+	state, verifier, err := s.generateState(ctx, redirect, linkUserID)
+	if err != nil {
+		return "", errors.New("failed to generate state")
 	}
 
+	return conf.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+func (s *AuthService) HandleCallback(ctx context.Context, provider, code, verifier string) (*model.User, error) {
+	socialBind, err := s.fetchSocial(ctx, provider, code, verifier)
 	if err != nil {
 		return nil, err
 	}
 
 	// 先通过社交账号查找用户
 	user, err := s.mongoRepo.GetUserBySocial(ctx, socialBind.Provider, socialBind.ProviderUserID)
-	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
 		return nil, err
 	}
 
@@ -134,13 +223,25 @@ func (s *AuthService) HandleCallback(ctx context.Context, provider, code string)
 		return user, nil
 	}
 
-	// 社交账号未绑定，尝试通过 email 查找已有用户
-	if socialBind.Email != "" {
-		user, err = s.mongoRepo.GetUserByEmail(ctx, socialBind.Email)
-		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+	// 社交账号未绑定，尝试通过 email 查找已有用户；
+	// 未验证的邮箱可能被他人冒用，不能据此关联，否则会导致账号被接管
+	email := ""
+	if socialBind.EmailVerified {
+		email = socialBind.Email
+	}
+	if email != "" {
+		user, err = s.mongoRepo.GetUserByEmail(ctx, email)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
 			return nil, err
 		}
 
+		// 注册时填写的邮箱未经验证，可能是他人抢先注册，设置了密码的账号须邮箱已验证才能关联；
+		// 否则另建账号，用户可登录原账号后通过绑定流程关联
+		if user != nil && user.PasswordHash != "" && !user.EmailVerified {
+			user = nil
+			email = ""
+		}
+
 		if user != nil {
 			// 找到同 email 用户，绑定新的社交账号
 			if err := s.mongoRepo.AddUserSocial(ctx, user.ID, socialBind); err != nil {
@@ -151,18 +252,20 @@ func (s *AuthService) HandleCallback(ctx context.Context, provider, code string)
 		}
 	}
 
-	// 创建新用户
+	// 创建新用户，邮箱未验证或已被占用时不写入用户邮箱（避免占用他人的邮箱，也不授予管理员）
 	role := string(model.RoleUser)
-	if s.cfg.AdminEmail != "" && socialBind.Email == s.cfg.AdminEmail {
+	if s.cfg.IsAdminEmail(email) {
 		role = string(model.RoleAdmin)
 	}
 
 	user = &model.User{
 		Role:     role,
 		Nickname: socialBind.Name,
-		Email:    socialBind.Email,
+		Email:    email,
 		Avatar:   socialBind.Avatar,
 		Socials:  []model.SocialBind{socialBind},
+
+		EmailVerified: email != "",
 	}
 	if err := s.mongoRepo.CreateUser(ctx, user); err != nil {
 		return nil, err
@@ -171,8 +274,91 @@ func (s *AuthService) HandleCallback(ctx context.Context, provider, code string)
 	return user, nil
 }
 
-func (s *AuthService) handleGitHubCallback(ctx context.Context, code string) (model.SocialBind, error) {
-	token, err := s.githubConfig.Exchange(ctx, code)
+// LinkSocial 将回调得到的第三方账号绑定到已登录的用户；
+// 该账号已绑定到其他用户，或用户已绑定同一提供商的其他账号时拒绝
+func (s *AuthService) LinkSocial(ctx context.Context, userID, provider, code, verifier string) (*model.User, error) {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted() || user.IsBanned() {
+		return nil, ErrInvalidCredentials
+	}
+
+	socialBind, err := s.fetchSocial(ctx, provider, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.mongoRepo.GetUserBySocial(ctx, socialBind.Provider, socialBind.ProviderUserID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if owner != nil {
+		if owner.ID != user.ID {
+			return nil, ErrSocialLinkedToOther
+		}
+		// 重复绑定同一个账号视为成功
+		return user, nil
+	}
+	for _, social := range user.Socials {
+		if social.Provider == socialBind.Provider {
+			return nil, ErrProviderLinked
+		}
+	}
+
+	if err := s.mongoRepo.AddUserSocial(ctx, user.ID, socialBind); err != nil {
+		return nil, err
+	}
+	user.Socials = append(user.Socials, socialBind)
+
+	// 用户主动绑定的第三方账号验证过同一邮箱，即可确认该邮箱归属
+	if !user.EmailVerified && user.Email != "" && socialBind.EmailVerified && socialBind.Email == user.Email {
+		if err := s.mongoRepo.MarkUserEmailVerified(ctx, user.ID, user.Email); err == nil {
+			user.EmailVerified = true
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// fetchSocial 用授权码换取 token 并读取提供商的用户信息
+func (s *AuthService) fetchSocial(ctx context.Context, provider, code, verifier string) (model.SocialBind, error) {
+	if _, err := s.oauthConfig(provider); err != nil {
+		return model.SocialBind{}, err
+	}
+
+	var socialBind model.SocialBind
+	var err error
+
+	switch provider {
+	case "github":
+		socialBind, err = s.handleGitHubCallback(ctx, code, verifier)
+	case "google":
+		socialBind, err = s.handleGoogleCallback(ctx, code, verifier)
+	case "gitlab":
+		socialBind, err = s.handleGitLabCallback(ctx, code, verifier)
+	case "discord":
+		socialBind, err = s.handleDiscordCallback(ctx, code, verifier)
+	default:
+		return model.SocialBind{}, ErrUnsupportedProvider
+	}
+
+	if err != nil {
+		return model.SocialBind{}, err
+	}
+
+	// 提供商返回的邮箱可能带大小写或空白差异，格式不合法时视为没有邮箱
+	socialBind.Email = utils.NormalizeEmail(socialBind.Email)
+	if !utils.ValidEmail(socialBind.Email) {
+		socialBind.Email = ""
+	}
+	return socialBind, nil
+}
+
+func (s *AuthService) handleGitHubCallback(ctx context.Context, code, verifier string) (model.SocialBind, error) {
+	token, err := s.githubConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
 		return model.SocialBind{}, err
 	}
@@ -194,20 +380,24 @@ func (s *AuthService) handleGitHubCallback(ctx context.Context, code string) (mo
 		return model.SocialBind{}, err
 	}
 
-	if ghUser.Email == "" {
-		emailResp, err := client.Get("https://api.github.com/user/emails")
-		if err == nil {
-			defer emailResp.Body.Close()
-			var emails []struct {
-				Email   string `json:"email"`
-				Primary bool   `json:"primary"`
-			}
-			if json.NewDecoder(emailResp.Body).Decode(&emails) == nil {
-				for _, e := range emails {
-					if e.Primary {
-						ghUser.Email = e.Email
-						break
-					}
+	// /user 中的公开邮箱不带验证状态，需要从 /user/emails 中确认
+	verified := false
+	emailResp, err := client.Get("https://api.github.com/user/emails")
+	if err == nil {
+		defer emailResp.Body.Close()
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if json.NewDecoder(emailResp.Body).Decode(&emails) == nil {
+			for _, e := range emails {
+				if ghUser.Email == "" && e.Primary {
+					ghUser.Email = e.Email
+				}
+				if strings.EqualFold(e.Email, ghUser.Email) {
+					verified = e.Verified
+					break
 				}
 			}
 		}
@@ -218,12 +408,13 @@ func (s *AuthService) handleGitHubCallback(ctx context.Context, code string) (mo
 		ProviderUserID: fmt.Sprintf("%d", ghUser.ID),
 		Name:           ghUser.Login,
 		Email:          ghUser.Email,
+		EmailVerified:  verified,
 		Avatar:         ghUser.AvatarURL,
 	}, nil
 }
 
-func (s *AuthService) handleGoogleCallback(ctx context.Context, code string) (model.SocialBind, error) {
-	token, err := s.googleConfig.Exchange(ctx, code)
+func (s *AuthService) handleGoogleCallback(ctx context.Context, code, verifier string) (model.SocialBind, error) {
+	token, err := s.googleConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
 		return model.SocialBind{}, err
 	}
@@ -236,10 +427,11 @@ func (s *AuthService) handleGoogleCallback(ctx context.Context, code string) (mo
 	defer resp.Body.Close()
 
 	var googleUser struct {
-		ID      string `json:"id"`
-		Name    string `json:"name"`
-		Email   string `json:"email"`
-		Picture string `json:"picture"`
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Picture       string `json:"picture"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
 		return model.SocialBind{}, err
@@ -250,18 +442,265 @@ func (s *AuthService) handleGoogleCallback(ctx context.Context, code string) (mo
 		ProviderUserID: googleUser.ID,
 		Name:           googleUser.Name,
 		Email:          googleUser.Email,
+		EmailVerified:  googleUser.VerifiedEmail,
 		Avatar:         googleUser.Picture,
 	}, nil
 }
 
+func (s *AuthService) handleGitLabCallback(ctx context.Context, code, verifier string) (model.SocialBind, error) {
+	token, err := s.gitlabConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return model.SocialBind{}, err
+	}
+
+	client := s.gitlabConfig.Client(ctx, token)
+	resp, err := client.Get(s.cfg.GitLabURL + "/api/v4/user")
+	if err != nil {
+		return model.SocialBind{}, err
+	}
+	defer resp.Body.Close()
+
+	// read_user 作用域下 email 即为账号的主邮箱
+	var glUser struct {
+		ID          int    `json:"id"`
+		Username    string `json:"username"`
+		Email       string `json:"email"`
+		PublicEmail string `json:"public_email"`
+		ConfirmedAt string `json:"confirmed_at"` // 主邮箱的确认时间，未确认时为空
+		AvatarURL   string `json:"avatar_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&glUser); err != nil {
+		return model.SocialBind{}, err
+	}
+	verified := glUser.Email != "" && glUser.ConfirmedAt != ""
+	if glUser.Email == "" {
+		glUser.Email = glUser.PublicEmail
+	}
+
+	return model.SocialBind{
+		Provider:       "gitlab",
+		ProviderUserID: fmt.Sprintf("%d", glUser.ID),
+		Name:           glUser.Username,
+		Email:          glUser.Email,
+		EmailVerified:  verified,
+		Avatar:         glUser.AvatarURL,
+	}, nil
+}
+
+func (s *AuthService) handleDiscordCallback(ctx context.Context, code, verifier string) (model.SocialBind, error) {
+	token, err := s.discordConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return model.SocialBind{}, err
+	}
+
+	client := s.discordConfig.Client(ctx, token)
+	resp, err := client.Get("https://discord.com/api/users/@me")
+	if err != nil {
+		return model.SocialBind{}, err
+	}
+	defer resp.Body.Close()
+
+	var dcUser struct {
+		ID         string `json:"id"`
+		Username   string `json:"username"`
+		GlobalName string `json:"global_name"`
+		Email      string `json:"email"`
+		Verified   bool   `json:"verified"`
+		Avatar     string `json:"avatar"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&dcUser); err != nil {
+		return model.SocialBind{}, err
+	}
+
+	name := dcUser.GlobalName
+	if name == "" {
+		name = dcUser.Username
+	}
+	// 未验证的邮箱不可用于账号关联
+	email := ""
+	if dcUser.Verified {
+		email = dcUser.Email
+	}
+	avatar := ""
+	if dcUser.Avatar != "" {
+		avatar = fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png", dcUser.ID, dcUser.Avatar)
+	}
+
+	return model.SocialBind{
+		Provider:       "discord",
+		ProviderUserID: dcUser.ID,
+		Name:           name,
+		Email:          email,
+		EmailVerified:  email != "",
+		Avatar:         avatar,
+	}, nil
+}
+
 func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*model.User, error) {
 	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, err
 	}
-	return s.mongoRepo.GetUserByID(ctx, oid)
+	user, err := s.mongoRepo.GetUserByID(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	user.HasPassword = user.PasswordHash != ""
+	return user, nil
+}
+
+// UnlinkSocial 解绑第三方账号，返回剩余的绑定列表；不允许移除唯一的登录方式
+func (s *AuthService) UnlinkSocial(ctx context.Context, user *model.User, provider string) ([]model.SocialBind, error) {
+	remaining := make([]model.SocialBind, 0, len(user.Socials))
+	for _, social := range user.Socials {
+		if social.Provider != provider {
+			remaining = append(remaining, social)
+		}
+	}
+	if len(remaining) == len(user.Socials) {
+		return nil, ErrSocialNotLinked
+	}
+	if len(remaining) == 0 && user.PasswordHash == "" {
+		return nil, ErrLastLoginMethod
+	}
+
+	if err := s.mongoRepo.RemoveUserSocial(ctx, user.ID, provider); err != nil {
+		// 并发解绑导致条件不再满足
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrLastLoginMethod
+		}
+		return nil, err
+	}
+	user.Socials = remaining
+	return remaining, nil
 }
 
 func (s *AuthService) UpdateUser(ctx context.Context, user *model.User) error {
 	return s.mongoRepo.UpdateUser(ctx, user)
 }
+
+// DeleteAccount 注销账号并删除其 API Key；session 由调用方通过 SessionStore.RevokeAll 注销
+func (s *AuthService) DeleteAccount(ctx context.Context, userID primitive.ObjectID, anonymize bool) error {
+	if err := s.mongoRepo.DeleteUser(ctx, userID, anonymize); err != nil {
+		return err
+	}
+	return s.mongoRepo.DeleteUserAPIKeys(ctx, userID)
+}
+
+// IssueJWT 为用户签发 HS256 JWT，未配置 JWT_SECRET 时返回 ErrJWTDisabled
+func (s *AuthService) IssueJWT(userID primitive.ObjectID, role string, ttl time.Duration) (string, error) {
+	if s.cfg.JWTSecret == "" {
+		return "", ErrJWTDisabled
+	}
+	now := time.Now()
+	claims := Claims{
+		UserID: userID.Hex(),
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.Hex(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.cfg.JWTSecret))
+}
+
+// ValidateJWT 校验签名与过期时间，只接受 HS256
+func (s *AuthService) ValidateJWT(tokenStr string) (*Claims, error) {
+	if s.cfg.JWTSecret == "" {
+		return nil, ErrJWTDisabled
+	}
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(s.cfg.JWTSecret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.UserID == "" {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// Register 使用邮箱密码注册本地账号
+// 邮箱未经验证，因此不会像 OAuth 登录那样自动授予管理员角色
+func (s *AuthService) Register(ctx context.Context, email, password, nickname string) (*model.User, error) {
+	email = utils.NormalizeEmail(email)
+
+	existing, err := s.mongoRepo.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrEmailTaken
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	if nickname == "" {
+		nickname = strings.SplitN(email, "@", 2)[0]
+	}
+
+	user := &model.User{
+		Role:         string(model.RoleUser),
+		Nickname:     nickname,
+		Email:        email,
+		Socials:      []model.SocialBind{},
+		PasswordHash: string(hash),
+	}
+	if err := s.mongoRepo.CreateUser(ctx, user); err != nil {
+		// The unique email index is the final arbiter for concurrent registrations
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	user.HasPassword = true
+	return user, nil
+}
+
+// Login 校验邮箱密码，未设置密码的 OAuth 账号同样返回 ErrInvalidCredentials
+func (s *AuthService) Login(ctx context.Context, email, password string) (*model.User, error) {
+	email = utils.NormalizeEmail(email)
+
+	user, err := s.mongoRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if user.PasswordHash == "" {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	user.HasPassword = true
+	return user, nil
+}
+
+// SetPassword 为已登录用户设置或修改密码；已有密码时必须提供正确的当前密码
+func (s *AuthService) SetPassword(ctx context.Context, user *model.User, currentPassword, newPassword string) error {
+	if user.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+			return ErrPasswordMismatch
+		}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.mongoRepo.SetUserPassword(ctx, user.ID, string(hash)); err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	user.HasPassword = true
+	return nil
+}
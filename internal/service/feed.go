@@ -0,0 +1,186 @@
+package service
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"matter-core/internal/model"
+)
+
+// FeedItem 生成订阅源所需的单条 entry 信息
+type FeedItem struct {
+	Entry  model.Entry
+	Link   string
+	Author string
+}
+
+// Feed 订阅源的频道信息，Items 按时间倒序
+type Feed struct {
+	Title       string
+	Link        string // 站点地址
+	Self        string // 订阅源自身的地址
+	Description string
+	Items       []FeedItem
+	Updated     time.Time // 没有条目时 Atom 的 updated，为空时取当前时间
+}
+
+// feedExcerptLength 摘要的最大字符数
+const feedExcerptLength = 300
+
+// Excerpt 按正文格式去掉标记并截断为 max 个字符的纯文本摘要
+func Excerpt(body string, format model.BodyFormat, max int) string {
+	text := strings.Join(strings.Fields(bodyText(body, format)), " ")
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:max])) + "…"
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          rssLink   `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	Description string  `xml:"description"`
+	Creator     string  `xml:"dc:creator,omitempty"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// RSS 渲染为 RSS 2.0
+func (f *Feed) RSS() ([]byte, error) {
+	doc := rssDoc{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Self:        rssLink{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+			Description: f.Description,
+			Items:       make([]rssItem, 0, len(f.Items)),
+		},
+	}
+	if len(f.Items) > 0 {
+		doc.Channel.LastBuildDate = f.Items[0].Entry.Base.CreatedAt.UTC().Format(time.RFC1123Z)
+	}
+	for _, item := range f.Items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       item.Entry.Base.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{Value: item.Link, IsPermaLink: true},
+			Description: Excerpt(item.Entry.Body, item.Entry.BodyFormat, feedExcerptLength),
+			Creator:     item.Author,
+			PubDate:     item.Entry.Base.CreatedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return marshalFeed(doc)
+}
+
+type atomDoc struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Summary   string      `xml:"summary"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+// Atom 渲染为 Atom 1.0
+func (f *Feed) Atom() ([]byte, error) {
+	var updated time.Time
+	for _, item := range f.Items {
+		if item.Entry.Base.UpdatedAt.After(updated) {
+			updated = item.Entry.Base.UpdatedAt
+		}
+	}
+	if updated.IsZero() {
+		updated = f.Updated
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	doc := atomDoc{
+		XMLNS: "http://www.w3.org/2005/Atom",
+		Title: f.Title,
+		ID:    f.Self,
+		Links: []atomLink{
+			{Href: f.Link},
+			{Href: f.Self, Rel: "self", Type: "application/atom+xml"},
+		},
+		Updated: updated.UTC().Format(time.RFC3339),
+		Entries: make([]atomEntry, 0, len(f.Items)),
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			Title:     item.Entry.Base.Title,
+			ID:        item.Link,
+			Link:      atomLink{Href: item.Link},
+			Published: item.Entry.Base.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   item.Entry.Base.UpdatedAt.UTC().Format(time.RFC3339),
+			Summary:   Excerpt(item.Entry.Body, item.Entry.BodyFormat, feedExcerptLength),
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return marshalFeed(doc)
+}
+
+func marshalFeed(doc any) ([]byte, error) {
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
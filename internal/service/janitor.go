@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"matter-core/internal/repository"
+)
+
+// Janitor 定期清理过期的 session 与 OAuth state，作为 TTL 索引的补充
+// （Mongo 的 TTL 后台任务约每 60 秒运行一次，负载高时可能明显滞后）
+type Janitor struct {
+	mongoRepo repository.Repository
+	interval  time.Duration
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func NewJanitor(mongoRepo repository.Repository, interval time.Duration) *Janitor {
+	return &Janitor{
+		mongoRepo: mongoRepo,
+		interval:  interval,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start 启动后台清理，interval <= 0 时不启动
+func (j *Janitor) Start() {
+	if j.interval <= 0 {
+		return
+	}
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				j.run()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台清理并等待进行中的一轮结束
+func (j *Janitor) Stop() {
+	close(j.stop)
+	j.wg.Wait()
+}
+
+func (j *Janitor) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	sessions, err := j.mongoRepo.DeleteExpiredSessions(ctx)
+	if err != nil {
+		log.Printf("janitor: failed to delete expired sessions: %v", err)
+	}
+	states, err := j.mongoRepo.DeleteExpiredOAuthStates(ctx)
+	if err != nil {
+		log.Printf("janitor: failed to delete expired oauth states: %v", err)
+	}
+	if sessions > 0 || states > 0 {
+		log.Printf("janitor: removed %d expired sessions, %d expired oauth states", sessions, states)
+	}
+}
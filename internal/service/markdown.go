@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	mdCodeBlock  = regexp.MustCompile("(?s)```[a-zA-Z0-9_-]*\\n?(.*?)```")
+	mdInlineCode = regexp.MustCompile("`([^`\n]+)`")
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBold       = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdItalic     = regexp.MustCompile(`\*([^*\n]+)\*`)
+	mdParagraph  = regexp.MustCompile(`\n{2,}`)
+	mdToken      = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// RenderMarkdown 将评论等短文本的 Markdown 渲染为安全的 HTML
+// 输入中的原始 HTML 一律转义，链接只允许 http/https/mailto
+func RenderMarkdown(md string) string {
+	// NUL delimits stash tokens below, so it must not come from the input;
+	// escape everything up front so no raw HTML survives
+	md = strings.ReplaceAll(md, "\x00", "")
+	text := html.EscapeString(strings.ReplaceAll(strings.TrimSpace(md), "\r\n", "\n"))
+
+	// Code is set aside first so its contents are not formatted
+	var stash []string
+	hold := func(fragment string) string {
+		stash = append(stash, fragment)
+		return fmt.Sprintf("\x00%d\x00", len(stash)-1)
+	}
+	blocks := make(map[string]bool)
+	text = mdCodeBlock.ReplaceAllStringFunc(text, func(m string) string {
+		token := hold("<pre><code>" + mdCodeBlock.FindStringSubmatch(m)[1] + "</code></pre>")
+		blocks[token] = true
+		return token
+	})
+	text = mdInlineCode.ReplaceAllStringFunc(text, func(m string) string {
+		return hold("<code>" + mdInlineCode.FindStringSubmatch(m)[1] + "</code>")
+	})
+
+	text = mdLink.ReplaceAllStringFunc(text, func(m string) string {
+		parts := mdLink.FindStringSubmatch(m)
+		if !isSafeLink(html.UnescapeString(parts[2])) {
+			return parts[1]
+		}
+		return hold(`<a href="` + parts[2] + `" rel="nofollow noopener">` + parts[1] + `</a>`)
+	})
+	text = mdBold.ReplaceAllString(text, "<strong>$1</strong>")
+	text = mdItalic.ReplaceAllString(text, "<em>$1</em>")
+
+	var b strings.Builder
+	for _, para := range mdParagraph.Split(text, -1) {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if blocks[para] {
+			// A standalone code block is not wrapped in <p>
+			b.WriteString(para)
+			continue
+		}
+		b.WriteString("<p>" + strings.ReplaceAll(para, "\n", "<br>") + "</p>")
+	}
+
+	// A fragment may hold earlier tokens (e.g. inline code inside link text)
+	var expand func(s string) string
+	expand = func(s string) string {
+		return mdToken.ReplaceAllStringFunc(s, func(m string) string {
+			i, err := strconv.Atoi(mdToken.FindStringSubmatch(m)[1])
+			if err != nil || i < 0 || i >= len(stash) {
+				return ""
+			}
+			return expand(stash[i])
+		})
+	}
+	return expand(b.String())
+}
+
+func isSafeLink(url string) bool {
+	lower := strings.ToLower(strings.TrimSpace(url))
+	return strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "mailto:")
+}
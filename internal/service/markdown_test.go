@@ -0,0 +1,35 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"paragraphs", "hello\n\nworld", "<p>hello</p><p>world</p>"},
+		{"escapes html", "<script>x</script>", "<p>&lt;script&gt;x&lt;/script&gt;</p>"},
+		{"inline code", "use `a*b*c`", "<p>use <code>a*b*c</code></p>"},
+		{"code block", "```go\nx := 1\n```", "<pre><code>x := 1\n</code></pre>"},
+		{"safe link", "[site](https://example.com)", `<p><a href="https://example.com" rel="nofollow noopener">site</a></p>`},
+		{"unsafe link", "[x](javascript:alert)", "<p>x</p>"},
+		{"code inside link text", "[`go`](https://go.dev)", `<p><a href="https://go.dev" rel="nofollow noopener"><code>go</code></a></p>`},
+		{"forged token", "hello \x0099\x00 world", "<p>hello 99 world</p>"},
+		{"forged token splice", "`a` \x000\x00", "<p><code>a</code> 0</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderMarkdown(tt.in)
+			if got != tt.want {
+				t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if strings.Contains(got, "\x00") {
+				t.Errorf("RenderMarkdown(%q) leaked a NUL byte", tt.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter 进程内固定窗口限流器，按 key 计数
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+type rateBucket struct {
+	count   int
+	resetAt time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:     limit,
+		window:    window,
+		buckets:   make(map[string]*rateBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow 记录一次请求，超出限额时返回 false 以及距离窗口重置的时间
+// limit <= 0 表示不限流
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok || now.After(b.resetAt) {
+		l.buckets[key] = &rateBucket{count: 1, resetAt: now.Add(l.window)}
+		return true, 0
+	}
+	if b.count >= l.limit {
+		return false, b.resetAt.Sub(now)
+	}
+	b.count++
+	return true, 0
+}
+
+// sweep 定期清理已过期的计数，避免 key 无限增长
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for k, b := range l.buckets {
+		if now.After(b.resetAt) {
+			delete(l.buckets, k)
+		}
+	}
+	l.lastSweep = now
+}
@@ -4,23 +4,35 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"time"
 
 	"matter-core/internal/model"
 	"matter-core/internal/repository"
+	"matter-core/pkg/utils"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// lastSeenThrottle 限制 last_seen_at 的写入频率，避免每个请求都写库
+const lastSeenThrottle = 5 * time.Minute
+
+// banCacheTTL 封禁状态的缓存时长，其他实例上的封禁最多延迟这么久生效
+const banCacheTTL = 30 * time.Second
+
 type SessionStore struct {
-	mongoRepo *repository.MongoRepo
+	mongoRepo repository.Repository
+	banCache  *utils.TTLCache[bool]
 }
 
-func NewSessionStore(mongoRepo *repository.MongoRepo) *SessionStore {
-	return &SessionStore{mongoRepo: mongoRepo}
+func NewSessionStore(mongoRepo repository.Repository) *SessionStore {
+	return &SessionStore{
+		mongoRepo: mongoRepo,
+		banCache:  utils.NewTTLCache[bool](banCacheTTL),
+	}
 }
 
-func (s *SessionStore) Create(ctx context.Context, userID primitive.ObjectID, role string, duration time.Duration) (string, error) {
+func (s *SessionStore) Create(ctx context.Context, userID primitive.ObjectID, role string, duration time.Duration, client model.SessionClient) (string, error) {
 	token, err := generateToken(32)
 	if err != nil {
 		return "", err
@@ -31,6 +43,8 @@ func (s *SessionStore) Create(ctx context.Context, userID primitive.ObjectID, ro
 		UserID:    userID,
 		Role:      role,
 		ExpiresAt: time.Now().Add(duration),
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
 	}
 
 	if err := s.mongoRepo.CreateSession(ctx, session); err != nil {
@@ -47,6 +61,109 @@ func (s *SessionStore) Delete(ctx context.Context, token string) error {
 	return s.mongoRepo.DeleteSession(ctx, token)
 }
 
+// ErrSessionInvalid 待刷新的 session 不存在、已过期、已被并发的刷新轮换，或用户已被封禁/注销
+var ErrSessionInvalid = errors.New("session is no longer valid")
+
+// Refresh 轮换 session，有效期重新计算，角色按用户当前记录重新读取。
+// 先写入新 session 再原子取走旧的：任一步失败时旧 session 保持有效；
+// 旧 token 已被并发的刷新取走时撤销新 session，同一 token 只能换到一个新 token
+func (s *SessionStore) Refresh(ctx context.Context, oldToken string, duration time.Duration, client model.SessionClient) (string, error) {
+	old, ok := s.IsValid(ctx, oldToken)
+	if !ok {
+		return "", ErrSessionInvalid
+	}
+	user, err := s.mongoRepo.GetUserByID(ctx, old.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrSessionInvalid
+		}
+		return "", err
+	}
+	if user.IsBanned() || user.IsDeleted() {
+		_ = s.mongoRepo.DeleteSession(ctx, oldToken)
+		return "", ErrSessionInvalid
+	}
+
+	token, err := s.Create(ctx, user.ID, user.Role, duration, client)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.mongoRepo.TakeSession(ctx, oldToken); err != nil {
+		_ = s.mongoRepo.DeleteSession(ctx, token)
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrSessionInvalid
+		}
+		return "", err
+	}
+	return token, nil
+}
+
+// Touch 更新 session 的最近活跃时间，距上次更新不足 lastSeenThrottle 时跳过
+func (s *SessionStore) Touch(ctx context.Context, session *model.Session) {
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < lastSeenThrottle {
+		return
+	}
+	if err := s.mongoRepo.TouchSession(ctx, session.ID, now); err == nil {
+		session.LastSeenAt = now
+	}
+}
+
+// ListByUser 返回用户所有未过期的 session，并标记 currentToken 对应的那个
+func (s *SessionStore) ListByUser(ctx context.Context, userID primitive.ObjectID, currentToken string) ([]model.Session, error) {
+	sessions, err := s.mongoRepo.GetSessionsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Token == currentToken
+	}
+	return sessions, nil
+}
+
+func (s *SessionStore) Revoke(ctx context.Context, userID, sessionID primitive.ObjectID) (bool, error) {
+	return s.mongoRepo.DeleteUserSession(ctx, userID, sessionID)
+}
+
+// RevokeOthers 注销用户在其他设备上的所有 session
+func (s *SessionStore) RevokeOthers(ctx context.Context, userID primitive.ObjectID, currentToken string) (int64, error) {
+	return s.mongoRepo.DeleteUserSessionsExcept(ctx, userID, currentToken)
+}
+
+// RevokeAll 注销用户的所有 session（封禁时使用），并刷新其封禁状态缓存
+func (s *SessionStore) RevokeAll(ctx context.Context, userID primitive.ObjectID) error {
+	s.banCache.Delete(userID.Hex())
+	return s.mongoRepo.DeleteUserSessions(ctx, userID)
+}
+
+// ForgetBan 丢弃缓存的封禁状态，使解封立即生效
+func (s *SessionStore) ForgetBan(userID primitive.ObjectID) {
+	s.banCache.Delete(userID.Hex())
+}
+
+// IsBanned 判断用户是否被封禁、已注销或不存在，结果缓存 banCacheTTL；
+// 查询失败时按封禁处理且不缓存，避免数据库故障期间放行被封禁的用户
+func (s *SessionStore) IsBanned(ctx context.Context, userID string) bool {
+	if banned, ok := s.banCache.Get(userID); ok {
+		return banned
+	}
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return true
+	}
+	user, err := s.mongoRepo.GetUserByID(ctx, oid)
+	if err == repository.ErrNotFound {
+		s.banCache.Set(userID, true)
+		return true
+	}
+	if err != nil {
+		return true
+	}
+	banned := user.IsBanned() || user.IsDeleted()
+	s.banCache.Set(userID, banned)
+	return banned
+}
+
 func (s *SessionStore) IsValid(ctx context.Context, token string) (*model.Session, bool) {
 	session, err := s.Get(ctx, token)
 	if err != nil {
@@ -1,22 +1,59 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"matter-core/internal/model"
 	"matter-core/internal/repository"
+	"matter-core/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// schemaCacheTTL schema 按版本不可变，缓存只是为了避免每次同步都查库
+const schemaCacheTTL = 10 * time.Minute
+
+// latestSchemaTTL 各 key 最新版本的缓存时间；本进程内修改 schema 时会立即失效，其他实例最多延迟这么久
+const latestSchemaTTL = time.Minute
+
+// resyncBatchSize / resyncBatchDelay 控制按 term 重新同步时对 Meilisearch 的写入节奏
+const (
+	resyncBatchSize  = 200
+	resyncBatchDelay = 500 * time.Millisecond
 )
 
 type SyncService struct {
-	meiliRepo *repository.MeiliRepo
+	meiliRepo   *repository.MeiliRepo
+	mongoRepo   repository.Repository
+	schemaCache *utils.TTLCache[*model.Schema]
+	latestCache *utils.TTLCache[*model.Schema] // 按 schema key 缓存最新版本，用于 Searchable
+
+	refreshMu sync.Mutex // 串行化 RefreshSearchableFields，避免旧的快照覆盖新的设置
+
+	resyncMu      sync.Mutex
+	resyncRunning map[string]bool // 正在重新同步的 term
+	resyncPending map[string]bool // 运行期间再次被触发，需要结束后再跑一轮
 }
 
-func NewSyncService(meiliRepo *repository.MeiliRepo) *SyncService {
-	return &SyncService{meiliRepo: meiliRepo}
+func NewSyncService(meiliRepo *repository.MeiliRepo, mongoRepo repository.Repository) *SyncService {
+	return &SyncService{
+		meiliRepo:   meiliRepo,
+		mongoRepo:   mongoRepo,
+		schemaCache: utils.NewTTLCache[*model.Schema](schemaCacheTTL),
+		latestCache: utils.NewTTLCache[*model.Schema](latestSchemaTTL),
+
+		resyncRunning: make(map[string]bool),
+		resyncPending: make(map[string]bool),
+	}
 }
 
 // SyncEntryAsync 异步同步 entry 到搜索引擎，带重试机制
@@ -43,11 +80,60 @@ func (s *SyncService) syncWithRetry(entry *model.Entry, maxRetries int) {
 	log.Printf("giving up syncing entry %s after %d attempts", entry.ID.Hex(), maxRetries)
 }
 
+// SyncEntry 索引只保存公开内容：未发布（draft 为 true）的 entry 会从索引中移除，发布后重新写入
 func (s *SyncService) SyncEntry(entry *model.Entry) error {
-	doc := s.entryToSearchDoc(entry)
+	if entry.Base.Draft {
+		return s.meiliRepo.DeleteDocument(entry.ID.Hex())
+	}
+	doc := s.entryToSearchDoc(entry, s.termNames(entryTermIDs(s.entrySchema(entry), entry)))
 	return s.meiliRepo.IndexDocument(doc)
 }
 
+// SyncEntriesAsync 异步批量同步，失败时整批重试
+func (s *SyncService) SyncEntriesAsync(entries []*model.Entry) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic in SyncEntriesAsync: %v", r)
+			}
+		}()
+		const maxRetries = 3
+		var err error
+		for i := 0; i < maxRetries; i++ {
+			if err = s.SyncEntries(entries); err == nil {
+				return
+			}
+			log.Printf("failed to sync %d entries (attempt %d/%d): %v", len(entries), i+1, maxRetries, err)
+			time.Sleep(time.Duration(i+1) * time.Second)
+		}
+		log.Printf("giving up syncing %d entries after %d attempts", len(entries), maxRetries)
+	}()
+}
+
+// SyncEntries 批量同步多个 entry，已发布的一次性写入，草稿一次性移除；
+// 整批引用的 term 名称通过一次查询解析
+func (s *SyncService) SyncEntries(entries []*model.Entry) error {
+	var published []*model.Entry
+	var drafts, termIDs []string
+	for _, entry := range entries {
+		if entry.Base.Draft {
+			drafts = append(drafts, entry.ID.Hex())
+			continue
+		}
+		published = append(published, entry)
+		termIDs = append(termIDs, entryTermIDs(s.entrySchema(entry), entry)...)
+	}
+	names := s.termNames(termIDs)
+	docs := make([]model.SearchDocument, 0, len(published))
+	for _, entry := range published {
+		docs = append(docs, s.entryToSearchDoc(entry, names))
+	}
+	if err := s.meiliRepo.IndexDocuments(docs); err != nil {
+		return err
+	}
+	return s.meiliRepo.DeleteDocuments(drafts)
+}
+
 // DeleteEntryAsync 异步删除搜索索引
 func (s *SyncService) DeleteEntryAsync(id string) {
 	go func() {
@@ -66,18 +152,285 @@ func (s *SyncService) DeleteEntry(id string) error {
 	return s.meiliRepo.DeleteDocument(id)
 }
 
-func (s *SyncService) entryToSearchDoc(entry *model.Entry) model.SearchDocument {
+// entryToSearchDoc termNames 为预先批量解析的 term ID 到名称的映射
+func (s *SyncService) entryToSearchDoc(entry *model.Entry, termNames map[string]string) model.SearchDocument {
+	schema := s.entrySchema(entry)
 	allText := s.extractTextFromAttributes(entry.Attributes)
+	termIDs := entryTermIDs(schema, entry)
+	// Term names are denormalized so that searching "golang" finds entries tagged with it
+	var names []string
+	for _, id := range termIDs {
+		if name, ok := termNames[id]; ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		allText = strings.TrimSpace(allText + " " + strings.Join(names, " "))
+	}
 
-	return model.SearchDocument{
+	doc := model.SearchDocument{
 		ID:        entry.ID.Hex(),
 		Title:     entry.Base.Title,
-		Body:      stripMarkdown(entry.Body),
+		Body:      bodyText(entry.Body, entry.BodyFormat),
 		SchemaKey: entry.SchemaKey,
 		AllText:   allText,
+		TermIDs:   termIDs,
+		CreatedAt: entry.Base.CreatedAt.Unix(),
+	}
+	// 可搜索字段与索引设置一致，取该 key 的最新版本，而不是 entry 创建时的版本
+	if latest := s.latestSchema(entry.SchemaKey); latest != nil {
+		for _, key := range latest.Searchable {
+			if value, ok := entry.Attributes[key]; ok {
+				if doc.Attributes == nil {
+					doc.Attributes = make(map[string]string, len(latest.Searchable))
+				}
+				doc.Attributes[key] = strings.Join(extractStrings(value), " ")
+			}
+		}
+	}
+	return doc
+}
+
+// latestSchema 返回 schema key 的最新版本，加载失败时返回 nil
+func (s *SyncService) latestSchema(key string) *model.Schema {
+	if schema, ok := s.latestCache.Get(key); ok {
+		return schema
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	schema, err := s.mongoRepo.GetLatestSchema(ctx, key)
+	if err != nil {
+		log.Printf("failed to load latest schema %s: %v", key, err)
+		return nil
+	}
+	s.latestCache.Set(key, schema)
+	return schema
+}
+
+// SchemaChanged 在 schema 新增版本或删除后调用：清除缓存的最新版本并在后台更新可搜索字段
+func (s *SyncService) SchemaChanged(key string) {
+	s.latestCache.Delete(key)
+	s.RefreshSearchableFieldsAsync()
+}
+
+// RefreshSearchableFields 汇总各 schema 最新版本声明的 Searchable 属性，更新索引的可搜索字段；
+// 已索引的 entry 需要重建索引（POST /api/v1/admin/reindex）后才会带上新字段
+func (s *SyncService) RefreshSearchableFields(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	schemas, err := s.mongoRepo.ListSchemas(ctx)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(schemas, func(a, b model.Schema) int { return strings.Compare(a.Key, b.Key) })
+	var keys []string
+	for _, schema := range schemas {
+		for _, key := range schema.Searchable {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return s.meiliRepo.SetAttributeFields(keys)
+}
+
+// RefreshSearchableFieldsAsync 在后台执行 RefreshSearchableFields，失败只记录日志
+func (s *SyncService) RefreshSearchableFieldsAsync() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic in RefreshSearchableFieldsAsync: %v", r)
+			}
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.RefreshSearchableFields(ctx); err != nil {
+			log.Printf("failed to update searchable fields: %v", err)
+		}
+	}()
+}
+
+// termNames 一次查询解析一组 term ID 的名称，按 ID 索引；查询失败时返回 nil
+func (s *SyncService) termNames(termIDs []string) map[string]string {
+	oids := make([]primitive.ObjectID, 0, len(termIDs))
+	seen := make(map[string]bool, len(termIDs))
+	for _, id := range termIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	if len(oids) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	terms, err := s.mongoRepo.GetTermsByIDs(ctx, oids)
+	if err != nil {
+		log.Printf("failed to resolve term names: %v", err)
+		return nil
+	}
+	names := make(map[string]string, len(terms))
+	for _, t := range terms {
+		names[t.ID.Hex()] = t.Name
+	}
+	return names
+}
+
+// ResyncByTerm 在后台分批重新同步所有引用该 term 的 entry，批次之间暂停以免压垮搜索引擎；
+// 同一 term 的重复触发会合并为运行结束后的一次补跑
+func (s *SyncService) ResyncByTerm(term *model.Term) {
+	key := term.ID.Hex()
+	s.resyncMu.Lock()
+	if s.resyncRunning[key] {
+		s.resyncPending[key] = true
+		s.resyncMu.Unlock()
+		return
+	}
+	s.resyncRunning[key] = true
+	s.resyncMu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("panic in ResyncByTerm: %v", r)
+			}
+		}()
+		for {
+			s.resyncTerm(term.TaxonomyKey, term.ID)
+
+			s.resyncMu.Lock()
+			if !s.resyncPending[key] {
+				delete(s.resyncRunning, key)
+				s.resyncMu.Unlock()
+				return
+			}
+			delete(s.resyncPending, key)
+			s.resyncMu.Unlock()
+		}
+	}()
+}
+
+func (s *SyncService) resyncTerm(taxonomyKey string, termID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	var synced int
+	err := s.mongoRepo.ForEachTermEntryBatch(ctx, taxonomyKey, termID, resyncBatchSize, func(batch []model.Entry) error {
+		entries := make([]*model.Entry, len(batch))
+		for i := range batch {
+			entries[i] = &batch[i]
+		}
+		if err := s.SyncEntries(entries); err != nil {
+			return err
+		}
+		synced += len(batch)
+		time.Sleep(resyncBatchDelay)
+		return nil
+	})
+	if err != nil {
+		log.Printf("failed to resync entries for term %s after %d entries: %v", termID.Hex(), synced, err)
+		return
+	}
+	log.Printf("resynced %d entries for term %s", synced, termID.Hex())
+}
+
+// entrySchema 返回 entry 创建时使用的 schema 版本，加载失败时返回 nil
+func (s *SyncService) entrySchema(entry *model.Entry) *model.Schema {
+	schema, ok := s.schemaCache.Get(entry.SchemaID.Hex())
+	if !ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var err error
+		schema, err = s.mongoRepo.GetSchemaByID(ctx, entry.SchemaID)
+		if err != nil {
+			log.Printf("failed to load schema %s for entry %s: %v", entry.SchemaID.Hex(), entry.ID.Hex(), err)
+			return nil
+		}
+		s.schemaCache.Set(entry.SchemaID.Hex(), schema)
+	}
+	return schema
+}
+
+// entryTermIDs 根据 entry 所用 schema 中的 taxonomy 字段收集引用的 term ID
+func entryTermIDs(schema *model.Schema, entry *model.Entry) []string {
+	if schema == nil {
+		return []string{}
+	}
+
+	seen := make(map[string]bool)
+	termIDs := []string{}
+	collectTermIDs(schema.Fields, entry.Attributes, func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			termIDs = append(termIDs, id)
+		}
+	})
+	return termIDs
+}
+
+func collectTermIDs(fields []model.FieldSchema, data map[string]any, add func(string)) {
+	for _, field := range fields {
+		if value, ok := data[field.Key]; ok {
+			collectFieldTermIDs(field, value, add)
+		}
+	}
+}
+
+func collectFieldTermIDs(field model.FieldSchema, value any, add func(string)) {
+	switch field.Type {
+	case model.TypeTaxonomy:
+		if id, ok := value.(string); ok {
+			add(id)
+			return
+		}
+		for _, item := range asSlice(value) {
+			if id, ok := item.(string); ok {
+				add(id)
+			}
+		}
+	case model.TypeObject:
+		if obj := asMap(value); obj != nil {
+			collectTermIDs(field.Children, obj, add)
+		}
+	case model.TypeArray:
+		if field.ItemType == nil {
+			return
+		}
+		for _, item := range asSlice(value) {
+			collectFieldTermIDs(*field.ItemType, item, add)
+		}
 	}
 }
 
+// asSlice/asMap 兼容 JSON 解码（[]any/map）与 BSON 解码（primitive.A/D/M）两种来源
+func asSlice(value any) []any {
+	switch v := value.(type) {
+	case []any:
+		return v
+	case primitive.A:
+		return v
+	}
+	return nil
+}
+
+func asMap(value any) map[string]any {
+	switch v := value.(type) {
+	case map[string]any:
+		return v
+	case primitive.M:
+		return v
+	case primitive.D:
+		return v.Map()
+	}
+	return nil
+}
+
 func (s *SyncService) extractTextFromAttributes(attrs map[string]any) string {
 	var texts []string
 	for _, v := range attrs {
@@ -107,19 +460,82 @@ func extractStrings(v any) []string {
 	return result
 }
 
-var mdPatterns = []*regexp.Regexp{
-	regexp.MustCompile(`#{1,6}\s`),
-	regexp.MustCompile(`\*\*([^*]+)\*\*`),
-	regexp.MustCompile(`\*([^*]+)\*`),
-	regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`),
-	regexp.MustCompile("```[^`]*```"),
-	regexp.MustCompile("`[^`]+`"),
+// bodyText 按正文格式提取用于索引和摘要的纯文本，格式为空时按 markdown 处理
+func bodyText(body string, format model.BodyFormat) string {
+	switch format {
+	case model.BodyHTML:
+		return stripHTML(body)
+	case model.BodyPlain:
+		return strings.TrimSpace(body)
+	}
+	return stripMarkdown(body)
+}
+
+// stripHTML 只保留文本节点并解码实体，丢弃 script/style 内容；块级元素之间换行，行内元素不插入空白
+func stripHTML(doc string) string {
+	var b strings.Builder
+	skip := 0
+	z := html.NewTokenizer(strings.NewReader(doc))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			var lines []string
+			for _, line := range strings.Split(b.String(), "\n") {
+				if line = strings.Join(strings.Fields(line), " "); line != "" {
+					lines = append(lines, line)
+				}
+			}
+			return strings.Join(lines, "\n")
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Script, atom.Style:
+				if z.Token().Type == html.StartTagToken {
+					skip++
+				} else if skip > 0 {
+					skip--
+				}
+			case atom.Br, atom.P, atom.Div, atom.Li, atom.Tr, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Blockquote, atom.Pre:
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
+
+// mdRules 按顺序执行：先移除代码块，图片要在链接之前处理，块级标记在行内标记之前
+var mdRules = []struct {
+	pattern *regexp.Regexp
+	repl    string
+}{
+	{regexp.MustCompile("```[^`]*```"), ""},
+	{regexp.MustCompile(`<[^>]+>`), " "},                                                            // HTML tags
+	{regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`), "$1"},                                            // images keep alt text
+	{regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`), "$1"},                                             // links keep label
+	{regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+`), ""},                                             // headings
+	{regexp.MustCompile(`(?m)^[ \t]*(?:>[ \t]?)+`), ""},                                             // blockquotes
+	{regexp.MustCompile(`(?m)^[ \t]*(?:[-*_][ \t]*){3,}$`), ""},                                     // horizontal rules
+	{regexp.MustCompile(`(?m)^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)+\|?[ \t]*$`), ""}, // table separators
+	{regexp.MustCompile(`(?m)^[ \t]*(?:[-*+]|\d+[.)])[ \t]+(?:\[[ xX]\][ \t]+)?`), ""},              // list markers and task boxes
+	{regexp.MustCompile(`\|`), " "},                                                                 // table cells
+	{regexp.MustCompile(`\*\*([^*]+)\*\*`), "$1"},
+	{regexp.MustCompile(`__([^_]+)__`), "$1"},
+	{regexp.MustCompile(`\b_([^_\n]+)_\b`), "$1"}, // \b keeps snake_case words intact
+	{regexp.MustCompile(`\*([^*]+)\*`), "$1"},
+	{regexp.MustCompile(`~~([^~]+)~~`), "$1"},
+	{regexp.MustCompile("`([^`]+)`"), "$1"},
+	{regexp.MustCompile(`[ \t]+`), " "},
+	{regexp.MustCompile(`(?m)^ | $`), ""},
+	{regexp.MustCompile(`\n{3,}`), "\n\n"},
 }
 
 func stripMarkdown(md string) string {
 	result := md
-	for _, pattern := range mdPatterns {
-		result = pattern.ReplaceAllString(result, "$1")
+	for _, rule := range mdRules {
+		result = rule.pattern.ReplaceAllString(result, rule.repl)
 	}
 	return strings.TrimSpace(result)
 }
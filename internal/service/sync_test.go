@@ -0,0 +1,33 @@
+package service
+
+import "testing"
+
+func TestStripMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"heading", "# Title\n\ntext", "Title\n\ntext"},
+		{"emphasis", "**bold** __strong__ *it* _em_ ~~gone~~ `code`", "bold strong it em gone code"},
+		{"snake_case kept", "use my_var_name here", "use my_var_name here"},
+		{"blockquote", "> quoted\n> > nested", "quoted\nnested"},
+		{"unordered list", "- one\n* two\n+ three", "one\ntwo\nthree"},
+		{"ordered list", "1. first\n2) second", "first\nsecond"},
+		{"task list", "- [x] done\n- [ ] todo", "done\ntodo"},
+		{"image keeps alt", "![a cat](cat.png) sleeps", "a cat sleeps"},
+		{"link keeps label", "see [docs](https://example.com)", "see docs"},
+		{"image inside link", "[![logo](l.png)](https://example.com)", "logo"},
+		{"table", "| a | b |\n|---|:-:|\n| 1 | 2 |", "a b\n\n1 2"},
+		{"html", "<div class=\"x\">hi<br/>there</div>", "hi there"},
+		{"code block", "before\n```go\nx := 1\n```\nafter", "before\n\nafter"},
+		{"horizontal rule", "a\n\n---\n\nb", "a\n\nb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripMarkdown(tt.in); got != tt.want {
+				t.Errorf("stripMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
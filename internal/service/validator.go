@@ -3,161 +3,310 @@ package service
 import (
 	"context"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
 	"time"
 
 	"matter-core/internal/model"
 	"matter-core/internal/repository"
+	"matter-core/pkg/utils"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// maxSafeInteger 超过 2^53 的整数经 JSON（float64）解码后已不精确
+const maxSafeInteger = 1 << 53
+
+// MaxArrayItems 数组字段（含多值 taxonomy）元素数的全局上限，schema 未设置 MaxItems 时同样生效
+const MaxArrayItems = 1000
+
+// DefaultMaxValidationDepth 对象/数组嵌套的默认最大层数
+const DefaultMaxValidationDepth = 32
+
 type SchemaValidator struct {
-	mongoRepo *repository.MongoRepo
+	mongoRepo repository.Repository
+	maxDepth  int
+}
+
+// NewSchemaValidator maxDepth 限制对象/数组的嵌套层数，防止恶意构造的 schema 或数据拖垮校验；<= 0 时取默认值
+func NewSchemaValidator(mongoRepo repository.Repository, maxDepth int) *SchemaValidator {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxValidationDepth
+	}
+	return &SchemaValidator{mongoRepo: mongoRepo, maxDepth: maxDepth}
+}
+
+// ValidationError 汇总 entry 的所有字段错误，而不是遇到第一个就返回
+type ValidationError struct {
+	Errors []utils.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fmt.Sprintf("field '%s' %s", fe.Field, fe.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// validation 一次校验的状态：收集到的字段错误（路径形如 "specs.size"、"tags[2]"），
+// 以及待批量查询的 term 引用
+type validation struct {
+	errs     []utils.FieldError
+	terms    []termRef
+	depth    int
+	maxDepth int
+	strict   bool // 拒绝未声明的属性
+}
+
+// termRef 遍历时记录的 term 引用，遍历结束后一次性查询并校验
+type termRef struct {
+	path        string
+	id          string
+	oid         primitive.ObjectID
+	taxonomyKey string
 }
 
-func NewSchemaValidator(mongoRepo *repository.MongoRepo) *SchemaValidator {
-	return &SchemaValidator{mongoRepo: mongoRepo}
+func (st *validation) add(field, format string, args ...any) {
+	st.errs = append(st.errs, utils.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
 }
 
-func (v *SchemaValidator) ValidateEntry(schema model.Schema, data map[string]any) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	return v.validateFields(ctx, schema.Fields, data)
+// descend 进入下一层嵌套，超过最大层数时记录错误并返回 false；成功时调用方需在返回前 st.depth--
+func (st *validation) descend(path string) bool {
+	if st.depth >= st.maxDepth {
+		st.add(path, "exceeds maximum nesting depth of %d", st.maxDepth)
+		return false
+	}
+	st.depth++
+	return true
+}
+
+// ValidateEntry 校验 entry 属性，失败时返回 *ValidationError。
+// 校验同时会就地规范化 data，例如多值 taxonomy 字段的单个 term ID 会转为数组
+// 请求被取消或超时时返回 ctx 的错误，而不是把 term 查询失败当作字段错误
+func (v *SchemaValidator) ValidateEntry(ctx context.Context, schema model.Schema, data map[string]any) error {
+	st := &validation{maxDepth: v.maxDepth, strict: schema.Strict}
+	v.validateFields(ctx, "", schema.Fields, data, st)
+	v.checkTerms(ctx, st)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(st.errs) > 0 {
+		return &ValidationError{Errors: st.errs}
+	}
+	return nil
 }
 
-func (v *SchemaValidator) validateFields(ctx context.Context, fields []model.FieldSchema, data map[string]any) error {
+// checkTerms 用一次查询取回所有引用的 term，再逐个检查是否存在以及所属 taxonomy
+func (v *SchemaValidator) checkTerms(ctx context.Context, st *validation) {
+	if len(st.terms) == 0 {
+		return
+	}
+	ids := make([]primitive.ObjectID, 0, len(st.terms))
+	for _, ref := range st.terms {
+		ids = append(ids, ref.oid)
+	}
+	terms, err := v.mongoRepo.GetTermsByIDs(ctx, ids)
+	if err != nil {
+		for _, ref := range st.terms {
+			st.add(ref.path, "failed to validate term")
+		}
+		return
+	}
+	byID := make(map[primitive.ObjectID]model.Term, len(terms))
+	for _, term := range terms {
+		byID[term.ID] = term
+	}
+	for _, ref := range st.terms {
+		term, ok := byID[ref.oid]
+		if !ok {
+			st.add(ref.path, "term '%s' not found", ref.id)
+			continue
+		}
+		if ref.taxonomyKey != "" && term.TaxonomyKey != ref.taxonomyKey {
+			st.add(ref.path, "term '%s' belongs to wrong taxonomy", ref.id)
+		}
+	}
+}
+
+func (v *SchemaValidator) validateFields(ctx context.Context, prefix string, fields []model.FieldSchema, data map[string]any, st *validation) {
+	if st.strict {
+		declared := make(map[string]bool, len(fields))
+		for _, field := range fields {
+			declared[field.Key] = true
+		}
+		// Sorted so the error order is stable across requests
+		for _, key := range slices.Sorted(maps.Keys(data)) {
+			if !declared[key] {
+				st.add(prefix+key, "is not defined in the schema")
+			}
+		}
+	}
+
 	for _, field := range fields {
+		path := prefix + field.Key
 		value, exists := data[field.Key]
 
 		if field.Required && !exists {
-			return fmt.Errorf("required field '%s' is missing", field.Key)
+			st.add(path, "is required")
+			continue
 		}
 
 		if !exists {
 			continue
 		}
 
-		if err := v.validateFieldType(ctx, field, value); err != nil {
-			return err
-		}
+		data[field.Key] = v.validateFieldType(ctx, path, field, value, st)
 	}
-	return nil
 }
 
-func (v *SchemaValidator) validateFieldType(ctx context.Context, field model.FieldSchema, value interface{}) error {
+// validateFieldType 校验单个值并返回规范化后的值，调用方需写回
+func (v *SchemaValidator) validateFieldType(ctx context.Context, path string, field model.FieldSchema, value interface{}, st *validation) any {
+	// 显式的 null 与缺省不同：只有非必填且声明了 Nullable 的字段才接受
 	if value == nil {
-		if field.Required {
-			return fmt.Errorf("field '%s' cannot be null", field.Key)
+		if field.Required || !field.Nullable {
+			st.add(path, "cannot be null")
 		}
-		return nil
+		return value
 	}
 
 	switch field.Type {
 	case model.TypeString:
 		if _, ok := value.(string); !ok {
-			return fmt.Errorf("field '%s' must be a string", field.Key)
+			st.add(path, "must be a string")
 		}
 
 	case model.TypeNumber:
-		switch value.(type) {
-		case float64, float32, int, int32, int64:
+		switch n := value.(type) {
+		case float64:
+			if field.Integer {
+				// JSON numbers decode as float64; store whole values as int64 so they don't round-trip as 42.0
+				if n != math.Trunc(n) || math.Abs(n) > maxSafeInteger {
+					st.add(path, "must be an integer")
+					return value
+				}
+				return int64(n)
+			}
+		case float32:
+			if field.Integer && float64(n) != math.Trunc(float64(n)) {
+				st.add(path, "must be an integer")
+			}
+		case int, int32, int64:
 			// valid
 		default:
-			return fmt.Errorf("field '%s' must be a number", field.Key)
+			st.add(path, "must be a number")
 		}
 
 	case model.TypeBool:
 		if _, ok := value.(bool); !ok {
-			return fmt.Errorf("field '%s' must be a boolean", field.Key)
+			st.add(path, "must be a boolean")
 		}
 
 	case model.TypeDate:
 		switch val := value.(type) {
 		case string:
 			if _, err := time.Parse(time.RFC3339, val); err != nil {
-				return fmt.Errorf("field '%s' must be a valid date (RFC3339)", field.Key)
+				st.add(path, "must be a valid date (RFC3339)")
 			}
 		case time.Time:
 			// valid
 		default:
-			return fmt.Errorf("field '%s' must be a date", field.Key)
+			st.add(path, "must be a date")
 		}
 
 	case model.TypeObject:
 		obj, ok := value.(map[string]any)
 		if !ok {
-			return fmt.Errorf("field '%s' must be an object", field.Key)
+			st.add(path, "must be an object")
+			return value
 		}
 		if len(field.Children) > 0 {
-			if err := v.validateFields(ctx, field.Children, obj); err != nil {
-				return err
+			if !st.descend(path) {
+				return value
 			}
+			v.validateFields(ctx, path+".", field.Children, obj, st)
+			st.depth--
 		}
 
 	case model.TypeArray:
 		arr, ok := value.([]any)
 		if !ok {
-			return fmt.Errorf("field '%s' must be an array", field.Key)
+			st.add(path, "must be an array")
+			return value
+		}
+		switch {
+		case len(arr) > MaxArrayItems:
+			st.add(path, "must have at most %d items", MaxArrayItems)
+			return value
+		case field.MaxItems != nil && len(arr) > *field.MaxItems:
+			st.add(path, "must have at most %d items", *field.MaxItems)
+		case field.MinItems != nil && len(arr) < *field.MinItems:
+			st.add(path, "must have at least %d items", *field.MinItems)
 		}
 		if field.ItemType != nil {
+			if !st.descend(path) {
+				return value
+			}
 			for i, item := range arr {
-				if err := v.validateFieldType(ctx, *field.ItemType, item); err != nil {
-					return fmt.Errorf("field '%s[%d]': %w", field.Key, i, err)
-				}
+				arr[i] = v.validateFieldType(ctx, fmt.Sprintf("%s[%d]", path, i), *field.ItemType, item, st)
 			}
+			st.depth--
 		}
 
 	case model.TypeTaxonomy:
-		if err := v.validateTaxonomyField(ctx, field, value); err != nil {
-			return err
-		}
+		return v.validateTaxonomyField(ctx, path, field, value, st)
 	}
 
-	return nil
+	return value
 }
 
-func (v *SchemaValidator) validateTaxonomyField(ctx context.Context, field model.FieldSchema, value interface{}) error {
-	validateTermID := func(termIDStr string) error {
+// validateTaxonomyField 多值字段始终存为数组（单个字符串会包装为单元素数组），单值字段始终存为字符串
+func (v *SchemaValidator) validateTaxonomyField(ctx context.Context, path string, field model.FieldSchema, value interface{}, st *validation) any {
+	validateTermID := func(path, termIDStr string) {
 		termID, err := primitive.ObjectIDFromHex(termIDStr)
 		if err != nil {
-			return fmt.Errorf("field '%s': invalid term ID format", field.Key)
-		}
-		term, err := v.mongoRepo.GetTermByID(ctx, termID)
-		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				return fmt.Errorf("field '%s': term '%s' not found", field.Key, termIDStr)
-			}
-			return fmt.Errorf("field '%s': failed to validate term", field.Key)
+			st.add(path, "invalid term ID format")
+			return
 		}
-		if field.TaxonomyKey != "" && term.TaxonomyKey != field.TaxonomyKey {
-			return fmt.Errorf("field '%s': term '%s' belongs to wrong taxonomy", field.Key, termIDStr)
-		}
-		return nil
+		st.terms = append(st.terms, termRef{path: path, id: termIDStr, oid: termID, taxonomyKey: field.TaxonomyKey})
 	}
 
 	if field.AllowMultiple {
+		if termIDStr, ok := value.(string); ok {
+			validateTermID(path, termIDStr)
+			return []any{termIDStr}
+		}
 		arr, ok := value.([]any)
 		if !ok {
-			return fmt.Errorf("field '%s' must be an array of term IDs", field.Key)
+			st.add(path, "must be an array of term IDs")
+			return value
 		}
-		for _, item := range arr {
+		if len(arr) > MaxArrayItems {
+			st.add(path, "must have at most %d items", MaxArrayItems)
+			return value
+		}
+		for i, item := range arr {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
 			termIDStr, ok := item.(string)
 			if !ok {
-				return fmt.Errorf("field '%s' must contain string term IDs", field.Key)
-			}
-			if err := validateTermID(termIDStr); err != nil {
-				return err
+				st.add(itemPath, "must be a term ID string")
+				continue
 			}
+			validateTermID(itemPath, termIDStr)
 		}
 	} else {
+		if _, ok := value.([]any); ok {
+			st.add(path, "accepts a single term ID, not an array")
+			return value
+		}
 		termIDStr, ok := value.(string)
 		if !ok {
-			return fmt.Errorf("field '%s' must be a term ID string", field.Key)
-		}
-		if err := validateTermID(termIDStr); err != nil {
-			return err
+			st.add(path, "must be a term ID string")
+			return value
 		}
+		validateTermID(path, termIDStr)
 	}
-	return nil
+	return value
 }
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"matter-core/internal/model"
+	"matter-core/internal/repository"
+)
+
+// nestedArray 构造 depth 层嵌套的数组字段及与之匹配的数据
+func nestedArray(depth int) (model.FieldSchema, any) {
+	field := model.FieldSchema{Type: model.TypeString}
+	var value any = "leaf"
+	for i := 0; i < depth; i++ {
+		item := field
+		field = model.FieldSchema{Type: model.TypeArray, ItemType: &item}
+		value = []any{value}
+	}
+	field.Key = "deep"
+	return field, value
+}
+
+func TestValidateEntryMaxDepth(t *testing.T) {
+	v := NewSchemaValidator(repository.NewMemoryRepo(), 8)
+
+	tests := []struct {
+		name    string
+		depth   int
+		wantErr bool
+	}{
+		{"within limit", 8, false},
+		{"one level too deep", 9, true},
+		{"pathological", 100000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, value := nestedArray(tt.depth)
+			schema := model.Schema{Key: "post", Fields: []model.FieldSchema{field}}
+			err := v.ValidateEntry(context.Background(), schema, map[string]any{"deep": value})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("ValidateEntry() = %v, want nil", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateEntry() = %v, want *ValidationError", err)
+			}
+			if len(verr.Errors) != 1 || !strings.Contains(verr.Errors[0].Message, "maximum nesting depth of 8") {
+				t.Errorf("errors = %+v, want a single depth error", verr.Errors)
+			}
+		})
+	}
+}
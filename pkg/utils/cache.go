@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TTLCache 简单的进程内缓存，条目在 ttl 后过期
+type TTLCache[V any] struct {
+	mu    sync.RWMutex
+	ttl   time.Duration
+	items map[string]cacheItem[V]
+}
+
+type cacheItem[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func NewTTLCache[V any](ttl time.Duration) *TTLCache[V] {
+	return &TTLCache[V]{
+		ttl:   ttl,
+		items: make(map[string]cacheItem[V]),
+	}
+}
+
+func (c *TTLCache[V]) Get(key string) (V, bool) {
+	c.mu.RLock()
+	item, ok := c.items[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(item.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return item.value, true
+}
+
+func (c *TTLCache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Drop expired items opportunistically to keep the map bounded
+	now := time.Now()
+	for k, item := range c.items {
+		if now.After(item.expiresAt) {
+			delete(c.items, k)
+		}
+	}
+	c.items[key] = cacheItem[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *TTLCache[V]) Delete(key string) {
+	c.mu.Lock()
+	delete(c.items, key)
+	c.mu.Unlock()
+}
@@ -0,0 +1,10 @@
+package utils
+
+import "regexp"
+
+var colorRegex = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// IsValidColor 检查颜色为 #RGB 或 #RRGGBB，空字符串表示不设置颜色
+func IsValidColor(s string) bool {
+	return s == "" || colorRegex.MatchString(s)
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag 根据响应内容生成强 ETag，内容不变则 ETag 不变
+func ETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified 写入 ETag 和 Last-Modified 响应头，并根据条件请求头判断客户端的缓存是否仍然有效；
+// 有效时响应 304 并返回 true。同时携带两者时以 If-None-Match 为准（RFC 7232），
+// lastModified 为零值时不输出 Last-Modified
+func NotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+
+	notModified := false
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		notModified = etag != "" && etagMatches(inm, etag)
+	} else if ims := c.GetHeader("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		// HTTP 日期只精确到秒
+		since, err := http.ParseTime(ims)
+		notModified = err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	if notModified {
+		c.AbortWithStatus(http.StatusNotModified)
+	}
+	return notModified
+}
+
+// etagMatches 对 If-None-Match 做弱比较：忽略 W/ 前缀，"*" 匹配任意 ETag
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// 机器可读的错误码，放在响应的 error_code 字段，客户端可据此分支处理或本地化
+const (
+	CodeBadRequest           = "BAD_REQUEST"
+	CodeUnauthorized         = "UNAUTHORIZED"
+	CodeForbidden            = "FORBIDDEN"
+	CodeNotFound             = "NOT_FOUND"
+	CodeConflict             = "CONFLICT"
+	CodeRateLimited          = "RATE_LIMITED"
+	CodeInternal             = "INTERNAL_ERROR"
+	CodeServiceUnavailable   = "SERVICE_UNAVAILABLE"
+	CodeUpstreamFailed       = "UPSTREAM_FAILED"
+	CodePayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+
+	CodeValidationFailed = "VALIDATION_FAILED"
+
+	CodeSchemaNotFound   = "SCHEMA_NOT_FOUND"
+	CodeEntryNotFound    = "ENTRY_NOT_FOUND"
+	CodeTaxonomyNotFound = "TAXONOMY_NOT_FOUND"
+	CodeTermNotFound     = "TERM_NOT_FOUND"
+	CodeCommentNotFound  = "COMMENT_NOT_FOUND"
+	CodeUserNotFound     = "USER_NOT_FOUND"
+	CodeSessionNotFound  = "SESSION_NOT_FOUND"
+	CodeAPIKeyNotFound   = "API_KEY_NOT_FOUND"
+	CodeMediaNotFound    = "MEDIA_NOT_FOUND"
+)
+
+// statusCode 未指定错误码时按 HTTP 状态给出通用错误码
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	case http.StatusBadGateway:
+		return CodeUpstreamFailed
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// FieldError 单个字段的校验错误，作为 VALIDATION_FAILED 响应的 details
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// 校验错误中使用 JSON 字段名，与请求体保持一致
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// BindError 响应请求体绑定失败；字段校验失败时返回 VALIDATION_FAILED 和逐字段的 details
+func BindError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		Error(c, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		BadRequest(c, err.Error())
+		return
+	}
+	details := make([]FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		details = append(details, FieldError{Field: fieldPath(fe), Message: fieldMessage(fe)})
+	}
+	ErrorWithDetails(c, http.StatusBadRequest, CodeValidationFailed, "validation failed", details)
+}
+
+// fieldPath 去掉顶层结构体名，例如 "CreateEntryRequest.base.title" -> "base.title"
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("must have length %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of [%s]", fe.Param())
+	case "url":
+		return "must be a valid URL"
+	case "email":
+		return "must be a valid email"
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed '%s=%s' validation", fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("failed '%s' validation", fe.Tag())
+}
@@ -1,17 +1,26 @@
 package utils
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Response struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Data    any    `json:"data,omitempty"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Data      any    `json:"data,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"` // 机器可读错误码，见 errors.go
+	Details   any    `json:"details,omitempty"`    // 错误详情，例如逐字段的校验错误
+	RequestID string `json:"request_id,omitempty"` // 出错时回显，便于与服务端日志对应
 }
 
+// RequestIDKey request ID 在 gin.Context 中的 key，由 handler.RequestLogger 写入
+const RequestIDKey = "request_id"
+
 type PaginatedResponse struct {
 	Code    int            `json:"code"`
 	Message string         `json:"message"`
@@ -24,6 +33,18 @@ type PaginationMeta struct {
 	Limit   int64 `json:"limit"`
 	Offset  int64 `json:"offset"`
 	HasMore bool  `json:"has_more"`
+
+	Snippets any  `json:"snippets,omitempty"` // 搜索高亮摘要，按 ID 索引
+	Degraded bool `json:"degraded,omitempty"` // 搜索引擎不可用，结果来自退化查询
+}
+
+func NewPaginationMeta(total, limit, offset int64) PaginationMeta {
+	return PaginationMeta{
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: offset+limit < total,
+	}
 }
 
 func Success(c *gin.Context, data any) {
@@ -35,16 +56,19 @@ func Success(c *gin.Context, data any) {
 }
 
 func SuccessWithPagination(c *gin.Context, data any, total, limit, offset int64) {
+	SuccessWithMeta(c, data, NewPaginationMeta(total, limit, offset))
+}
+
+// TotalCountHeader 分页总数的响应头，供读取 header 的管理后台类前端使用
+const TotalCountHeader = "X-Total-Count"
+
+func SuccessWithMeta(c *gin.Context, data any, meta PaginationMeta) {
+	c.Header(TotalCountHeader, strconv.FormatInt(meta.Total, 10))
 	c.JSON(http.StatusOK, PaginatedResponse{
 		Code:    0,
 		Message: "success",
 		Data:    data,
-		Meta: PaginationMeta{
-			Total:   total,
-			Limit:   limit,
-			Offset:  offset,
-			HasMore: offset+limit < total,
-		},
+		Meta:    meta,
 	})
 }
 
@@ -57,9 +81,31 @@ func Created(c *gin.Context, data any) {
 }
 
 func Error(c *gin.Context, status int, message string) {
+	ErrorWithCode(c, status, statusCode(status), message)
+}
+
+// ErrorWithCode 返回带业务错误码的错误，例如 SCHEMA_NOT_FOUND
+func ErrorWithCode(c *gin.Context, status int, appCode, message string) {
+	ErrorWithDetails(c, status, appCode, message, nil)
+}
+
+func ErrorWithDetails(c *gin.Context, status int, appCode, message string, details any) {
 	c.JSON(status, Response{
-		Code:    status,
-		Message: message,
+		Code:      status,
+		Message:   message,
+		ErrorCode: appCode,
+		Details:   details,
+		RequestID: c.GetString(RequestIDKey),
+	})
+}
+
+func ErrorWithData(c *gin.Context, status int, message string, data any) {
+	c.JSON(status, Response{
+		Code:      status,
+		Message:   message,
+		Data:      data,
+		ErrorCode: statusCode(status),
+		RequestID: c.GetString(RequestIDKey),
 	})
 }
 
@@ -79,6 +125,23 @@ func NotFound(c *gin.Context, message string) {
 	Error(c, http.StatusNotFound, message)
 }
 
+func Conflict(c *gin.Context, message string) {
+	Error(c, http.StatusConflict, message)
+}
+
+func TooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	Error(c, http.StatusTooManyRequests, message)
+}
+
 func InternalError(c *gin.Context, message string) {
 	Error(c, http.StatusInternalServerError, message)
 }
+
+func ServiceUnavailable(c *gin.Context, message string) {
+	Error(c, http.StatusServiceUnavailable, message)
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+var slugRegex = regexp.MustCompile(`^[\p{L}\p{N}]+(?:[-_][\p{L}\p{N}]+)*$`)
+
+// Slugify 将名称转换为 slug：小写、非字母数字字符折叠为连字符
+func Slugify(s string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingHyphen = true
+	}
+	return b.String()
+}
+
+// NormalizeSlug 生成用于查找和唯一性比较的 slug：小写并去掉重音（"Café" -> "cafe"），展示仍使用原 slug
+func NormalizeSlug(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(t, s)
+	if err != nil {
+		result = s
+	}
+	return strings.ToLower(result)
+}
+
+// IsValidSlug 检查 slug 只包含字母、数字以及分隔用的 - 或 _
+func IsValidSlug(s string) bool {
+	return slugRegex.MatchString(s)
+}